@@ -0,0 +1,97 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Pad the keys of encoded data so that the = signs of each block line up.
+// The continuation lines of a multi-line value are moved along with its key,
+// and heredoc content is left as is.
+func alignValues(bs []byte) []byte {
+	lines := strings.SplitAfter(string(bs), lf)
+	// each block is a list of its key lines
+	type keyLine struct {
+		n, width int   // Line number, and width of the key in bytes
+		cols     int   // Width of the key in characters
+		cont     []int // Continuation lines of a multi-line value
+	}
+	var stack [][]keyLine
+	var block []keyLine
+	align := func(block []keyLine) {
+		var max int
+		for _, k := range block {
+			if k.cols > max {
+				max = k.cols
+			}
+		}
+		for _, k := range block {
+			pad := strings.Repeat(" ", max-k.cols)
+			s := lines[k.n]
+			indent := len(s) - len(strings.TrimLeft(s, " "))
+			lines[k.n] = s[:indent+k.width] + pad + s[indent+k.width:]
+			for _, c := range k.cont {
+				lines[c] = pad + lines[c]
+			}
+		}
+	}
+	for n := 0; n < len(lines); n++ {
+		s := trim(lines[n])
+		width := encodedKeyWidth(s)
+		switch {
+		case s == "}" && len(stack) > 0:
+			align(block)
+			block, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			continue
+		case width == 0 || s[0] == '#':
+			continue
+		}
+		k := keyLine{n: n, width: width, cols: utf8.RuneCountInString(s[:width])}
+		val := s[width+3:]
+		switch {
+		case val == "{":
+			block = append(block, k)
+			stack = append(stack, block)
+			block = nil
+			continue
+		case strings.HasPrefix(val, "<<"):
+			// skip the heredoc content, up to the terminating code
+			code := val[2:]
+			for n++; n < len(lines) && trim(lines[n]) != code; n++ {
+			}
+		case strings.HasSuffix(val, `\`):
+			for n++; n < len(lines); n++ {
+				k.cont = append(k.cont, n)
+				if !strings.HasSuffix(rtrim(lines[n]), `\`) {
+					break
+				}
+			}
+		}
+		block = append(block, k)
+	}
+	align(block)
+	return []byte(strings.Join(lines, ""))
+}
+
+// Return the width of the key of an encoded line, eg. 4 for Name = Rick, or
+// zero if the line is not a key and value. A quoted key may contain spaces.
+func encodedKeyWidth(s string) int {
+	i := 0
+	if strings.HasPrefix(s, `"`) {
+		for i = 1; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' {
+				i++
+			}
+		}
+		i++
+	}
+	j := strings.Index(s[min(i, len(s)):], " = ")
+	if j < 0 || i+j == 0 {
+		return 0
+	}
+	return i + j
+}
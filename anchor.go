@@ -0,0 +1,71 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import "fmt"
+
+// Parse an anchor, a named block which may be reused by an alias later in
+// the source. The anchor itself does not add any keys.
+//
+//	&defaults {
+//		Host = localhost
+//		Port = 80
+//	}
+//	Server1 = *defaults {
+//		Port = 81
+//	}
+//	Server2 = *defaults
+func (o *Parser) parseAnchor(name string, depth int) {
+	lineno := o.lineno
+	emap, err := o.recursive_parse(depth + 1)
+	if err != nil {
+		if o.abort == nil {
+			o.appendErr(err, lineno)
+		}
+		return
+	}
+	o.sections++
+	if o.anchors == nil {
+		o.anchors = make(map[string]fMap)
+	}
+	if _, ok := o.anchors[name]; ok {
+		o.appendError("Duplicate anchor ("+name+")", lineno)
+		return
+	}
+	o.anchors[name] = emap
+}
+
+// Parse an alias, a block key which copies the keys of an anchor. The keys
+// of an optional block which follows the alias override the copied keys.
+func (o *Parser) parseAlias(fieldMap fMap, key, name string, block bool, depth int) {
+	lineno := o.lineno
+	emap := make(fMap)
+	if block {
+		if max := o.limits.MaxDepth; max > 0 && depth >= max {
+			o.abort = newError(fmt.Sprintf("Nesting exceeds limit of %d", max), lineno)
+			return
+		}
+		m, err := o.recursive_parse(depth + 1)
+		if err != nil {
+			if o.abort == nil {
+				o.appendErr(err, lineno)
+			}
+			return
+		}
+		emap = m
+	}
+	a, ok := o.anchors[name]
+	if !ok {
+		o.appendError("Undefined anchor ("+name+")", lineno)
+		return
+	}
+	for k, val := range a {
+		if !exists(emap, k) {
+			c := *val
+			emap[k] = &c
+		}
+	}
+	o.addBlock(fieldMap, key, emap, lineno)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAnchors(t *testing.T) {
+
+	src := `
+		&defaults {
+			Host = localhost
+			Port = 80
+			Tls {
+				Enabled = false
+			}
+		}
+		Server1 = *defaults {
+			Port = 81
+			Tls {
+				Enabled = true
+			}
+		}
+		Server2 = *defaults
+		Name = *literal
+	`
+
+	Convey("Parse anchors and aliases", t, func() {
+		m, err := Parse(src)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{
+			"Server1.Host": "localhost", "Server1.Port": "81", "Server1.Tls.Enabled": "true",
+			"Server2.Host": "localhost", "Server2.Port": "80", "Server2.Tls.Enabled": "false",
+			"Name": "*literal",
+		})
+	})
+
+	Convey("Decode anchors and aliases", t, func() {
+		type server struct {
+			Host string
+			Port int
+			Tls  struct{ Enabled bool }
+		}
+		var x struct {
+			Server1 server
+			Server2 server
+			Name    string
+		}
+		So(Decode(&x, src), ShouldBeNil)
+		So(x.Server1.Port, ShouldEqual, 81)
+		So(x.Server1.Tls.Enabled, ShouldBeTrue)
+		So(x.Server2.Host, ShouldEqual, "localhost")
+		So(x.Server2.Tls.Enabled, ShouldBeFalse)
+	})
+
+	Convey("Force error: Undefined and duplicate anchors", t, func() {
+		_, err := Parse("A = 1\nB = *nope {\nC = 1\n}")
+		So(err.Error(), ShouldEqual, "Undefined anchor (nope) at line 2")
+		_, err = Parse("&a {\nB = 1\n}\n&a {\nB = 2\n}\nC = *a")
+		So(err.Error(), ShouldEqual, "Duplicate anchor (a) at line 4")
+	})
+
+}
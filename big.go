@@ -0,0 +1,116 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// Return true if t is big.Int, big.Float or big.Rat, or a pointer to one.
+func isBigType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == bigIntType || t == bigFloatType || t == bigRatType
+}
+
+// Assign a string to a big.Int, big.Float or big.Rat, or a pointer to one. A
+// nil pointer is allocated. A big.Int accepts the same abbreviations as an
+// int, a big.Float is given enough precision for every digit supplied unless
+// it already has a precision, and a big.Rat accepts a fraction, eg. 1/3.
+func set_big(v1 reflect.Value, val string) error {
+	p := v1
+	if v1.Kind() != reflect.Ptr {
+		p = v1.Addr()
+	} else if v1.IsNil() {
+		p = reflect.New(v1.Type().Elem())
+	}
+	var ok bool
+	switch x := p.Interface().(type) {
+	case *big.Int:
+		var n *big.Int
+		if n, ok = new(big.Int).SetString(iFix(val), 10); ok {
+			x.Set(n)
+		}
+	case *big.Float:
+		prec := x.Prec()
+		if prec == 0 {
+			prec = bigFloatPrec(val)
+		}
+		f, _, err := big.ParseFloat(val, 10, prec, big.ToNearestEven)
+		if ok = err == nil; ok {
+			x.Set(f)
+		}
+	case *big.Rat:
+		var r *big.Rat
+		if r, ok = new(big.Rat).SetString(val); ok {
+			x.Set(r)
+		}
+	}
+	if !ok {
+		return errors.New("Invalid numeric value")
+	}
+	if v1.Kind() == reflect.Ptr && v1.IsNil() {
+		v1.Set(p)
+	}
+	return nil
+}
+
+// Return a precision in bits sufficient for the decimal digits of s.
+func bigFloatPrec(s string) uint {
+	// log2(10) is slightly less than 10/3
+	prec := uint(len(s))*10/3 + 8
+	if prec < 64 {
+		prec = 64
+	}
+	return prec
+}
+
+// Return the value of a big.Int, big.Float or big.Rat, or a pointer to one,
+// as a string which decodes to the same value. A nil pointer is zero.
+func formatBig(v1 reflect.Value) string {
+	if v1.Kind() == reflect.Ptr && v1.IsNil() {
+		return "0"
+	}
+	if v1.Kind() != reflect.Ptr {
+		if !v1.CanAddr() {
+			p := reflect.New(v1.Type())
+			p.Elem().Set(v1)
+			v1 = p.Elem()
+		}
+		v1 = v1.Addr()
+	}
+	switch x := v1.Interface().(type) {
+	case *big.Int:
+		return x.String()
+	case *big.Float:
+		return x.Text('g', -1)
+	case *big.Rat:
+		return x.RatString()
+	}
+	return ""
+}
+
+// Return true if a big.Int, big.Float or big.Rat, or a pointer to one, is
+// zero or nil.
+func isZeroBig(v1 reflect.Value) bool {
+	return formatBig(v1) == "0"
+}
+
+func (o *Encoder) encodeBig(v1 reflect.Value, depth int, parent_key string) bool {
+	if !o.isOption(ENCODE_ZERO_VALUES) && isZeroBig(v1) {
+		return true
+	}
+	o.write_kv(depth, parent_key, formatBig(v1))
+	return true
+}
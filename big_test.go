@@ -0,0 +1,90 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"math/big"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBig(t *testing.T) {
+
+	type cfg struct {
+		Supply *big.Int
+		Rate   *big.Float
+		Ratio  *big.Rat
+		Total  big.Int
+		Limits map[string]*big.Int
+	}
+
+	src := `
+		Supply = 123456789012345678901234567890
+		Rate   = 3.14159265358979323846264338327950288
+		Ratio  = 1/3
+		Total  = 2E
+		Limits {
+			Max = 99999999999999999999
+		}
+	`
+
+	Convey("Decode values exceeding 64 bits", t, func() {
+		var x cfg
+		So(Decode(&x, src), ShouldBeNil)
+		So(x.Supply.String(), ShouldEqual, "123456789012345678901234567890")
+		So(x.Rate.Text('g', -1), ShouldEqual, "3.14159265358979323846264338327950288")
+		So(x.Ratio.RatString(), ShouldEqual, "1/3")
+		So(x.Total.String(), ShouldEqual, "2000000000000000000")
+		So(x.Limits["Max"].String(), ShouldEqual, "99999999999999999999")
+	})
+
+	Convey("Encode and decode without loss", t, func() {
+		var x cfg
+		So(Decode(&x, src), ShouldBeNil)
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, `Supply = 123456789012345678901234567890
+Rate = 3.14159265358979323846264338327950288
+Ratio = 1/3
+Total = 2000000000000000000
+Limits = {
+  Max = 99999999999999999999
+}
+`)
+		var y cfg
+		So(Decode(&y, b1), ShouldBeNil)
+		So(y.Supply.Cmp(x.Supply), ShouldEqual, 0)
+		So(y.Rate.Cmp(x.Rate), ShouldEqual, 0)
+		So(y.Ratio.Cmp(x.Ratio), ShouldEqual, 0)
+	})
+
+	Convey("A big.Float keeps an existing precision", t, func() {
+		x := struct{ Rate *big.Float }{new(big.Float).SetPrec(24)}
+		So(Decode(&x, "Rate = 3.14159265358979323846"), ShouldBeNil)
+		So(x.Rate.Prec(), ShouldEqual, 24)
+	})
+
+	Convey("Nil and zero values are not encoded", t, func() {
+		b1, err := Encode(cfg{Ratio: new(big.Rat)})
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "")
+		b1, err = Encode(struct{ A, B *big.Int }{nil, big.NewInt(0)}, ENCODE_ZERO_VALUES)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "A = 0\nB = 0\n")
+	})
+
+	Convey("Force error: Invalid big numbers", t, func() {
+		orig := big.NewInt(42)
+		x := cfg{Supply: orig}
+		err := Decode(&x, "Supply = 1.5")
+		So(err.Error(), ShouldEqual, "Supply: Invalid numeric value at line 1")
+		So(x.Supply.Int64(), ShouldEqual, 42)
+		So(Decode(&x, "Rate = pi"), ShouldNotBeNil)
+		So(Decode(&x, "Ratio = 1/0"), ShouldNotBeNil)
+		err = Decode(&x, "Supply = 8K", STRICT_NUMBERS)
+		So(err.Error(), ShouldEqual, "Supply: Invalid numeric literal at line 1")
+	})
+
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// An Issue describes a single problem found by Check.
+type Issue struct {
+	Line int    // Line number in source, or zero if unknown
+	Key  string // Dotted key, or empty if unknown
+	Msg  string // Description of the problem
+}
+
+func (i Issue) String() string {
+	s := i.Msg
+	if i.Key != "" {
+		s += " (" + i.Key + ")"
+	}
+	if i.Line > 0 {
+		s += fmt.Sprintf(" at line %d", i.Line)
+	}
+	return s
+}
+
+// Check verifies that the supplied source (a string, byte slice or io.Reader)
+// would decode into x without error. Every problem is reported, rather than
+// just the first, and x itself is never modified. Decoder options are
+// optional. Check returns nil if no issues were found. To check a source
+// which needs a decrypter, decode hooks or other settings of a decoder, use
+// Decoder.Check.
+func Check(x interface{}, src interface{}, options ...int) []Issue {
+	target, err := checkTarget(x)
+	if err != nil {
+		return []Issue{{Msg: err.Error()}}
+	}
+	return NewDecoder(target, options...).checkSource(src)
+}
+
+// Check verifies that the supplied source would decode without error into
+// the struct or map of the decoder, applying the options and settings of the
+// decoder, such as its decrypter, decode hooks and variables. The struct or
+// map itself is never modified. See Check.
+//
+//	issues := config.NewDecoder(&cfg).WithDecrypter(decrypt).Check(src)
+func (o *Decoder) Check(src interface{}) []Issue {
+	target, err := checkTarget(o.v)
+	if err != nil {
+		return []Issue{{Msg: err.Error()}}
+	}
+	// a copy of the decoder, so that its state is not changed
+	d := *o
+	d.v = target
+	d.reset()
+	d.issues, d.set, d.fieldMap = nil, nil, nil
+	return d.checkSource(src)
+}
+
+// Return a new value of the type of x to be decoded by Check: a map, or a
+// pointer to a struct.
+func checkTarget(x interface{}) (interface{}, error) {
+	t := reflect.TypeOf(x)
+	switch {
+	case t == nil:
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		return reflect.MakeMap(t).Interface(), nil
+	case t.Kind() == reflect.Struct:
+		return reflect.New(t).Interface(), nil
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
+		return reflect.New(t.Elem()).Interface(), nil
+	}
+	return nil, errors.New("Expecting pointer to a struct or a map")
+}
+
+// Check a source in check mode, decoding into the value of the decoder.
+func (o *Decoder) checkSource(src interface{}) []Issue {
+	t := reflect.TypeOf(o.v)
+	target := o.v
+	o.check = true
+	o.parser = o.newParser()
+	o.parser.reader = bufio.NewReader(toReader(src))
+	o.fieldMap, _ = o.parser.parse()
+	for _, e := range o.parser.errs {
+		if pe, ok := e.(*ParseError); ok {
+			o.issues = append(o.issues, Issue{Line: pe.Line, Key: pe.Key, Msg: pe.Msg})
+		} else {
+			o.issues = append(o.issues, Issue{Msg: e.Error()})
+		}
+	}
+	o.decryptValues()
+	if o.isMap && isInterfaceMap(t) {
+		o.traverseInterfaceMap(reflect.ValueOf(target), "")
+	} else if o.isMap {
+		vt := t.Elem()
+		for k := range o.fieldMap {
+			if val, lineno, ok := o.getValue(k); ok {
+				if err := o.setValue(reflect.New(vt).Elem(), val); err != nil {
+					o.failValue(k, err, lineno)
+				}
+			}
+		}
+	} else {
+		o.traverseStruct(reflect.ValueOf(target), "")
+	}
+	for k, v := range o.fieldMap {
+		if !v.isDefined && !isOption(ALLOW_EXTRA_FIELDS, o.options) {
+			o.issues = append(o.issues, Issue{v.no, k, "Extra field"})
+		}
+	}
+	sort.SliceStable(o.issues, func(i, j int) bool {
+		if o.issues[i].Line != o.issues[j].Line {
+			return o.issues[i].Line < o.issues[j].Line
+		}
+		return o.issues[i].Key < o.issues[j].Key
+	})
+	return o.issues
+}
+
+// Report an error. In check mode the error is recorded as an issue, and under
+// the COLLECT_ERRORS option it is recorded to be reported with the others.
+// In either case decoding continues.
+func (o *Decoder) fail(key, msg string, lineno int) error {
+	if o.check {
+		o.issues = append(o.issues, Issue{lineno, key, msg})
+		return nil
+	}
+	return o.collect(&ParseError{File: o.sourceOf(key), Line: lineno, Msg: msg})
+}
+
+// Report an error in the value of a key. The key is named in the error,
+// which wraps err.
+func (o *Decoder) failValue(key string, err error, lineno int) error {
+	if o.check {
+		o.issues = append(o.issues, Issue{lineno, key, err.Error()})
+		return nil
+	}
+	return o.collect(&ParseError{File: o.sourceOf(key), Line: lineno, Key: key, Msg: err.Error(), Err: err})
+}
+
+// Record an error under the COLLECT_ERRORS option and return nil, so that
+// decoding continues, or errMaxErrors once the error limit is reached.
+// Without the option the error is returned as is.
+func (o *Decoder) collect(err error) error {
+	if !isOption(COLLECT_ERRORS, o.options) {
+		return err
+	}
+	o.collected = append(o.collected, err)
+	if max := maxErrors(o.options, o.limits); max > 0 && len(o.collected) >= max {
+		return errMaxErrors
+	}
+	return nil
+}
+
+// Return the name of the file which supplied a key, or an empty string.
+func (o *Decoder) sourceOf(key string) string {
+	if vs := o.lookup(key); vs != nil {
+		return vs.src
+	}
+	return ""
+}
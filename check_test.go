@@ -0,0 +1,103 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/base64"
+	"time"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheck(t *testing.T) {
+
+	type sx struct {
+		Int1 int8
+	}
+	type cfgX struct {
+		Name  string
+		Port  uint16
+		Start time.Time
+		Map1  map[string]sx
+		Ints  map[string]int
+	}
+
+	Convey("Valid source produces no issues", t, func() {
+		var x cfgX
+		cfg := `
+			Name  = Rick
+			Port  = 8080
+			Map1 {
+				Key1 {
+					Int1 = 1
+				}
+			}
+		`
+		So(Check(&x, cfg), ShouldBeNil)
+	})
+
+	Convey("All issues are reported and the target is not modified", t, func() {
+		x := cfgX{Name: "Morty"}
+		cfg := `
+			Name  = Rick
+			Port  = 65536
+			Start = 2017-13-45
+			Extra = 1
+			Map1 {
+				Key1 {
+					Int1 = 256
+				}
+			}
+			Ints {
+				Key1 = one
+			}
+			Bad..Key = 2
+		`
+		issues := Check(&x, cfg)
+		So(len(issues), ShouldEqual, 6)
+		So(issues[0].String(), ShouldEqual, "Overflow (Port) at line 3")
+		So(issues[1].Key, ShouldEqual, "Start")
+		So(issues[1].Line, ShouldEqual, 4)
+		So(issues[2].String(), ShouldEqual, "Extra field (Extra) at line 5")
+		So(issues[3].String(), ShouldEqual, "Overflow (Map1.Key1.Int1) at line 8")
+		So(issues[4].Key, ShouldEqual, "Ints.Key1")
+		So(issues[5].String(), ShouldEqual, "Invalid key at line 14")
+		So(x.Name, ShouldEqual, "Morty")
+	})
+
+	Convey("Check a map", t, func() {
+		m := map[string]int{"Key1": 1}
+		issues := Check(m, "Key1 = 2\nKey2 = two")
+		So(len(issues), ShouldEqual, 1)
+		So(issues[0].Key, ShouldEqual, "Key2")
+		So(m["Key1"], ShouldEqual, 1)
+	})
+
+	Convey("Check with the settings of a decoder", t, func() {
+		var x struct {
+			Name string
+			Pin  int
+		}
+		src := "Name = Rick\nPin = ENC[MTIzNA==]\nColor = green"
+		So(Check(&x, src), ShouldHaveLength, 2)
+		d := NewDecoder(&x, ALLOW_EXTRA_FIELDS).WithDecrypter(func(s string) (string, error) {
+			bs, err := base64.StdEncoding.DecodeString(s)
+			return string(bs), err
+		})
+		So(d.Check(src), ShouldBeNil)
+		So(x.Pin, ShouldEqual, 0)
+		So(d.DecodeString(src), ShouldBeNil)
+		So(x.Pin, ShouldEqual, 1234)
+		So(d.Check("Pin = 1\nPin = 2"), ShouldHaveLength, 1)
+		So(d.Warnings(), ShouldResemble, []Warning{{"", 3, "Color", "Extra field"}})
+	})
+
+	Convey("Force error: Not a struct or a map", t, func() {
+		m := map[string]int{}
+		So(Check(&m, "Key1 = 2"), ShouldResemble, []Issue{{Msg: "Expecting pointer to a struct or a map"}})
+		So(Check(5, "Key1 = 2"), ShouldResemble, []Issue{{Msg: "Expecting pointer to a struct or a map"}})
+	})
+
+}
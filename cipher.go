@@ -0,0 +1,126 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// A Cipher encrypts and decrypts entire configuration files. Any scheme, such
+// as age, may be supported by implementing this interface. NewAESGCM returns
+// a Cipher using AES-GCM with a provided key.
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+var (
+	ciphersMu sync.RWMutex
+	ciphers   = make(map[string]Cipher)
+)
+
+// RegisterCipher selects a Cipher for every file with the given extension,
+// eg. ".enc", which is read or written by the file functions of this package,
+// including included files. A Cipher set with WithCipher takes precedence.
+// A nil Cipher removes the registration.
+func RegisterCipher(ext string, c Cipher) {
+	ciphersMu.Lock()
+	defer ciphersMu.Unlock()
+	if c == nil {
+		delete(ciphers, ext)
+		return
+	}
+	ciphers[ext] = c
+}
+
+// WithCipher sets a Cipher to decrypt every file read by DecodeFile,
+// including included files.
+func (o *Decoder) WithCipher(c Cipher) *Decoder {
+	o.cipher = c
+	return o
+}
+
+// WithCipher sets a Cipher to encrypt the file written by ToFile.
+func (o *Encoder) WithCipher(c Cipher) *Encoder {
+	o.cipher = c
+	return o
+}
+
+// Return the supplied Cipher, or the one registered for the file extension.
+func cipherFor(filename string, c Cipher) Cipher {
+	if c != nil {
+		return c
+	}
+	ciphersMu.RLock()
+	defer ciphersMu.RUnlock()
+	return ciphers[filepath.Ext(filename)]
+}
+
+// Open a configuration file, decrypting it if a Cipher applies.
+func openFile(filename string, c Cipher) (io.ReadCloser, error) {
+	fh, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, &notExistError{err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c = cipherFor(filename, c); c == nil {
+		return fh, nil
+	}
+	defer fh.Close()
+	bs, err := ioutil.ReadAll(fh)
+	if err != nil {
+		return nil, err
+	}
+	if bs, err = c.Decrypt(bs); err != nil {
+		return nil, fmt.Errorf("Cannot decrypt %s: %w", filename, err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(bs)), nil
+}
+
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM returns a Cipher using AES-GCM. The key must be 16, 24 or 32
+// bytes long. A random nonce is prepended to each encrypted file.
+func NewAESGCM(key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCM{aead}, nil
+}
+
+func (o *aesGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, o.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return o.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (o *aesGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := o.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, errors.New("Ciphertext too short")
+	}
+	return o.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCipher(t *testing.T) {
+
+	type cfg struct {
+		Name string
+		Port int
+	}
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	Convey("Encrypt and decrypt a file with WithCipher", t, func() {
+		c, err := NewAESGCM(key)
+		So(err, ShouldBeNil)
+		tempfile := createTempFile("GOTEST_CONFIG")
+		os.Remove(tempfile)
+		defer os.Remove(tempfile)
+
+		So(NewEncoder(cfg{"Rick", 80}).WithCipher(c).ToFile(tempfile), ShouldBeNil)
+		bs, _ := ioutil.ReadFile(tempfile)
+		So(bytes.Contains(bs, []byte("Rick")), ShouldBeFalse)
+
+		var x cfg
+		So(NewDecoder(&x).WithCipher(c).DecodeFile(tempfile), ShouldBeNil)
+		So(x, ShouldResemble, cfg{"Rick", 80})
+		So(DecodeFile(tempfile, &x), ShouldNotBeNil)
+	})
+
+	Convey("Select a cipher by file extension", t, func() {
+		c, _ := NewAESGCM(key)
+		RegisterCipher(".enc", c)
+		defer RegisterCipher(".enc", nil)
+		basefile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(basefile)
+		tempfile := basefile + ".enc"
+		defer os.Remove(tempfile)
+
+		So(EncodeToFile(cfg{"Morty", 81}, tempfile), ShouldBeNil)
+		var x cfg
+		So(DecodeFile(tempfile, &x), ShouldBeNil)
+		So(x, ShouldResemble, cfg{"Morty", 81})
+		smap, err := ParseFile(tempfile)
+		So(err, ShouldBeNil)
+		So(smap["Name"], ShouldEqual, "Morty")
+	})
+
+	Convey("Force error: Wrong key and bad key length", t, func() {
+		c1, _ := NewAESGCM(key)
+		c2, _ := NewAESGCM([]byte("fedcba9876543210"))
+		tempfile := createTempFile("GOTEST_CONFIG")
+		os.Remove(tempfile)
+		defer os.Remove(tempfile)
+		So(NewEncoder(cfg{"Rick", 80}).WithCipher(c1).ToFile(tempfile), ShouldBeNil)
+		var x cfg
+		So(NewDecoder(&x).WithCipher(c2).DecodeFile(tempfile), ShouldNotBeNil)
+		_, err := NewAESGCM([]byte("short"))
+		So(err, ShouldNotBeNil)
+	})
+
+}
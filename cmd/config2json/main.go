@@ -0,0 +1,110 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+/*
+Config2json converts configuration files to JSON and back.
+
+Usage:
+
+	config2json [flags] [path]
+
+Without an explicit path, config2json reads the standard input. Include
+directives are only followed when a path is given. Brace blocks become nested
+JSON objects; numbers and booleans are detected as described for config.ToJSON.
+
+The flags are:
+
+	-r
+		Reverse mode (json2config). Read a JSON object and write the
+		equivalent configuration.
+	-o file
+		Write the result to file instead of standard output.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/mkmueller/config"
+)
+
+var (
+	reverse = flag.Bool("r", false, "convert JSON to configuration (json2config)")
+	output  = flag.String("o", "", "write result to `file` instead of stdout")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: config2json [flags] [path]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() > 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	var res []byte
+	var err error
+	if *reverse {
+		res, err = json2config(flag.Arg(0))
+	} else {
+		res, err = config2json(flag.Arg(0))
+	}
+	if err == nil {
+		if *output != "" {
+			err = ioutil.WriteFile(*output, res, 0644)
+		} else {
+			_, err = os.Stdout.Write(res)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func config2json(filename string) ([]byte, error) {
+	var smap config.StringMap
+	var err error
+	if filename == "" {
+		smap, err = config.Parse(os.Stdin)
+	} else {
+		smap, err = config.ParseFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, err := config.ToJSON(smap)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = json.Indent(&buf, b, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func json2config(filename string) ([]byte, error) {
+	var src []byte
+	var err error
+	if filename == "" {
+		src, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		src, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config.FromJSON(src)
+}
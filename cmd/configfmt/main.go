@@ -0,0 +1,169 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+/*
+Configfmt formats configuration files.
+
+Usage:
+
+	configfmt [flags] [path ...]
+
+Without an explicit path, configfmt processes the standard input. By default
+the formatted result is written to standard output.
+
+The flags are:
+
+	-d
+		Do not print reformatted sources to standard output.
+		If a file's formatting is different than configfmt's, print diffs
+		to standard output.
+	-l
+		Do not print reformatted sources to standard output.
+		If a file's formatting is different from configfmt's, print its name
+		to standard output.
+	-w
+		Do not print reformatted sources to standard output.
+		If a file's formatting is different from configfmt's, overwrite it
+		with configfmt's version.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/mkmueller/config"
+)
+
+var (
+	list   = flag.Bool("l", false, "list files whose formatting differs from configfmt's")
+	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
+
+	exitCode = 0
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: configfmt [flags] [path ...]\n")
+	flag.PrintDefaults()
+}
+
+func report(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	exitCode = 2
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			report(fmt.Errorf("cannot use -w with standard input"))
+		} else if err := processFile("<standard input>", os.Stdin); err != nil {
+			report(err)
+		}
+		os.Exit(exitCode)
+	}
+
+	for _, path := range flag.Args() {
+		if err := processFile(path, nil); err != nil {
+			report(err)
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func processFile(filename string, in *os.File) error {
+	var src []byte
+	var err error
+	if in != nil {
+		src, err = ioutil.ReadAll(in)
+	} else {
+		src, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return err
+	}
+
+	res, err := config.Format(src)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	if bytes.Equal(src, res) {
+		if !*list && !*write && !*doDiff {
+			_, err = os.Stdout.Write(res)
+		}
+		return err
+	}
+
+	if *list {
+		fmt.Println(filename)
+	}
+	if *write {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(filename, res, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if *doDiff {
+		data, err := diff(src, res)
+		if err != nil {
+			return fmt.Errorf("computing diff: %s", err)
+		}
+		fmt.Printf("diff %s configfmt/%s\n", filename, filename)
+		os.Stdout.Write(data)
+	}
+	if !*list && !*write && !*doDiff {
+		_, err = os.Stdout.Write(res)
+	}
+	return err
+}
+
+// Run the system diff utility on two temporary files.
+func diff(b1, b2 []byte) ([]byte, error) {
+	f1, err := writeTempFile("configfmt", b1)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f1)
+
+	f2, err := writeTempFile("configfmt", b2)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f2)
+
+	data, err := exec.Command("diff", "-u", f1, f2).CombinedOutput()
+	if len(data) > 0 {
+		// diff exits with a non-zero status when the files don't match.
+		// Ignore that failure as long as we get output.
+		err = nil
+	}
+	return data, err
+}
+
+func writeTempFile(prefix string, data []byte) (string, error) {
+	file, err := ioutil.TempFile("", prefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
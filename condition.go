@@ -0,0 +1,85 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"regexp"
+)
+
+// A condition is a variable name, optionally negated, or a comparison of a
+// variable with a quoted or unquoted value.
+var conditionRegexp = regexp.MustCompile(`^(?:(!?)(\w+)|(\w+)\s*(==|!=)\s*(?:"([^"]*)"|([^"\s]+)))$`)
+
+// SetVars sets the variables of @if blocks. The keys of an @if block are
+// parsed as if they appeared in place of the block when the condition is
+// true, and are ignored otherwise, along with the files it includes. A
+// condition compares a variable with a value using == or !=, or tests that a
+// variable is set to anything other than an empty string, "0" or "false". An
+// unset variable is empty.
+//
+//	@if env == "prod" {
+//		Debug = false
+//	}
+//	@if !verbose {
+//		LogLevel = warn
+//	}
+func (o *Parser) SetVars(vars map[string]string) *Parser {
+	o.vars = vars
+	return o
+}
+
+// SetVars sets the variables of @if blocks, and of include directives under
+// the EXPAND_PATHS option. See Parser.SetVars.
+func (o *Decoder) SetVars(vars map[string]string) *Decoder {
+	o.vars = vars
+	return o
+}
+
+// Parse an @if block, adding its keys to the field map if the condition is
+// true. The files it includes are only loaded if the condition is true.
+func (o *Parser) parseCondition(fieldMap fMap, cond string, depth int) {
+	lineno := o.lineno
+	ok, cerr := o.evalCondition(cond)
+	if cerr != nil {
+		o.appendErr(cerr, lineno)
+	}
+	n := len(o.include)
+	emap, err := o.recursive_parse(depth + 1)
+	if err != nil {
+		if o.abort == nil {
+			o.appendErr(err, lineno)
+		}
+		return
+	}
+	o.sections++
+	if !ok || cerr != nil {
+		o.dropIncludes(n)
+		return
+	}
+	for k, val := range emap {
+		if o.addKey(fieldMap, k, val.no) {
+			fieldMap[k] = val
+		}
+	}
+}
+
+// Evaluate the condition of an @if block.
+func (o *Parser) evalCondition(cond string) (bool, error) {
+	a := conditionRegexp.FindStringSubmatch(cond)
+	if a == nil {
+		return false, errors.New("Invalid condition")
+	}
+	if a[2] != "" {
+		val := o.vars[a[2]]
+		set := val != "" && val != "0" && toLower(val) != "false"
+		return set != (a[1] == "!"), nil
+	}
+	val := a[5] + a[6]
+	if a[4] == "==" {
+		return o.vars[a[3]] == val, nil
+	}
+	return o.vars[a[3]] != val, nil
+}
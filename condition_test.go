@@ -0,0 +1,96 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConditions(t *testing.T) {
+
+	src := `
+		Name = app
+		@if env == "prod" {
+			Debug = false
+			Server {
+				Host = example.com
+			}
+		}
+		@if env != prod {
+			Debug = true
+		}
+		Server {
+			Port = 8080
+			@if verbose {
+				LogLevel = debug
+			}
+			@if !verbose {
+				LogLevel = warn
+			}
+		}
+	`
+
+	Convey("Keep the keys of true conditions", t, func() {
+		m, err := NewParser().SetVars(map[string]string{"env": "prod", "verbose": "yes"}).Parse([]byte(src))
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"Name": "app", "Debug": "false", "Server.Host": "example.com",
+			"Server.Port": "8080", "Server.LogLevel": "debug"})
+	})
+
+	Convey("Unset variables are empty", t, func() {
+		m, err := NewParser().Parse([]byte(src))
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"Name": "app", "Debug": "true", "Server.Port": "8080",
+			"Server.LogLevel": "warn"})
+	})
+
+	Convey("Decode with variables", t, func() {
+		var x struct {
+			Name   string
+			Debug  bool
+			Server struct {
+				Host     string
+				Port     int
+				LogLevel string
+			}
+		}
+		vars := map[string]string{"env": "prod", "verbose": "false"}
+		So(NewDecoder(&x).SetVars(vars).DecodeString(src), ShouldBeNil)
+		So(x.Debug, ShouldBeFalse)
+		So(x.Server.Host, ShouldEqual, "example.com")
+		So(x.Server.LogLevel, ShouldEqual, "warn")
+	})
+
+	Convey("Files are only included by true conditions", t, func() {
+		prodfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(prodfile)
+		writeFile(prodfile, []byte("Debug = 0\nSecret = prod"))
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Debug = 1\nSecret = base\n@if env == prod {\n  include "+prodfile+"\n}"))
+		var x struct {
+			Debug  int
+			Secret string
+		}
+		So(NewDecoder(&x).SetVars(map[string]string{"env": "dev"}).DecodeFile(tempfile), ShouldBeNil)
+		So(x.Debug, ShouldEqual, 1)
+		So(x.Secret, ShouldEqual, "base")
+		So(NewDecoder(&x).SetVars(map[string]string{"env": "prod"}).DecodeFile(tempfile), ShouldBeNil)
+		So(x.Debug, ShouldEqual, 0)
+		So(x.Secret, ShouldEqual, "prod")
+	})
+
+	Convey("Force error: Invalid conditions", t, func() {
+		_, err := Parse("A = 1\n@if env = prod {\nB = 1\n}")
+		So(err.Error(), ShouldEqual, "Invalid condition at line 2")
+		_, err = Parse("A = 1\n@if {\nB = 1\n}")
+		So(err.Error(), ShouldEqual, "Invalid condition at line 2")
+		_, err = NewParser().SetVars(map[string]string{"x": "1"}).Parse([]byte("A = 1\n@if x {\nA = 2\n}"))
+		So(err.Error(), ShouldEqual, "Duplicate key at line 3")
+	})
+
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A Config holds parsed configuration data which may be read and modified by
+// dotted path, eg. "Server.TLS.Cert", for programs which cannot predeclare a
+// struct. Values are converted exactly as the decoder would convert them. A
+// Config is not safe for concurrent modification.
+type Config struct {
+	m StringMap
+}
+
+// NewConfig returns an empty Config.
+func NewConfig() *Config {
+	return &Config{make(StringMap)}
+}
+
+// ParseConfig parses a string, a byte slice or an io.Reader to a Config.
+// Parser options are optional.
+func ParseConfig(src interface{}, options ...int) (*Config, error) {
+	smap, err := Parse(src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Config{smap}, nil
+}
+
+// ParseConfigFile parses a file, and any files it includes, to a Config.
+func ParseConfigFile(filename string, options ...int) (*Config, error) {
+	smap, err := ParseFile(filename, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Config{smap}, nil
+}
+
+// Has returns true if the path names a value, or a block containing values.
+func (o *Config) Has(path string) bool {
+	if _, ok := o.m[path]; ok {
+		return true
+	}
+	prefix := path + "."
+	for k := range o.m {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetString returns the value at path, or an empty string if there is none.
+func (o *Config) GetString(path string) string {
+	return o.m[path]
+}
+
+// GetInt returns the value at path as an int. An error is returned if the path
+// does not exist or its value cannot be converted.
+func (o *Config) GetInt(path string) (int, error) {
+	var i int
+	err := o.get(path, &i)
+	return i, err
+}
+
+// GetFloat returns the value at path as a float64.
+func (o *Config) GetFloat(path string) (float64, error) {
+	var f float64
+	err := o.get(path, &f)
+	return f, err
+}
+
+// GetBool returns the value at path as a bool. True, yes, on and 1, or false,
+// no, off and 0, are accepted in any case. An error is returned if the path
+// does not exist or its value is not one of these.
+func (o *Config) GetBool(path string) (bool, error) {
+	var b bool
+	err := o.get(path, &b)
+	if err == nil && !isBool(o.m[path]) {
+		err = errors.New("Invalid boolean value (" + path + ")")
+	}
+	return b, err
+}
+
+// GetTime returns the value at path as a time.Time.
+func (o *Config) GetTime(path string) (time.Time, error) {
+	var t time.Time
+	err := o.get(path, &t)
+	return t, err
+}
+
+// Set assigns a value to path, replacing any value or block already there.
+// Strings are stored as is. Other values are stored as they would be encoded.
+func (o *Config) Set(path string, value interface{}) {
+	o.Delete(path)
+	o.m[path] = formatValue(value)
+}
+
+// Delete removes the value at path, or every value in the block at path.
+func (o *Config) Delete(path string) {
+	delete(o.m, path)
+	prefix := path + "."
+	for k := range o.m {
+		if strings.HasPrefix(k, prefix) {
+			delete(o.m, k)
+		}
+	}
+}
+
+// Map returns the dotted key/value pairs held by the Config.
+func (o *Config) Map() StringMap {
+	return o.m
+}
+
+// Convert the value at path into the value pointed to by x.
+func (o *Config) get(path string, x interface{}) error {
+	val, ok := o.m[path]
+	if !ok {
+		return errors.New("Key not found (" + path + ")")
+	}
+	if err := setScalar(reflect.ValueOf(x).Elem(), val); err != nil {
+		return errors.New(err.Error() + " (" + path + ")")
+	}
+	return nil
+}
+
+// Return the string representation of a value as the encoder would write it.
+func formatValue(value interface{}) string {
+	switch t := value.(type) {
+	case string:
+		return t
+	case bool:
+		if t {
+			return "True"
+		}
+		return "False"
+	case time.Time:
+		switch {
+		case isTimeOnly(t):
+			return t.Format(time_fmt)
+		case isDateOnly(t):
+			return t.Format(date_fmt)
+		case isDateTime(t):
+			return t.Format(date_time)
+		case isUTCTime(t):
+			return t.Format(utc_time)
+		}
+		return t.Format(utc_date)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfig(t *testing.T) {
+
+	src := `
+		Name = Rick
+		Server {
+			Port  = 8080
+			Ratio = 0.5
+			TLS {
+				Cert    = /etc/ssl/rick.pem
+				Enabled = yes
+			}
+		}
+		Started = 2018-06-01
+	`
+
+	Convey("Get values by dotted path", t, func() {
+		c, err := ParseConfig(src)
+		So(err, ShouldBeNil)
+		So(c.GetString("Server.TLS.Cert"), ShouldEqual, "/etc/ssl/rick.pem")
+		So(c.GetString("Server.TLS.Key"), ShouldEqual, "")
+		i, err := c.GetInt("Server.Port")
+		So(err, ShouldBeNil)
+		So(i, ShouldEqual, 8080)
+		f, err := c.GetFloat("Server.Ratio")
+		So(err, ShouldBeNil)
+		So(f, ShouldEqual, 0.5)
+		b, err := c.GetBool("Server.TLS.Enabled")
+		So(err, ShouldBeNil)
+		So(b, ShouldBeTrue)
+		d, err := c.GetTime("Started")
+		So(err, ShouldBeNil)
+		So(d, ShouldEqual, time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC))
+	})
+
+	Convey("Has", t, func() {
+		c, _ := ParseConfig(src)
+		So(c.Has("Name"), ShouldBeTrue)
+		So(c.Has("Server.TLS"), ShouldBeTrue)
+		So(c.Has("Server.TL"), ShouldBeFalse)
+		So(c.Has("Morty"), ShouldBeFalse)
+	})
+
+	Convey("Set and Delete", t, func() {
+		c := NewConfig()
+		c.Set("Server.Port", 81)
+		c.Set("Server.TLS", true)
+		c.Set("Started", time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC))
+		So(c.GetString("Server.Port"), ShouldEqual, "81")
+		So(c.GetString("Server.TLS"), ShouldEqual, "True")
+		So(c.GetString("Started"), ShouldEqual, "2018-06-01")
+		c.Set("Server", "none")
+		So(c.Map(), ShouldResemble, StringMap{"Server": "none", "Started": "2018-06-01"})
+		c.Delete("Server")
+		So(c.Has("Server"), ShouldBeFalse)
+	})
+
+	Convey("Force error: Missing keys and invalid values", t, func() {
+		c, _ := ParseConfig(src)
+		_, err := c.GetInt("Server.Host")
+		So(err.Error(), ShouldEqual, "Key not found (Server.Host)")
+		_, err = c.GetInt("Name")
+		So(err, ShouldNotBeNil)
+		b, err := c.GetBool("Name")
+		So(err.Error(), ShouldEqual, "Invalid boolean value (Name)")
+		So(b, ShouldBeFalse)
+		_, err = ParseConfig("Key1={")
+		So(err, ShouldNotBeNil)
+	})
+
+}
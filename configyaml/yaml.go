@@ -0,0 +1,69 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+/*
+Package configyaml converts between the config file format and YAML. It lives
+in its own package so that the config package itself remains free of third
+party dependencies.
+
+Values are typed the same way config.ToJSON types them: plain numbers and the
+words true and false become YAML numbers and booleans, everything else is a
+string. YAML sequences and null values have no equivalent in the config format
+and are rejected by FromYAML.
+*/
+package configyaml
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/mkmueller/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ToYAML parses a string, a byte slice, an io.Reader or a config.StringMap and
+// returns the equivalent YAML document. Brace blocks become nested mappings.
+func ToYAML(src interface{}) ([]byte, error) {
+	b, err := config.ToJSON(src)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err = d.Decode(&m); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(numbers(m))
+}
+
+// FromYAML converts a YAML mapping to configuration format. Nested mappings
+// are encoded as brace blocks and zero values are retained.
+func FromYAML(bs []byte) ([]byte, error) {
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(bs, &m); err != nil {
+		return nil, err
+	}
+	return config.Encode(m, config.ENCODE_ZERO_VALUES)
+}
+
+// Replace json.Number values with int64 or float64 so that the YAML encoder
+// emits them unquoted.
+func numbers(m map[string]interface{}) map[string]interface{} {
+	for k, val := range m {
+		switch t := val.(type) {
+		case map[string]interface{}:
+			numbers(t)
+		case json.Number:
+			if i, err := t.Int64(); err == nil {
+				m[k] = i
+			} else if f, err := t.Float64(); err == nil {
+				m[k] = f
+			} else {
+				m[k] = t.String()
+			}
+		}
+	}
+	return m
+}
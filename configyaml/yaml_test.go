@@ -0,0 +1,63 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package configyaml
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestToYAML(t *testing.T) {
+
+	Convey("Convert a configuration to YAML", t, func() {
+		cfg := `
+			Name   = Rick
+			Active = True
+			Server {
+				Host = localhost
+				Port = 8080
+			}
+		`
+		b, err := ToYAML(cfg)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "Active: true\n"+
+			"Name: Rick\n"+
+			"Server:\n"+
+			"    Host: localhost\n"+
+			"    Port: 8080\n")
+	})
+
+	Convey("Force error: Parse error", t, func() {
+		_, err := ToYAML("Key1={Key=2")
+		So(err, ShouldNotBeNil)
+	})
+
+}
+
+func TestFromYAML(t *testing.T) {
+
+	Convey("Convert YAML to configuration format", t, func() {
+		src := "Name: Rick\nServer:\n  Host: localhost\n  Port: 8080\n  TLS: false\n"
+		b, err := FromYAML([]byte(src))
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "Name = Rick\n"+
+			"Server = {\n"+
+			"  Host = localhost\n"+
+			"  Port = 8080\n"+
+			"  TLS = False\n"+
+			"}\n")
+	})
+
+	Convey("Force error: YAML sequences are not allowed", t, func() {
+		_, err := FromYAML([]byte("Hosts:\n  - a\n  - b\n"))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Force error: Invalid YAML", t, func() {
+		_, err := FromYAML([]byte("Key: [\n"))
+		So(err, ShouldNotBeNil)
+	})
+
+}
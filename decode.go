@@ -5,16 +5,52 @@
 /*
 Config provides encoding and decoding routines for configuration files. This
 package supports most of the built-in datatypes, including string, int8-64,
-uint8-64, float32-64, time.Time, struct, and string-keyed maps. Deeply nested
-structs are supported as well as maps of structs. The data types not supported
-are complex64/128, byte arrays, and slices.
+uint8-64, float32-64, complex64-128, time.Time, time.Duration, big.Int,
+big.Float, big.Rat, struct, and string-keyed maps. Deeply nested structs are
+supported as well as maps of structs. The data types not supported are byte
+arrays and slices, with the exception of the network address types net.IP,
+net.IPNet, net.HardwareAddr, netip.Addr, netip.AddrPort and netip.Prefix.
+Absolute URLs may be decoded to url.URL, octal permissions such as 0644 to
+os.FileMode, and time zone names such as America/New_York to *time.Location.
 
 This package also provides a Parse function which will allow any configuration
 data to be parsed directly into a string map.
 
-At this writing, struct tags are not supported. However, optional flags provide
-a means to convert all fields to lower case or snake_case for encoding and
-decoding.
+Gzip compressed input, such as a file named app.conf.gz, is detected by its
+magic bytes and decompressed transparently when decoding or parsing.
+
+A value may refer to another key with ${Key}, eg. URL = http://${Server.Host}/,
+to avoid repeating base URLs, paths and ports. References are resolved after
+parsing, and may name keys of the same source or of a file which included it.
+
+Integer and float values may be written as arithmetic expressions using +, -,
+*, / and parentheses, eg. CacheSize = 4 * 1024 * 1024 or Timeout = ${Base} + 30.
+
+A block may be defined once as an anchor and reused by aliases, which may
+override some of its keys:
+
+	&defaults {
+		Host = localhost
+		Port = 80
+	}
+	Server1 = *defaults {
+		Port = 81
+	}
+	Server2 = *defaults
+
+A file may include another with include other.conf. The keys of the included
+file may be placed within a block, so that a self-contained component file
+can be reused, eg. include backend.conf as Database
+
+An unquoted ~ or null value explicitly unsets a key. The decoder sets the
+field to its zero value, a pointer to nil, or deletes the map entry, so that
+an override file may remove a default.
+
+The key of a struct field may be changed with a config tag, which may also
+list alternative keys accepted when decoding, eg.
+`config:"timeout,alias=timeout_seconds"`. A key of "-" skips the field.
+Optional flags provide a means to convert all fields to lower case or
+snake_case for encoding and decoding.
 */
 package config
 
@@ -24,11 +60,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"log/slog"
+	"math/big"
 	"os"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -60,9 +103,142 @@ const (
 	// OVERWRITE_FILE will cause the function EncodeToFile() to overwrite the
 	// supplied filename if it already exists.
 	OVERWRITE_FILE
+
+	// BACKUP_FILE will cause the function EncodeToFile() to copy an existing
+	// file to the same name plus ".bak" before overwriting it.
+	BACKUP_FILE
+
+	// SECURE_FILE will cause the function EncodeToFile() to write the file,
+	// and any backup, with 0600 permissions, and to refuse to write through a
+	// symbolic link. Use this for files which contain secrets.
+	SECURE_FILE
+
+	// SECURE_DIR will cause the function EncodeToFile() to refuse to write to
+	// a directory which other users may read or write.
+	SECURE_DIR
+
+	// LOCK_FILE will cause DecodeFile() to take a shared lock, and
+	// EncodeToFile() an exclusive lock, on the file name plus ".lock" while
+	// reading or writing, so that processes sharing a configuration file do
+	// not interleave. The lock is advisory and is only supported on Unix.
+	LOCK_FILE
+
+	// EXPAND_PATHS will cause DecodeFile() and ParseFile() to expand a leading
+	// ~ or ~user, and $VAR or ${VAR} environment variables, in the supplied
+	// file name and in include directives, eg. include ~/.app/local.conf
+	// The variables set by SetVars take precedence over the environment, eg.
+	// include /etc/app/${env}.conf
+	EXPAND_PATHS
+
+	// SAFE_MODE will cause the parser to apply SafeLimits to the input, for
+	// parsing untrusted configuration data. See Limits.
+	SAFE_MODE
+
+	// ENCODE_ABBREVIATIONS will cause the encoder to write integers with the
+	// shortest exact decimal or binary suffix, eg. 2000 == 2K, 1048576 == 1Mi.
+	ENCODE_ABBREVIATIONS
+
+	// ALLOW_INT_PERCENT will cause the decoder to accept a percentage for an
+	// integer field, eg. 75% == 75. A percentage is always accepted for a
+	// float field, eg. 75% == 0.75.
+	ALLOW_INT_PERCENT
+
+	// ALLOW_EPOCH_TIMES will cause the decoder to accept an integer number of
+	// seconds since the Unix epoch for a time.Time field. A field tagged with
+	// `epoch:"s"` or `epoch:"ms"` accepts seconds or milliseconds, and is
+	// encoded in the same way.
+	ALLOW_EPOCH_TIMES
+
+	// FIRST_KEY_WINS will cause the parser to keep the first value of a key
+	// which is repeated, rather than reporting a duplicate key. A repeated
+	// block is merged with the first.
+	FIRST_KEY_WINS
+
+	// LAST_KEY_WINS will cause the parser to keep the last value of a key
+	// which is repeated, rather than reporting a duplicate key. A repeated
+	// block is merged with the first, overriding its keys.
+	LAST_KEY_WINS
+
+	// STRICT_NUMBERS will cause the decoder, and ParseMap, to accept only
+	// exact numeric literals, eg. Port = 8K is an error rather than 8000.
+	// Abbreviations, comma grouping, unit suffixes and percentages are
+	// rejected.
+	STRICT_NUMBERS
+
+	// ENCODE_FLAT will cause the encoder to write nested structs and maps as
+	// dotted keys rather than brace blocks, eg. Server.Tls.Port = 443
+	ENCODE_FLAT
+
+	// ENCODE_INLINE_BLOCKS will cause the encoder to write a nested struct or
+	// map of scalar values on one line when it fits, eg.
+	// Limits = { Min = 1, Max = 10 }
+	ENCODE_INLINE_BLOCKS
+
+	// ALLOW_KEBAB_CASE will cause the decoder to interpret kebab case fields
+	// in the configuration file, eg. max-conns == MaxConns. Decode will
+	// attempt to find the actual struct field before trying kebab case.
+	ALLOW_KEBAB_CASE
+
+	// ENCODE_KEBAB_CASE will cause the encoder to convert all fields into
+	// kebab case, eg. DarkMatter == dark-matter.
+	ENCODE_KEBAB_CASE
+
+	// ALLOW_SCREAMING_SNAKE_CASE will cause the decoder to interpret upper
+	// snake case fields in the configuration file, eg. MAX_CONNS == MaxConns,
+	// matching the names of environment variables. Decode will attempt to
+	// find the actual struct field before trying upper snake case.
+	ALLOW_SCREAMING_SNAKE_CASE
+
+	// ENCODE_SCREAMING_SNAKE_CASE will cause the encoder to convert all fields
+	// into upper snake case, eg. DarkMatter == DARK_MATTER.
+	ENCODE_SCREAMING_SNAKE_CASE
+
+	// FOLD_MAP_KEYS will cause the decoder to convert the keys of maps to
+	// lower case, so that keys which differ only in case, eg. Host and host,
+	// become one map entry. See Decoder.SetMapKeyFunc.
+	FOLD_MAP_KEYS
+
+	// STRICT_KEYS will cause the decoder to report a field which is given a
+	// value under more than one of the keys allowed by the case options, eg.
+	// MaxConns and max_conns, rather than using the first in order of
+	// precedence. See Decoder.SetKeyPrecedence.
+	STRICT_KEYS
+
+	// ALLOW_EXTRA_FIELDS will cause the decoder to report keys which do not
+	// match a field as warnings rather than errors. See Decoder.Warnings.
+	ALLOW_EXTRA_FIELDS
+
+	// FAIL_FAST will cause the parser, and the decoder, to stop at the first
+	// error, rather than reporting every error in the source. This is the
+	// same as a MaxErrors limit of 1. See Limits.
+	FAIL_FAST
+
+	// COLLECT_ERRORS will cause the decoder to continue after an error in the
+	// source or in a value, so that every error is reported at once, up to
+	// the MaxErrors limit. Without it, the decoder stops at the first error
+	// in a value. See Limits.
+	COLLECT_ERRORS
+
+	// ENCODE_SORTED_FIELDS will cause the encoder to write the fields of a
+	// struct sorted by key, after any case option is applied, rather than in
+	// the order they are declared. The keys of maps are always sorted.
+	ENCODE_SORTED_FIELDS
+
+	// option_limit follows the last option. Options are ints, so every option
+	// must fit in 32 bits; further encoder settings belong in setters.
+	option_limit
 )
 
+// Fails to compile once there are more options than fit in a 32-bit int.
+const _ int32 = option_limit - 1
+
 // The Decoder converts the parsed data to the expected data type and assignes it to a struct.
+//
+// A Decoder may decode several sources in turn into the same struct or map.
+// Each source overrides the values of the keys it contains and leaves other
+// values unchanged. Maps, including maps of structs, are merged: existing
+// entries are kept and entries in the source are added or overridden. Errors
+// and Metadata describe the most recent source only.
 type Decoder struct {
 	reader   io.Reader
 	lineno   int
@@ -72,6 +248,34 @@ type Decoder struct {
 	parser   *Parser
 	isMap    bool
 	errs     []error
+	check    bool
+	issues   []Issue
+	meta     Metadata
+	unknown  func(key string, line int, value string) error
+	hooks    []DecodeHook
+	decrypt  func(ciphertext string) (string, error)
+	cipher   Cipher
+	verify   VerifyFunc
+	limits   Limits
+	depth    int
+	incl     includeState
+	schemes  []string
+	tag      reflect.StructTag
+	loc      *time.Location
+	profile  string
+	vars     map[string]string
+	refs     map[string]string
+	set      map[string]bool
+	mapper   NameMapper
+	mapKeyFn func(string) string
+	precedence []int
+	warnings   []Warning
+	logger     *slog.Logger
+	tracing    bool
+	trace      []TraceEntry
+	collected  []error
+	resolver   IncludeResolver
+	prefix     string // Key prefix of the file being included
 }
 
 
@@ -85,7 +289,6 @@ func NewDecoder(x interface{}, options ...int) *Decoder {
 			panic("Expecting map with string keys")
 		}
 		o.isMap = true
-		return o
 	case isStructPtr(x):
 		break
 	default:
@@ -97,30 +300,33 @@ func NewDecoder(x interface{}, options ...int) *Decoder {
 		}
 		o.options = options[0]
 	}
+	if isOption(SAFE_MODE, o.options) {
+		o.limits = SafeLimits
+	}
 	return o
 }
 
 func (o *Decoder) allowedOption(option int) bool {
-	return option == option&(ALLOW_SNAKE_CASE|ENCODE_SNAKE_CASE|IGNORE_CASE|ENCODE_LOWER_CASE)
+	return option == option&(ALLOW_SNAKE_CASE|ENCODE_SNAKE_CASE|IGNORE_CASE|ENCODE_LOWER_CASE|LOCK_FILE|EXPAND_PATHS|SAFE_MODE|ALLOW_INT_PERCENT|STRICT_NUMBERS|ALLOW_EPOCH_TIMES|FIRST_KEY_WINS|LAST_KEY_WINS|ALLOW_KEBAB_CASE|ALLOW_SCREAMING_SNAKE_CASE|FOLD_MAP_KEYS|STRICT_KEYS|ALLOW_EXTRA_FIELDS|FAIL_FAST|COLLECT_ERRORS)
 }
 
 // DecodeStream will accept an io.Reader
 func (o *Decoder) DecodeStream(r io.Reader) error {
-	o.parser = NewParser()
+	o.parser = o.newParser()
 	o.reader = r
 	return o.decode()
 }
 
 // DecodeBytes will accept a byteslice
 func (o *Decoder) DecodeBytes(bs []byte) error {
-	o.parser = NewParser()
+	o.parser = o.newParser()
 	o.reader = bytes.NewReader(bs)
 	return o.decode()
 }
 
 // DecodeString will accept a string
 func (o *Decoder) DecodeString(s string) error {
-	o.parser = NewParser()
+	o.parser = o.newParser()
 	o.reader = strings.NewReader(s)
 	return o.decode()
 }
@@ -142,24 +348,98 @@ func Decode(x interface{}, src interface{}, options ...int) error {
 
 // DecodeFile will decode the supplied filename
 func (o *Decoder) DecodeFile(filename string) error {
+	return o.decodeFile("", filename)
+}
+
+// Decode a file, and the files it includes. from is the name of the including
+// file, or empty.
+func (o *Decoder) decodeFile(from, filename string) error {
 	var err error
-	fh, err := os.Open(filename)
+	if o.depth == 0 {
+		o.incl = includeState{}
+		o.reset()
+	}
+	o.depth++
+	defer func() { o.depth-- }()
+	if isOption(EXPAND_PATHS, o.options) {
+		if filename, err = expandPathVars(filename, o.vars); err != nil {
+			return err
+		}
+	}
+	unlock, err := o.lockSource(from, filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	fh, err := o.openSource(from, filename)
 	if err != nil {
 		return err
 	}
 	defer fh.Close()
-	if err = o.DecodeStream(fh); err != nil {
+	o.debug("config: reading file", "file", filename)
+	o.parser = o.newParser()
+	o.parser.filename = filename
+	o.reader = fh
+	if from != "" {
+		o.reader = o.incl.count(fh, filename, o.limits)
+	}
+	if err = o.decode(); err != nil {
 		return err
 	}
 	fh.Close()
-	for _, f := range o.parser.include {
-		if err := o.DecodeFile(f); err != nil {
-			o.appendErr("%s\n", err.Error())
+	p, prefix := o.parser, o.prefix
+	defer func() { o.prefix = prefix }()
+	for i, f := range p.include {
+		if err := o.incl.add(f, o.limits); err != nil {
+			o.errs = append(o.errs, err)
+			break
+		}
+		o.prefix = includeKey(prefix, p.includeAs[i])
+		if err := o.decodeFile(filename, f); err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%w\n", err))
 		}
 	}
 	return o.getErrs()
 }
 
+// Take a shared lock on a file under the LOCK_FILE option. The lock is only
+// taken on a file which exists, so that no lock file is left behind. Returns a
+// function to release the lock.
+func (o *Decoder) lockSource(from, filename string) (func(), error) {
+	if !isOption(LOCK_FILE, o.options) || o.resolved(from) {
+		return func() {}, nil
+	}
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil, &notExistError{err}
+	}
+	return lockFile(filename, false)
+}
+
+// Open a file to be decoded, decrypting it and verifying its signature as
+// configured by WithCipher and WithVerifier.
+func (o *Decoder) openSource(from, filename string) (io.ReadCloser, error) {
+	fh, err := o.openFile(from, filename, o.cipher)
+	if err != nil || o.verify == nil {
+		return fh, err
+	}
+	defer fh.Close()
+	r, err := verifyFile(filename, fh, o.verify)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(r), nil
+}
+
+// DecodeFileIfExists is like DecodeFile, but a file which does not exist is
+// not an error. The supplied struct or map is left unchanged in that case.
+func (o *Decoder) DecodeFileIfExists(filename string) error {
+	err := o.DecodeFile(filename)
+	if errors.Is(err, ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
 func (o *Decoder) appendErr(s string, v interface{}) {
 	o.errs = append(o.errs, errors.New(fmt.Sprintf(s, v)))
 }
@@ -170,27 +450,79 @@ func (o *Decoder) getErrs() error {
 		s += e.Error() + "\n"
 	}
 	if s != "" {
-		return errors.New(s)
+		return &multiError{s, o.errs}
 	}
 	return nil
 }
 
 // Decode the supplied source
+// Clear the errors, references, warnings and trace of the previous decode.
+func (o *Decoder) reset() {
+	o.errs = nil
+	o.refs = nil
+	o.warnings = nil
+	o.trace = nil
+}
+
 func (o *Decoder) decode() error {
 	var err error
+	if o.depth == 0 {
+		o.reset()
+	}
 	o.parser.reader = bufio.NewReader(o.reader)
 	o.fieldMap, err = o.parser.parse()
-	if err != nil {
+	o.warnings = append(o.warnings, o.parser.warnings...)
+	o.trace = append(o.trace, o.parser.trace...)
+	if err != nil && (!isOption(COLLECT_ERRORS, o.options) || o.parser.abort != nil) {
+		return err
+	}
+	if o.prefix != "" {
+		o.fieldMap = prefixKeys(o.prefix, o.fieldMap)
+	}
+	o.addRefs(o.fieldMap)
+	return o.assign(o.parser.errs...)
+}
+
+// Assign the values in the field map to the supplied struct or map. Under
+// the COLLECT_ERRORS option, the supplied errors of the parser are reported
+// along with those of the decoder.
+func (o *Decoder) assign(errs ...error) error {
+	o.collected = errs
+	err := o.assignValues()
+	switch {
+	case len(o.collected) == 0:
+		return err
+	case err == nil || err == errMaxErrors:
+		return getErrors(o.collected)
+	}
+	return getErrors(append(o.collected, err))
+}
+
+func (o *Decoder) assignValues() error {
+	var err error
+	o.meta = Metadata{}
+	if err = o.decryptValues(); err != nil {
 		return err
 	}
 	if o.isMap {
 		v1 := reflect.ValueOf(o.v)
+		if isInterfaceMap(v1.Type()) {
+			return o.traverseInterfaceMap(v1, "")
+		}
 		vt := v1.Type().Elem()
+		if err := o.foldMapKeys("", true); err != nil {
+			return err
+		}
 		for k, _ := range o.fieldMap {
+			if o.isNull(k) {
+				v1.SetMapIndex(reflect.ValueOf(unquoteKey(k)), reflect.Value{})
+				continue
+			}
 			newValue := reflect.New(vt).Elem()
 			if val, _, ok := o.getValue(k); ok {
-				if err := setScalar(newValue, val); err == nil {
-					v1.SetMapIndex(reflect.ValueOf(k), newValue)
+				if err := o.setValue(newValue, val); err == nil {
+					v1.SetMapIndex(reflect.ValueOf(unquoteKey(k)), newValue)
+					o.markSet(unquoteKey(k))
 				}
 			}
 		}
@@ -209,60 +541,155 @@ func DecodeFile(filename string, x interface{}, options ...int) error {
 	return NewDecoder(x, options...).DecodeFile(filename)
 }
 
+// DecodeFileIfExists will decode the supplied file, if it exists, into the
+// supplied struct. Decoder options are optional.
+func DecodeFileIfExists(filename string, x interface{}, options ...int) error {
+	return NewDecoder(x, options...).DecodeFileIfExists(filename)
+}
+
 func (o *Decoder) findExtraFields() error {
-	var err error
-	var msg string
+	if o.unknown != nil {
+		return o.callUnknown()
+	}
+	var errs []error
+	for k, v := range o.fieldMap {
+		if v.isDefined {
+			continue
+		}
+		if isOption(ALLOW_EXTRA_FIELDS, o.options) {
+			w := Warning{v.src, v.no, k, "Extra field"}
+			o.warnings = append(o.warnings, w)
+			logWarning(o.logger, w)
+			continue
+		}
+		errs = append(errs, &ParseError{File: v.src, Line: v.no, Msg: "Extra field (" + k + ")", Err: ErrExtraField})
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		a, b := errs[i].(*ParseError), errs[j].(*ParseError)
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Msg < b.Msg
+	})
+	if max := maxErrors(o.options, o.limits); max > 0 {
+		// the errors already collected count towards the limit
+		n := max - len(o.collected)
+		if n < 0 {
+			n = 0
+		}
+		if len(errs) > n {
+			errs = errs[:n]
+		}
+	}
+	return getErrors(errs)
+}
+
+// OnUnknownKey sets a function to be called, in line order, for each key in
+// the source which does not match a field, instead of reporting an "Extra
+// field" error. Errors returned by fn are combined and returned by Decode.
+// Return nil to accept the key.
+func (o *Decoder) OnUnknownKey(fn func(key string, line int, value string) error) *Decoder {
+	o.unknown = fn
+	return o
+}
+
+func (o *Decoder) callUnknown() error {
+	var keys []string
 	for k, v := range o.fieldMap {
 		if !v.isDefined {
-			if msg != "" {
-				msg += "\n"
-			}
-			msg += fmt.Sprintf("Extra field (%s) at line %v", k, v.no)
+			keys = append(keys, k)
 		}
 	}
-	if msg != "" {
-		err = errors.New(msg)
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := o.fieldMap[keys[i]], o.fieldMap[keys[j]]
+		if a.no != b.no {
+			return a.no < b.no
+		}
+		return keys[i] < keys[j]
+	})
+	var errs []error
+	for _, k := range keys {
+		v := o.fieldMap[k]
+		if err := o.unknown(k, v.no, v.val); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return err
+	return getErrors(errs)
 }
 
 func (o *Decoder) traverseStruct(v1 reflect.Value, parent_key string) error {
+	if claimed, err := o.hookValue(v1, parent_key); claimed {
+		return err
+	}
+	if o.setNull(v1, parent_key) {
+		return nil
+	}
+	if isBigType(v1.Type()) || isTextType(v1.Type()) || v1.Type() == locationType {
+		return o.traverseScalar(v1, parent_key)
+	}
 	switch v1.Kind() {
 	case reflect.Slice:
-		return newError(parent_key+" type slice not allowed", 0)
+		return o.fail(parent_key, parent_key+" type slice not allowed", 0)
 	case reflect.Struct:
 		return o.iterateStructFields(v1, parent_key)
 	case reflect.Map:
 		return o.traverseMap(v1, parent_key)
-	case reflect.Interface, reflect.Ptr:
+	case reflect.Interface:
 		return o.traverseStruct(v1.Elem(), parent_key)
-	default:
-		if val, lineno, ok := o.getValue(parent_key); ok && v1.CanSet() {
-			if err := setScalar(v1, val); err != nil {
-				return newError(err.Error(),lineno)
+	case reflect.Ptr:
+		if v1.IsNil() {
+			if !v1.CanSet() || !o.hasKey(parent_key) {
+				return nil
 			}
+			v1.Set(reflect.New(v1.Type().Elem()))
+		}
+		return o.traverseStruct(v1.Elem(), parent_key)
+	default:
+		return o.traverseScalar(v1, parent_key)
+	}
+}
+
+func (o *Decoder) traverseScalar(v1 reflect.Value, parent_key string) error {
+	if err := o.checkAmbiguous(parent_key); err != nil {
+		return err
+	}
+	val, lineno, ok := o.getValue(parent_key)
+	if !ok {
+		o.missing(v1, parent_key)
+	} else if v1.CanSet() {
+		if err := o.setValue(v1, val); err != nil {
+			return o.failValue(parent_key, err, lineno)
 		}
+		o.checkValue(v1, parent_key, val)
+		o.markSet(parent_key)
 	}
 	return nil
 }
 
 func (o *Decoder) iterateStructFields(v1 reflect.Value, parent_key string) error {
 	if isTimeType(v1.Type()) {
-		if val, lineno, ok := o.getValue(parent_key); ok && v1.CanSet() {
-			if err := set_time(v1, val); err != nil {
-				return newError(err.Error(), lineno)
-			}
-		}
-		return nil
+		return o.traverseScalar(v1, parent_key)
 	}
+	tag := o.tag
+	defer func() { o.tag = tag }()
 	for i, n := 0, v1.NumField(); i < n; i++ {
-		this_key := v1.Type().Field(i).Name
-		if !isPublic(this_key) {
+		this_key := fieldKey(v1.Type().Field(i))
+		if this_key == "" {
+			o.debug("config: field skipped", "field", v1.Type().Field(i).Name)
 			continue
 		}
 		if parent_key != "" {
 			this_key = parent_key + "." + this_key
 		}
+		// the tag of the field being decoded
+		o.tag = v1.Type().Field(i).Tag
+		ft := parseFieldTag(v1.Type().Field(i))
+		if err := o.applyAliases(parent_key, this_key, ft.aliases); err != nil {
+			return err
+		}
+		if ft.deprecated && o.hasKey(this_key) {
+			o.warn(this_key, "Deprecated key")
+		}
 		if err := o.traverseStruct(v1.Field(i), this_key); err != nil {
 			return err
 		}
@@ -271,23 +698,44 @@ func (o *Decoder) iterateStructFields(v1 reflect.Value, parent_key string) error
 }
 
 func (o *Decoder) traverseMap(v1 reflect.Value, parent_key string) error {
+	if isInterfaceMap(v1.Type()) {
+		return o.traverseInterfaceMap(v1, parent_key)
+	}
 	if v1.Type().Elem().Kind() != reflect.Struct {
 		return o.traverseScalarMap(v1, parent_key)
 	}
 	if isTimeType(v1.Type().Elem()) {
 		return o.traverseScalarMap(v1, parent_key)
 	}
-	v1.Set(reflect.MakeMap(v1.Type()))
-	pkey := setKeyCase(o.options, parent_key)
+	// the tag of the map field does not apply to the fields of its elements
+	tag := o.tag
+	o.tag = ""
+	defer func() { o.tag = tag }()
+	if v1.IsNil() {
+		v1.Set(reflect.MakeMap(v1.Type()))
+	}
+	pkey := o.mapPrefix(parent_key)
+	if err := o.foldMapKeys(pkey+".", false); err != nil {
+		return err
+	}
 	for mapkey, v := range o.fieldMap {
 		v.kind = v1.Kind()
 		if strings.Index(mapkey, pkey+".") == 0 {
 			l := len(pkey) + 1
 
-			if i := strings.Index(mapkey[l:], "."); i >= 0 {
-				k := mapkey[l : l+i]
+			if v.val == null_value && keyIndex(mapkey[l:]) < 0 {
+				v.isDefined = true
+				v1.SetMapIndex(reflect.ValueOf(unquoteKey(mapkey[l:])), reflect.Value{})
+				continue
+			}
+			if i := keyIndex(mapkey[l:]); i >= 0 {
+				k := unquoteKey(mapkey[l : l+i])
 				key := mapkey[0 : l+i]
 				newValue := reflect.New(v1.Type().Elem()).Elem()
+				// merge with an existing value
+				if old := v1.MapIndex(reflect.ValueOf(k)); old.IsValid() {
+					newValue.Set(old)
+				}
 				if err := o.traverseStruct(newValue, key); err != nil {
 					return err
 				}
@@ -299,16 +747,29 @@ func (o *Decoder) traverseMap(v1 reflect.Value, parent_key string) error {
 }
 
 func (o *Decoder) traverseScalarMap(v1 reflect.Value, parent_key string) error {
-	v1.Set(reflect.MakeMap(v1.Type()))
-	pkey := setKeyCase(o.options, parent_key)
+	if v1.IsNil() {
+		v1.Set(reflect.MakeMap(v1.Type()))
+	}
+	pkey := o.mapPrefix(parent_key)
+	if err := o.foldMapKeys(pkey+".", true); err != nil {
+		return err
+	}
 	for mapkey, v := range o.fieldMap {
 		v.kind = v1.Kind()
 		if strings.Index(mapkey, pkey+".") == 0 {
-			k := mapkey[len(pkey)+1:]
+			k := unquoteKey(mapkey[len(pkey)+1:])
 			newValue := reflect.New(v1.Type().Elem()).Elem()
-			if val, _, ok := o.getValue(mapkey); ok {
-				if err := setScalar(newValue, val); err == nil {
+			if o.isNull(mapkey) {
+				v1.SetMapIndex(reflect.ValueOf(k), reflect.Value{})
+				continue
+			}
+			if val, lineno, ok := o.getValue(mapkey); ok {
+				if err := o.setValue(newValue, val); err == nil {
 					v1.SetMapIndex(reflect.ValueOf(k), newValue)
+					o.checkValue(newValue, mapkey, val)
+					o.markSet(parent_key + "." + k)
+				} else if o.check {
+					o.failValue(mapkey, err, lineno)
 				}
 			}
 		}
@@ -316,10 +777,111 @@ func (o *Decoder) traverseScalarMap(v1 reflect.Value, parent_key string) error {
 	return nil
 }
 
+// Populate a map[string]interface{} with every key under parent_key. Blocks
+// become nested maps and values are converted by inferValue.
+func (o *Decoder) traverseInterfaceMap(v1 reflect.Value, parent_key string) error {
+	var pkey string
+	if parent_key != "" {
+		pkey = o.mapPrefix(parent_key) + "."
+	}
+	if err := o.foldMapKeys(pkey, true); err != nil {
+		return err
+	}
+	smap := make(StringMap)
+	for mapkey, v := range o.fieldMap {
+		if strings.HasPrefix(mapkey, pkey) {
+			v.isDefined = true
+			smap[mapkey[len(pkey):]] = v.val
+			if parent_key != "" {
+				o.markSet(parent_key + "." + mapkey[len(pkey):])
+			} else {
+				o.markSet(mapkey)
+			}
+		}
+	}
+	tree, err := nest(smap, inferValue)
+	if err != nil {
+		return o.fail(parent_key, err.Error(), 0)
+	}
+	if v1.IsNil() && v1.CanSet() {
+		v1.Set(reflect.MakeMap(v1.Type()))
+	}
+	mergeTree(v1, tree)
+	return nil
+}
+
+// Merge a tree of values into a map of empty interfaces. Nested maps are
+// merged, other values are replaced, and null values are deleted.
+func mergeTree(v1 reflect.Value, tree map[string]interface{}) {
+	for k, val := range tree {
+		kv := reflect.ValueOf(k)
+		if val == null_value {
+			v1.SetMapIndex(kv, reflect.Value{})
+			continue
+		}
+		if sub, ok := val.(map[string]interface{}); ok {
+			if old := v1.MapIndex(kv); old.IsValid() {
+				if old, ok := old.Interface().(map[string]interface{}); ok {
+					mergeTree(reflect.ValueOf(old), sub)
+					continue
+				}
+			}
+			m := make(map[string]interface{})
+			mergeTree(reflect.ValueOf(m), sub)
+			val = m
+		}
+		v1.SetMapIndex(kv, reflect.ValueOf(val))
+	}
+}
+
+// Return true if t is a map of empty interfaces.
+func isInterfaceMap(t reflect.Type) bool {
+	return t.Elem().Kind() == reflect.Interface && t.Elem().NumMethod() == 0
+}
+
+// A plain decimal numeric literal, without units or thousands separators
+var plainNumber = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// Return the value of s as an int64, float64, bool or time.Time if it can be
+// converted to one, in that order, otherwise as a string. Only plain numeric
+// literals are taken to be numbers; units and thousands separators, eg. 8K or
+// 1,000, are left to typed fields. Only the words true and false (in any case)
+// are taken to be booleans.
+func inferValue(s string) interface{} {
+	if s == "" {
+		return s
+	}
+	if plainNumber.MatchString(s) {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	switch toLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	var t time.Time
+	if set_time(reflect.ValueOf(&t).Elem(), s) == nil {
+		return t
+	}
+	return s
+}
+
 func setKeyCase(option int, k string) string {
 	if isOption(ALLOW_SNAKE_CASE, option) || isOption(ENCODE_SNAKE_CASE, option) {
 		k = toSnakeCase(k)
 	}
+	if isOption(ENCODE_KEBAB_CASE, option) {
+		k = toKebabCase(k)
+	}
+	if isOption(ENCODE_SCREAMING_SNAKE_CASE, option) {
+		k = toScreamingSnakeCase(k)
+	}
 	if isOption(IGNORE_CASE, option) || isOption(ENCODE_LOWER_CASE, option) {
 		k = toLower(k)
 	}
@@ -328,6 +890,18 @@ func setKeyCase(option int, k string) string {
 
 func setScalar(v1 reflect.Value, val string) error {
 	var err error
+	if isBigType(v1.Type()) {
+		return set_big(v1, val)
+	}
+	if v1.Type() == numberType {
+		return set_number(v1, val)
+	}
+	if isTextType(v1.Type()) {
+		return set_text(v1, val)
+	}
+	if v1.Type() == locationType {
+		return set_location(v1, val)
+	}
 	switch v1.Kind() {
 	case reflect.Struct:
 		if isTimeType(v1.Type()) {
@@ -340,13 +914,19 @@ func setScalar(v1 reflect.Value, val string) error {
 	case reflect.Int8, reflect.Int16, reflect.Int32:
 		err = set_int(v1, val)
 	case reflect.Int64, reflect.Int:
-		err = set_int64(v1, val)
+		if v1.Type() == durationType {
+			err = set_duration(v1, val)
+		} else {
+			err = set_int64(v1, val)
+		}
 	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
 		err = set_uint(v1, val)
 	case reflect.Uint64, reflect.Uint:
 		err = set_uint64(v1, val)
 	case reflect.Float32, reflect.Float64:
 		err = set_float(v1, val)
+	case reflect.Complex64, reflect.Complex128:
+		err = set_complex(v1, val)
 	default:
 		err = errors.New(fmt.Sprintf("type %v not allowed", v1.Kind()))
 	}
@@ -354,6 +934,12 @@ func setScalar(v1 reflect.Value, val string) error {
 }
 
 func set_time(v1 reflect.Value, val string) error {
+	return set_time_in(v1, val, time.UTC)
+}
+
+// Like set_time, but a date or timestamp without an offset is taken to be in
+// the supplied location. A time of day alone is always UTC.
+func set_time_in(v1 reflect.Value, val string, loc *time.Location) error {
 	var tformat string
 	switch len(val) {
 	case 25:
@@ -366,15 +952,41 @@ func set_time(v1 reflect.Value, val string) error {
 		tformat = date_fmt
 	case 8:
 		tformat = time_fmt
+		loc = time.UTC
 	default:
 	}
-	t, err := time.Parse(tformat, val)
+	if len(val) > 10 && (val[10] == 'T' || val[10] == 't') {
+		// RFC 3339, with optional fractional seconds
+		tformat = time.RFC3339Nano
+		val = toUpper(val)
+	}
+	t, err := time.ParseInLocation(tformat, val, loc)
 	if err == nil {
 		v1.Set(reflect.ValueOf(t))
 	}
 	return err
 }
 
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func set_duration(v1 reflect.Value, val string) error {
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return set_int64(v1, val)
+	}
+	v1.SetInt(int64(d))
+	return nil
+}
+
+// Return true if a value is one of the boolean values accepted by set_bool.
+func isBool(val string) bool {
+	switch toLower(val) {
+	case "true", "yes", "on", "1", "false", "no", "off", "0":
+		return true
+	}
+	return false
+}
+
 func set_bool(v1 reflect.Value, val string) {
 	val = toLower(val)
 	if val == "true" || val == "yes" || val == "on" || val == "1" {
@@ -390,7 +1002,7 @@ func set_int(v1 reflect.Value, val string) error {
 	v, err := strconv.Atoi(val)
 	if err == nil {
 		if v1.OverflowInt(int64(v)) {
-			return errors.New("Overflow")
+			return ErrOverflow
 		}
 		v1.SetInt(int64(v))
 	}
@@ -410,7 +1022,7 @@ func set_uint(v1 reflect.Value, val string) error {
 	v, err := strconv.Atoi(val)
 	if err == nil {
 		if v1.OverflowUint(uint64(v)) {
-			return errors.New("Overflow")
+			return ErrOverflow
 		}
 		v1.SetUint(uint64(v))
 	}
@@ -439,28 +1051,100 @@ func set_float(v1 reflect.Value, val string) error {
 	return err
 }
 
-func (o *Decoder) getValue(k string) (string, int, bool) {
-	if vs, ok := o.fieldMap[k]; ok {
-		vs.isDefined = true
-		return vs.val, vs.no, true
+// Assign a complex number, eg. 3+4i, optionally enclosed in parentheses.
+func set_complex(v1 reflect.Value, val string) error {
+	v, err := strconv.ParseComplex(val, v1.Type().Bits())
+	if err == nil {
+		v1.SetComplex(v)
 	}
-	if vs, ok := o.fieldMap[toSnakeCase(k)]; isOption(ALLOW_SNAKE_CASE, o.options) && ok {
-		vs.isDefined = true
-		return vs.val, vs.no, true
+	return err
+}
+
+// Verify that a value for a numeric field is an exact literal.
+func checkStrictNumber(v1 reflect.Value, val string) error {
+	var err error
+	if isBigType(v1.Type()) || v1.Type() == numberType {
+		if _, ok := new(big.Rat).SetString(val); !ok {
+			return errors.New("Invalid numeric literal")
+		}
+		return nil
+	}
+	switch v1.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v1.Type() == durationType {
+			return nil
+		}
+		_, err = strconv.ParseInt(val, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = strconv.ParseUint(val, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(val, 64)
+	case reflect.Complex64, reflect.Complex128:
+		_, err = strconv.ParseComplex(val, 128)
+	default:
+		return nil
+	}
+	if err != nil {
+		return errors.New("Invalid numeric literal")
 	}
-	if vs, ok := o.fieldMap[toLower(k)]; isOption(IGNORE_CASE, o.options) && ok {
+	return nil
+}
+
+func (o *Decoder) getValue(k string) (string, int, bool) {
+	if key, vs := o.lookupKey(k); vs != nil {
 		vs.isDefined = true
+		if o.tracing {
+			o.trace = append(o.trace, TraceEntry{vs.no, "assign", key, k, vs.val})
+		}
 		return vs.val, vs.no, true
 	}
 	return "", 0, false
 }
 
+// Find a key in the field map without marking it as defined.
+func (o *Decoder) lookup(k string) *v {
+	_, vs := o.lookupKey(k)
+	return vs
+}
+
+// Find a key in the field map, returning the key under which it was found.
+func (o *Decoder) lookupKey(k string) (string, *v) {
+	for _, key := range o.keyVariants(k) {
+		if vs, ok := o.fieldMap[key]; ok {
+			return key, vs
+		}
+	}
+	return "", nil
+}
+
+// Shift counts of the binary suffixes, eg. 4Gi == 4 << 30
+var binarySuffix = map[byte]uint{'K': 10, 'M': 20, 'G': 30, 'T': 40, 'P': 50, 'E': 60}
+
 func iFix(s string) string {
+	if num, fn, ok := unitFor(s); ok {
+		// registered unit. a fraction is reported when the string is
+		// converted.
+		v, err := convertUnit(num, fn, 64)
+		if err != nil {
+			return s
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
 	if len(s) < 2 {
 		return s
 	}
 	s = strings.Replace(s, ",", "", -1)  // remove commas
 	n := len(s) - 1
+	if s[n] == 'i' && n > 0 {
+		// binary suffix. the result may be out of range, which is
+		// reported when the string is converted.
+		if shift, ok := binarySuffix[s[n-1]]; ok {
+			if i, ok := new(big.Int).SetString(s[:n-1], 10); ok {
+				return i.Lsh(i, shift).String()
+			}
+		}
+		return s
+	}
 	switch s[n] {
 	case 'K':
 		return s[:n] + "000"
@@ -480,6 +1164,9 @@ func iFix(s string) string {
 }
 
 func floatFix(s string, b int) (float64, error) {
+	if num, fn, ok := unitFor(s); ok {
+		return convertUnit(num, fn, b)
+	}
 	n := len(s)
 	switch {
 	case n == 0:
@@ -493,11 +1180,24 @@ func floatFix(s string, b int) (float64, error) {
 	if c >= '0' && c <= '9' {
 		return strconv.ParseFloat(s, b)
 	}
+	if c == 'i' && n > 0 {
+		shift, ok := binarySuffix[s[n-1]]
+		if !ok {
+			return 0, errors.New("Invalid numeric abbreviation")
+		}
+		v, err := strconv.ParseFloat(s[:n-1], b)
+		if err != nil {
+			return 0, err
+		}
+		return v * float64(uint64(1)<<shift), nil
+	}
 	v, err := strconv.ParseFloat(s[:n], b)
 	if err != nil {
 		return 0, err
 	}
 	switch c {
+	case '%':
+		return v / 100, nil
 	case 'K':
 		return v * 1e3, nil
 	case 'M':
@@ -523,7 +1223,7 @@ func toSnakeCase(s string) string {
 	var lastn, lastu, lastw bool
 	var i int
 	var bs string
-	for _, c := range []byte(s) {
+	for _, c := range s {
 		i++
 		n := isNumber(c)
 		w := isLower(c)
@@ -547,20 +1247,31 @@ func toSnakeCase(s string) string {
 	return bs
 }
 
+// Convert to upper snake case, eg. MaxConns -> MAX_CONNS
+func toScreamingSnakeCase(s string) string {
+	return toUpper(toSnakeCase(s))
+}
+
+// Convert to kebab case, eg. MaxConns -> max-conns
+func toKebabCase(s string) string {
+	return strings.Replace(toSnakeCase(s), "_", "-", -1)
+}
+
 func isPublic(s string) bool {
-	return isUpper(s[0])
+	r, _ := utf8.DecodeRuneInString(s)
+	return isUpper(r)
 }
 
-func isUpper(c byte) bool {
-	return c >= 'A' && c <= 'Z'
+func isUpper(c rune) bool {
+	return unicode.IsUpper(c)
 }
 
-func isLower(c byte) bool {
-	return c >= 'a' && c <= 'z'
+func isLower(c rune) bool {
+	return unicode.IsLower(c)
 }
 
-func isNumber(c byte) bool {
-	return c >= '0' && c <= '9'
+func isNumber(c rune) bool {
+	return unicode.IsDigit(c)
 }
 
 //func setCase__SAVE(opt int, k string) string {
@@ -576,23 +1287,13 @@ func isNumber(c byte) bool {
 //}
 
 func toLower(s string) string {
-	z := []byte(s)
-	for i := 0; i < len(z); i++ {
-		z[i] = lower(z[i])
-	}
-	return string(z)
+	return strings.Map(lower, s)
 }
 
-func lower(r byte) byte {
-	if 'A' <= r && r <= 'Z' {
-		r += 'a' - 'A'
-	}
-	return r
+func lower(r rune) rune {
+	return unicode.ToLower(r)
 }
 
 func newError(msg string, no int) error {
-	if no > 0 {
-		return errors.New(fmt.Sprintf("%s at line %d", msg, no))
-	}
-	return errors.New(msg)
+	return &ParseError{Line: no, Msg: msg}
 }
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"time"
 	"reflect"
+	"strings"
 	"testing"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -354,7 +355,7 @@ func TestDecode_Force_NumericErrors(t *testing.T) {
 		cfg := "Float1 = 3.1A"
 		err := NewDecoder(&x).DecodeString(cfg)
 		if err != nil {
-			So(err.Error(), ShouldEqual, "Invalid numeric abbreviation at line 1")
+			So(err.Error(), ShouldEqual, "Float1: Invalid numeric abbreviation at line 1")
 		}
 		So(err, ShouldNotBeNil)
 	})
@@ -425,7 +426,7 @@ func TestDecode_Force_NumericErrors(t *testing.T) {
 		cfg := `Key1=String1`
 		err := NewDecoder(&x).DecodeString(cfg)
 		if err != nil {
-			So(err.Error(), ShouldEqual, "type array not allowed at line 1")
+			So(err.Error(), ShouldEqual, "Key1: type array not allowed at line 1")
 		}
 		So(err, ShouldNotBeNil)
 	})
@@ -560,6 +561,237 @@ func TestDecode_NumericAbbreviations(t *testing.T) {
 
 }
 
+func TestDecode_BinaryAbbreviations(t *testing.T) {
+
+	Convey("Given numbers with binary suffixes", t, func() {
+		var x numAbbrevStruct
+		cfg := `
+			Ki = 512Ki
+			Mi = 1,024Mi
+			Gi = 4Gi
+			Ti = 2Ti
+			Pi = 3Pi
+			Ei = 7Ei
+			Kf = 1.5Ki
+			Ef = 0.5Ei
+		`
+		err := NewDecoder(&x).DecodeString(cfg)
+		So(err, ShouldBeNil)
+		So(x.Ki, ShouldEqual, 512<<10)
+		So(x.Mi, ShouldEqual, 1024<<20)
+		So(x.Gi, ShouldEqual, 4<<30)
+		So(x.Ti, ShouldEqual, 2<<40)
+		So(x.Pi, ShouldEqual, 3<<50)
+		So(x.Ei, ShouldEqual, 7<<60)
+		So(x.Kf, ShouldEqual, 1536)
+		So(x.Ef, ShouldEqual, float64(1<<59))
+	})
+
+	Convey("Force error: Invalid binary suffixes", t, func() {
+		cfgs := []string{
+			"Ki = 1Xi",
+			"Ki = Ki",
+			"Ei = 8Ei",
+			"Kf = 1.5Xi",
+		}
+		for _, cfg := range cfgs {
+			var x numAbbrevStruct
+			So(NewDecoder(&x).DecodeString(cfg), ShouldNotBeNil)
+		}
+	})
+
+}
+
+func TestDecode_StrictNumbers(t *testing.T) {
+
+	type cfg struct {
+		Port    int
+		Account uint64
+		Rate    float64
+		Timeout time.Duration
+	}
+
+	Convey("Exact literals are accepted with STRICT_NUMBERS", t, func() {
+		var x cfg
+		src := "Port = 8080\nAccount = 18446744073709551615\nRate = 1.5e-3\nTimeout = 5s"
+		So(Decode(&x, src, STRICT_NUMBERS), ShouldBeNil)
+		So(x, ShouldResemble, cfg{8080, 18446744073709551615, 0.0015, 5 * time.Second})
+	})
+
+	Convey("Abbreviations are not numbers in an interface map", t, func() {
+		x := make(map[string]interface{})
+		So(Decode(x, "A = 8K\nB = 42\nC = 0.5", STRICT_NUMBERS), ShouldBeNil)
+		So(x, ShouldResemble, map[string]interface{}{"A": "8K", "B": int64(42), "C": 0.5})
+	})
+
+	Convey("Only plain literals are numbers in an interface map", t, func() {
+		x := make(map[string]interface{})
+		So(Decode(x, "A = 1,2,3\nB = 1E\nC = 8K\nD = -42\nE = 1.5e3\nF = inf"), ShouldBeNil)
+		So(x, ShouldResemble, map[string]interface{}{"A": "1,2,3", "B": "1E", "C": "8K",
+			"D": int64(-42), "E": 1500.0, "F": "inf"})
+	})
+
+	Convey("Force error: Inexact literals with STRICT_NUMBERS", t, func() {
+		srcs := []string{
+			"Port = 8K",
+			"Port = 8,080",
+			"Account = 1Ki",
+			"Rate = 75%",
+			"Rate = 1.5M",
+		}
+		for _, src := range srcs {
+			var x cfg
+			err := Decode(&x, src, STRICT_NUMBERS|ALLOW_INT_PERCENT)
+			key := strings.Fields(src)[0]
+			So(err.Error(), ShouldEqual, key+": Invalid numeric literal at line 1")
+		}
+	})
+
+}
+
+func TestDecode_Complex(t *testing.T) {
+
+	type cfg struct {
+		C64  complex64
+		C128 complex128
+		Real complex128
+		Imag complex128
+	}
+
+	Convey("Decode complex numbers", t, func() {
+		var x cfg
+		src := "C64 = 3+4i\nC128 = (-1.5e-3-2i)\nReal = 7\nImag = 0.5i"
+		So(Decode(&x, src), ShouldBeNil)
+		So(x, ShouldResemble, cfg{3 + 4i, -1.5e-3 - 2i, 7, 0.5i})
+	})
+
+	Convey("Force error: Invalid complex numbers", t, func() {
+		var x cfg
+		So(Decode(&x, "C128 = 3+4j"), ShouldNotBeNil)
+		So(Decode(&x, "C64 = 1e40+1i"), ShouldNotBeNil)
+	})
+
+}
+
+func TestDecode_RFC3339(t *testing.T) {
+
+	type cfg struct {
+		UTC    time.Time
+		Frac   time.Time
+		Offset time.Time
+		Lower  time.Time
+	}
+
+	Convey("Decode RFC 3339 timestamps", t, func() {
+		var x cfg
+		src := `
+			UTC    = 2017-12-25T08:10:00Z
+			Frac   = 2017-12-25T08:10:00.123456789Z
+			Offset = 2017-12-25T08:10:00+01:00
+			Lower  = 2017-12-25t08:10:00.5z
+		`
+		So(Decode(&x, src), ShouldBeNil)
+		So(x.UTC.Equal(time.Date(2017, 12, 25, 8, 10, 0, 0, time.UTC)), ShouldBeTrue)
+		So(x.Frac.Nanosecond(), ShouldEqual, 123456789)
+		So(x.Offset.Equal(time.Date(2017, 12, 25, 7, 10, 0, 0, time.UTC)), ShouldBeTrue)
+		So(x.Lower.Nanosecond(), ShouldEqual, 500000000)
+	})
+
+	Convey("Existing layouts are still recognized", t, func() {
+		var x cfg
+		So(Decode(&x, "UTC = 2017-12-25 08:10:00 +0100"), ShouldBeNil)
+		So(x.UTC.Equal(time.Date(2017, 12, 25, 7, 10, 0, 0, time.UTC)), ShouldBeTrue)
+	})
+
+	Convey("Force error: Invalid RFC 3339 timestamps", t, func() {
+		var x cfg
+		So(Decode(&x, "UTC = 2017-12-25T08:10:00"), ShouldNotBeNil)
+		So(Decode(&x, "UTC = 2017-12-25T25:10:00Z"), ShouldNotBeNil)
+	})
+
+}
+
+func TestDecode_Successive(t *testing.T) {
+
+	type server struct {
+		Host string
+		Port int
+	}
+	type cfg struct {
+		Name    string
+		Debug   bool
+		Limits  map[string]int
+		Servers map[string]server
+		Extra   map[string]interface{}
+	}
+
+	base := `
+		Name  = base
+		Debug = true
+		Limits {
+			Conns = 10
+			Rate  = 5
+		}
+		Servers {
+			A {
+				Host = alpha
+				Port = 80
+			}
+			B {
+				Host = beta
+			}
+		}
+		Extra {
+			Color = red
+			Sub {
+				X = 1
+			}
+		}
+	`
+	override := `
+		Name = local
+		Limits {
+			Rate = 50
+		}
+		Servers {
+			A {
+				Port = 8080
+			}
+			C {
+				Host = gamma
+			}
+		}
+		Extra {
+			Sub {
+				Y = 2
+			}
+		}
+	`
+
+	Convey("Successive sources override scalars and merge maps", t, func() {
+		var x cfg
+		o := NewDecoder(&x)
+		So(o.DecodeString(base), ShouldBeNil)
+		So(o.DecodeString(override), ShouldBeNil)
+		So(x.Name, ShouldEqual, "local")
+		So(x.Debug, ShouldBeTrue)
+		So(x.Limits, ShouldResemble, map[string]int{"Conns": 10, "Rate": 50})
+		So(x.Servers, ShouldResemble, map[string]server{
+			"A": {"alpha", 8080}, "B": {"beta", 0}, "C": {"gamma", 0}})
+		So(x.Extra, ShouldResemble, map[string]interface{}{"Color": "red",
+			"Sub": map[string]interface{}{"X": int64(1), "Y": int64(2)}})
+	})
+
+	Convey("Errors describe the most recent source only", t, func() {
+		var x cfg
+		o := NewDecoder(&x)
+		So(o.DecodeString("Name = a\nBogus = 1"), ShouldNotBeNil)
+		So(o.DecodeString("Name = b"), ShouldBeNil)
+		So(x.Name, ShouldEqual, "b")
+	})
+
+}
+
 func TestDecode_ForceError_ExtraFields(t *testing.T) {
 	var x struct{ Key2 int }
 	Convey("Force error: Check for extra fields", t, func() {
@@ -575,6 +807,76 @@ func TestDecode_ForceError_ExtraFields(t *testing.T) {
 	})
 }
 
+func TestDecode_InterfaceMap(t *testing.T) {
+
+	cfg := `
+		Name    = Rick
+		Age     = 70
+		Ratio   = 0.5
+		Debug   = True
+		Born    = 1948-03-04
+		Server {
+			Port = 8080
+			Host = citadel
+		}
+	`
+
+	Convey("Decode to map[string]interface{} with inferred types", t, func() {
+		x := make(map[string]interface{})
+		So(Decode(x, cfg), ShouldBeNil)
+		So(x["Name"], ShouldEqual, "Rick")
+		So(x["Age"], ShouldEqual, int64(70))
+		So(x["Ratio"], ShouldEqual, 0.5)
+		So(x["Debug"], ShouldEqual, true)
+		So(x["Born"], ShouldEqual, time.Date(1948, 3, 4, 0, 0, 0, 0, time.UTC))
+		So(x["Server"], ShouldResemble, map[string]interface{}{"Port": int64(8080), "Host": "citadel"})
+	})
+
+	Convey("Decode a block to a map[string]interface{} field", t, func() {
+		var x struct {
+			Name   string
+			Server map[string]interface{}
+		}
+		So(Decode(&x, cfg), ShouldNotBeNil) // extra fields
+		So(x.Server, ShouldResemble, map[string]interface{}{"Port": int64(8080), "Host": "citadel"})
+		So(Check(&x, "Server {\n Port = 1\n}"), ShouldBeNil)
+	})
+
+}
+
+func TestDecode_OnUnknownKey(t *testing.T) {
+
+	cfg := `
+		Name  = Rick
+		Color = green
+		Size  = 3
+	`
+
+	Convey("Call the function for each unknown key instead of failing", t, func() {
+		var x struct{ Name string }
+		var seen []string
+		err := NewDecoder(&x).OnUnknownKey(func(key string, line int, value string) error {
+			seen = append(seen, fmt.Sprintf("%s=%s:%d", key, value, line))
+			return nil
+		}).DecodeString(cfg)
+		So(err, ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+		So(seen, ShouldResemble, []string{"Color=green:3", "Size=3:4"})
+	})
+
+	Convey("Return errors from the function", t, func() {
+		var x struct{ Name string }
+		err := NewDecoder(&x).OnUnknownKey(func(key string, line int, value string) error {
+			if key == "Size" {
+				return newError("Unsupported key", line)
+			}
+			return nil
+		}).DecodeString(cfg)
+		So(err.Error(), ShouldEqual, "Unsupported key at line 4")
+	})
+
+}
+
 func TestDecodeFile_errors(t *testing.T) {
 
 	tempfile1 := createTempFile("GOTEST_CONFIG")
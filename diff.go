@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+const (
+	// KEY_ADDED indicates a key that exists only in the second source.
+	KEY_ADDED = iota + 1
+
+	// KEY_REMOVED indicates a key that exists only in the first source.
+	KEY_REMOVED
+
+	// KEY_CHANGED indicates a key whose value differs between sources.
+	KEY_CHANGED
+)
+
+// A Change describes the difference of a single key between two sources.
+// Line numbers are zero for a source which does not contain the key.
+type Change struct {
+	Kind    int    // KEY_ADDED, KEY_REMOVED or KEY_CHANGED
+	Key     string // Dotted key
+	Old     string // Value in the first source
+	New     string // Value in the second source
+	OldLine int    // Line number in the first source
+	NewLine int    // Line number in the second source
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case KEY_ADDED:
+		return fmt.Sprintf("+ %s = %q", c.Key, c.New)
+	case KEY_REMOVED:
+		return fmt.Sprintf("- %s = %q", c.Key, c.Old)
+	default:
+		return fmt.Sprintf("~ %s = %q -> %q", c.Key, c.Old, c.New)
+	}
+}
+
+// Diff parses two configuration sources and returns the keys which were
+// added, removed or changed, sorted by key. Parser options are optional.
+func Diff(a, b []byte, options ...int) ([]Change, error) {
+	m1, err := parseFieldMap(a, options...)
+	if err != nil {
+		return nil, err
+	}
+	m2, err := parseFieldMap(b, options...)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for k, v1 := range m1 {
+		v2, ok := m2[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{KEY_REMOVED, k, v1.val, "", v1.no, 0})
+		case v1.val != v2.val:
+			changes = append(changes, Change{KEY_CHANGED, k, v1.val, v2.val, v1.no, v2.no})
+		}
+	}
+	for k, v2 := range m2 {
+		if _, ok := m1[k]; !ok {
+			changes = append(changes, Change{KEY_ADDED, k, "", v2.val, 0, v2.no})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes, nil
+}
+
+// Parse a byte slice to a field map, retaining line numbers.
+func parseFieldMap(bs []byte, options ...int) (fMap, error) {
+	o := NewParser(options...)
+	o.reader = bufio.NewReader(bytes.NewReader(bs))
+	vmap, err := o.parse()
+	if err != nil {
+		return nil, err
+	}
+	if isOption(PARSE_LOWER_CASE, o.options) {
+		m := make(fMap, len(vmap))
+		for k, v := range vmap {
+			m[toLower(k)] = v
+		}
+		vmap = m
+	}
+	return vmap, nil
+}
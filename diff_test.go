@@ -0,0 +1,58 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiff(t *testing.T) {
+
+	cfg1 := `
+		Name = Rick
+		Port = 8080
+		Server {
+			Host = localhost
+		}
+	`
+	cfg2 := `
+		Name = Rick
+		Server {
+			Host = citadel
+			TLS  = on
+		}
+	`
+
+	Convey("Report added, removed and changed keys", t, func() {
+		changes, err := Diff([]byte(cfg1), []byte(cfg2))
+		So(err, ShouldBeNil)
+		So(len(changes), ShouldEqual, 3)
+		So(changes[0], ShouldResemble, Change{KEY_REMOVED, "Port", "8080", "", 3, 0})
+		So(changes[1], ShouldResemble, Change{KEY_CHANGED, "Server.Host", "localhost", "citadel", 5, 4})
+		So(changes[2], ShouldResemble, Change{KEY_ADDED, "Server.TLS", "", "on", 0, 5})
+		So(changes[1].String(), ShouldEqual, `~ Server.Host = "localhost" -> "citadel"`)
+	})
+
+	Convey("Identical sources produce no changes", t, func() {
+		changes, err := Diff([]byte(cfg1), []byte(cfg1))
+		So(err, ShouldBeNil)
+		So(len(changes), ShouldEqual, 0)
+	})
+
+	Convey("Compare lower case keys", t, func() {
+		changes, err := Diff([]byte("Name = Rick"), []byte("name = Rick"), PARSE_LOWER_CASE)
+		So(err, ShouldBeNil)
+		So(len(changes), ShouldEqual, 0)
+	})
+
+	Convey("Force error: Parse errors", t, func() {
+		_, err := Diff([]byte("Key1={"), []byte(cfg1))
+		So(err, ShouldNotBeNil)
+		_, err = Diff([]byte(cfg1), []byte("Key1={"))
+		So(err, ShouldNotBeNil)
+	})
+
+}
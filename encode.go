@@ -6,7 +6,9 @@ package config
 
 import (
 	"io"
+	"bufio"
 	"os"
+	"path/filepath"
 	"fmt"
 	"sort"
 	"time"
@@ -15,16 +17,34 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // The Encoder handles encoding a struct to an io.Writer.
 type Encoder struct {
 	writer       io.Writer
+	werr         error // First error writing to the writer
 	previous_key string
 	options      int
 	v            reflect.Value
 	fileMode     os.FileMode
+	template     bool
+	hooks        []EncodeHook
+	encrypt      func(plaintext string) (string, error)
+	cipher       Cipher
+	backups      int
+	tag          reflect.StructTag
+	timeLayout   string
+	path         []string
+	mapper       NameMapper
 	errs         []error
+	visiting     map[uintptr]bool  // Maps being encoded, to detect cycles
+	heredocLines int               // Newlines in a string before it is written as a heredoc
+	utf8         bool              // Write printable non-ASCII characters as is
+	quoteAll     bool              // Quote every string value
+	align        bool              // Line up the = signs of each block
+	split        map[string]string // File name of each block written by ToFiles
+	parts        map[string][]byte // Encoded blocks written by ToFiles
 }
 
 // NewEncoder accepts a struct or map and returns a new Encoder.
@@ -44,7 +64,7 @@ func NewEncoder(x interface{}, options ...int) *Encoder {
 	default:
 		panic("Expecting a struct or a map")
 	}
-	o := &Encoder{v: rv}
+	o := &Encoder{v: rv, heredocLines: heredoc_lines}
 	if len(options) > 0 {
 		if !o.allowedOption(options[0]) {
 			panic("Option not allowed")
@@ -55,12 +75,90 @@ func NewEncoder(x interface{}, options ...int) *Encoder {
 }
 
 func (o *Encoder) allowedOption(option int) bool {
-	return option == option&(ENCODE_ZERO_VALUES|ENCODE_LOWER_CASE|ENCODE_SNAKE_CASE|OVERWRITE_FILE)
+	return option == option&(ENCODE_ZERO_VALUES|ENCODE_LOWER_CASE|ENCODE_SNAKE_CASE|OVERWRITE_FILE|BACKUP_FILE|SECURE_FILE|SECURE_DIR|LOCK_FILE|ENCODE_ABBREVIATIONS|ENCODE_FLAT|ENCODE_INLINE_BLOCKS|ENCODE_KEBAB_CASE|ENCODE_SCREAMING_SNAKE_CASE|ENCODE_SORTED_FIELDS)
+}
+
+// SetFileMode sets the permissions of files written by ToFile. By default a
+// file which is overwritten keeps its permissions, and a new file is created
+// with 0644.
+func (o *Encoder) SetFileMode(mode os.FileMode) *Encoder {
+	o.fileMode = mode
+	return o
+}
+
+// SetUTF8 will cause the encoder to write printable non-ASCII characters as
+// is, rather than as escapes such as \u00fc, so that text in other languages
+// remains readable. Such characters no longer cause a string to be quoted,
+// eg. Greeting = Grüße
+func (o *Encoder) SetUTF8(on bool) *Encoder {
+	o.utf8 = on
+	return o
+}
+
+// SetQuoteStrings will cause the encoder to quote every string value,
+// including values such as IP addresses and URLs, so that no value can be
+// mistaken for a number, a comment or a heredoc, and trailing spaces are never
+// lost. Long strings are written as quoted multi-line values rather than
+// heredocs. This is a setter rather than an ENCODE_QUOTE_STRINGS option
+// because every bit of a 32-bit option int is in use.
+func (o *Encoder) SetQuoteStrings(on bool) *Encoder {
+	o.quoteAll = on
+	return o
+}
+
+// SetAlignValues will cause the encoder to pad the keys of each block so that
+// their = signs, and the values which follow, line up, eg.
+//
+//	Name     = Rick
+//	MaxConns = 10
+func (o *Encoder) SetAlignValues(on bool) *Encoder {
+	o.align = on
+	return o
+}
+
+// SetHeredocThreshold sets the number of newlines a string value may contain
+// before it is written as a heredoc. The default is 3. A threshold of 0 writes
+// every multi-line string as a heredoc, and a negative threshold disables
+// heredocs, so that newlines are escaped within quoted values instead.
+func (o *Encoder) SetHeredocThreshold(n int) *Encoder {
+	o.heredocLines = n
+	return o
 }
 
 // ToFile will encode a struct to the supplied filename. If the file exists,
-// it will not be overwritten unless the overwrite options is used.
+// it will not be overwritten unless the overwrite options is used. The data
+// is written to a temporary file in the same directory, synced, and renamed
+// over the target, so that a crash cannot leave a truncated file. If nothing
+// is encoded, no file is written.
 func (o *Encoder) ToFile(filename string) error {
+	if filename == "" {
+		return errors.New("missing filename")
+	}
+	var buf bytes.Buffer
+	if err := o.ToStream(&buf); err != nil {
+		return err
+	}
+	return o.writeFile(filename, buf.Bytes())
+}
+
+// Write encoded data to a file, applying the file options of the encoder.
+func (o *Encoder) writeFile(filename string, bs []byte) error {
+	if err := o.checkSecure(filename); err != nil {
+		return err
+	}
+	// write through a symbolic link to its target, rather than replacing it
+	if !o.isOption(SECURE_FILE) {
+		if target, err := filepath.EvalSymlinks(filename); err == nil {
+			filename = target
+		}
+	}
+	if o.isOption(LOCK_FILE) {
+		unlock, err := lockFile(filename, true)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
 	fi, err := os.Stat(filename)
 	if err == nil {
 		// file exists
@@ -71,30 +169,41 @@ func (o *Encoder) ToFile(filename string) error {
 			return errors.New("file already exists")
 		}
 	}
-	fh, err := os.Create(filename)
-	if err != nil {
-		return err
+	if len(bs) == 0 {
+		return nil
 	}
-	defer func() {
-		fi, err := fh.Stat()
-		fh.Close()
-		if err == nil {
-			// Remove file if it's empty
-			if fi.Size() == 0 {
-				os.Remove(filename)
-			}
+	mode := o.fileMode
+	switch {
+	case o.isOption(SECURE_FILE):
+		mode = 0600
+	case mode != 0:
+	case fi != nil:
+		mode = fi.Mode().Perm()
+	default:
+		mode = 0644
+	}
+	if fi != nil && (o.isOption(BACKUP_FILE) || o.backups > 0) {
+		bmode := fi.Mode()
+		if o.isOption(SECURE_FILE) {
+			bmode = 0600
+		}
+		if err = backupFile(filename, bmode, o.backups); err != nil {
+			return err
 		}
-	}()
-	// We don't care if chmod returns an error. Just ignore it.
-	fh.Chmod(o.fileMode)
-	return o.ToStream(fh)
+	}
+	if c := cipherFor(filename, o.cipher); c != nil {
+		if bs, err = c.Encrypt(bs); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(filename, bs, mode)
 }
 
 func Encode(x interface{}, options ...int) ([]byte, error) {
 	o := NewEncoder(x, options...)
 	var buf bytes.Buffer
 	o.writer = &buf
-	o.encodeTraverseStruct(o.v, 0, "")
+	o.encode()
 	return buf.Bytes(), getErrors(o.errs)
 }
 
@@ -110,20 +219,92 @@ func (o *Encoder) ToBytes(bs *[]byte) error {
 	return err
 }
 
-// ToStream
+// ToStream will encode a struct to the supplied writer, through a buffer
+// which is flushed before it returns. If w is a *bufio.Writer, it is used as
+// the buffer and is not flushed, so that several values may be encoded to it
+// before the caller flushes it. See Flush. Nothing more is written after an
+// error writing to w.
 func (o *Encoder) ToStream(w io.Writer) error {
-	o.writer = w
-	o.encodeTraverseStruct(o.v, 0, "")
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	o.writer = bw
+	o.werr = nil
+	o.encode()
+	if !ok {
+		o.Flush()
+	}
 	return getErrors(o.errs)
 }
 
+// Flush writes any buffered data to the writer of the last call to ToStream.
+func (o *Encoder) Flush() error {
+	bw, ok := o.writer.(*bufio.Writer)
+	if !ok || o.werr != nil {
+		return o.werr
+	}
+	if err := bw.Flush(); err != nil {
+		o.werr = err
+		o.errs = append(o.errs, err)
+	}
+	return o.werr
+}
+
+// Encode the value of the encoder to its writer.
+func (o *Encoder) encode() {
+	if !o.align {
+		o.encodeTraverseStruct(o.v, 0, "")
+		return
+	}
+	var buf bytes.Buffer
+	w := o.writer
+	o.writer = &buf
+	o.encodeTraverseStruct(o.v, 0, "")
+	o.writer = w
+	o.write(0, string(alignValues(buf.Bytes())))
+}
+
 func (o *Encoder) appendErr(s string, v interface{}) {
 	o.errs = append(o.errs, errors.New(fmt.Sprintf(s, v)))
 }
 
 func (o *Encoder) encodeTraverseStruct(v1 reflect.Value, depth int, parent_key string) bool {
+	if depth > max_encode_depth {
+		o.errs = append(o.errs, fmt.Errorf("Nesting exceeds limit of %d (%s)", max_encode_depth, parent_key))
+		return false
+	}
+	if claimed, ok := o.encodeHook(v1, depth, parent_key); claimed {
+		return ok
+	}
+	if isBigType(v1.Type()) {
+		return o.encodeBig(v1, depth, parent_key)
+	}
+	if isTextType(v1.Type()) {
+		return o.encodeText(v1, depth, parent_key)
+	}
+	if v1.Type() == locationType {
+		return o.encodeLocation(v1, depth, parent_key)
+	}
 	switch v1.Kind() {
+	case reflect.Interface:
+		if v1.IsNil() {
+			o.appendErr("Cannot encode nil value (%s)", parent_key)
+			return false
+		}
+		return o.encodeTraverseStruct(v1.Elem(), depth, parent_key)
 	case reflect.Map:
+		// a map which contains itself, by way of an interface value
+		ptr := v1.Pointer()
+		if o.visiting[ptr] {
+			o.appendErr("Cannot encode a map which contains itself (%s)", parent_key)
+			return false
+		}
+		if o.visiting == nil {
+			o.visiting = make(map[uintptr]bool)
+		}
+		o.visiting[ptr] = true
+		defer delete(o.visiting, ptr)
 		return o.encodeMap(v1, depth, parent_key)
 	case reflect.Struct:
 		if isTimeType(v1.Type()) {
@@ -140,6 +321,13 @@ func (o *Encoder) encodeTraverseStruct(v1 reflect.Value, depth int, parent_key s
 }
 
 func (o *Encoder) encodeTime(v1 reflect.Value, depth int, parent_key string) bool {
+	if o.encodeTaggedTime(v1, depth, parent_key) {
+		return true
+	}
+	if o.timeLayout != "" {
+		o.write_kv(depth, parent_key, v1.Interface().(time.Time).Format(o.timeLayout))
+		return true
+	}
 	if isTimeType(v1.Type()) {
 		t := v1.Interface().(time.Time)
 		var dt string
@@ -163,6 +351,10 @@ func (o *Encoder) encodeTime(v1 reflect.Value, depth int, parent_key string) boo
 func (o *Encoder) encodeScalar(v1 reflect.Value, depth int, parent_key string) bool {
 	switch v1.Kind() {
 	case reflect.String:
+		if v1.Type() == numberType {
+			o.encodeNumber(v1, depth, parent_key)
+			break
+		}
 		o.encodeString(v1, depth, parent_key)
 	case reflect.Bool:
 		BoolStr := "False"
@@ -177,17 +369,36 @@ func (o *Encoder) encodeScalar(v1 reflect.Value, depth int, parent_key string) b
 		if !o.isOption(ENCODE_ZERO_VALUES) && isZero(v1) {
 			break
 		}
+		if o.isOption(ENCODE_ABBREVIATIONS) && v1.Type() != durationType {
+			i := v1.Int()
+			if i < 0 {
+				o.write_kv(depth, parent_key, "-"+abbreviate(uint64(-i)))
+			} else {
+				o.write_kv(depth, parent_key, abbreviate(uint64(i)))
+			}
+			break
+		}
 		o.write_kv(depth, parent_key, v1)
 	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
 		if !o.isOption(ENCODE_ZERO_VALUES) && isZero(v1) {
 			break
 		}
+		if o.isOption(ENCODE_ABBREVIATIONS) {
+			o.write_kv(depth, parent_key, abbreviate(v1.Uint()))
+			break
+		}
 		o.write_kv(depth, parent_key, v1)
 	case reflect.Float32, reflect.Float64:
 		if !o.isOption(ENCODE_ZERO_VALUES) && isZero(v1) {
 			break
 		}
 		o.write_kv(depth, parent_key, v1)
+	case reflect.Complex64, reflect.Complex128:
+		if !o.isOption(ENCODE_ZERO_VALUES) && isZero(v1) {
+			break
+		}
+		c := strconv.FormatComplex(v1.Complex(), 'g', -1, v1.Type().Bits())
+		o.write_kv(depth, parent_key, c[1:len(c)-1])
 	default:
 		return false
 	}
@@ -196,14 +407,12 @@ func (o *Encoder) encodeScalar(v1 reflect.Value, depth int, parent_key string) b
 
 func (o *Encoder) encodeString(v1 reflect.Value, depth int, parent_key string) bool {
 	str := v1.String()
-	if len(str) > 50 {
-		if needs_heredoc(str) {
-			str = output_heredoc(str)
-		} else {
-			str = encodeMultiline(parent_key, str)
-		}
+	if o.needsHeredoc(str) {
+		str = output_heredoc(str)
+	} else if len(str) > 50 {
+		str = o.encodeMultiline(parent_key, str)
 	} else {
-		str = quote(str)
+		str = o.quote(str)
 	}
 	if str == "" {
 		if o.isOption(ENCODE_ZERO_VALUES) {
@@ -217,6 +426,9 @@ func (o *Encoder) encodeString(v1 reflect.Value, depth int, parent_key string) b
 }
 
 func (o *Encoder) encodeMap(v1 reflect.Value, depth int, parent_key string) bool {
+	if o.isOption(ENCODE_INLINE_BLOCKS) && o.encodeInline(v1, depth, parent_key) {
+		return true
+	}
 	last_parent := ""
 	open__brace := false
 	keys := v1.MapKeys()
@@ -225,13 +437,22 @@ func (o *Encoder) encodeMap(v1 reflect.Value, depth int, parent_key string) bool
 		sorted[i] = k.String()
 	}
 	sort.Strings(sorted)
+	// the tag of the map field applies only to the values of a map of times
+	tag := o.tag
+	defer func() { o.tag = tag }()
+	if !isTimeType(v1.Type().Elem()) {
+		o.tag = ""
+	}
 	for _, ky := range sorted {
-		this_key := ky
+		this_key := encodeKey(ky)
 		v := v1.MapIndex(reflect.ValueOf(ky))
+		if parent_key == "" && o.encodeSection(v, ky) {
+			continue
+		}
 		if !(o.isOption(ENCODE_ZERO_VALUES) && isZeroStruct(v1)) {
 			if parent_key != o.previous_key && last_parent != parent_key {
 				o.previous_key = parent_key
-				o.write_kv(depth, parent_key, "{")
+				o.openBlock(depth, parent_key)
 				open__brace = true
 				last_parent = parent_key
 			}
@@ -239,18 +460,23 @@ func (o *Encoder) encodeMap(v1 reflect.Value, depth int, parent_key string) bool
 		}
 	}
 	if open__brace && parent_key != "" {
-		o.write(depth, "}\n")
+		o.closeBlock(depth)
 		open__brace = false
 	}
 	return true
 }
 
 func (o *Encoder) encodeStruct(v1 reflect.Value, depth int, parent_key string) bool {
+	if o.isOption(ENCODE_INLINE_BLOCKS) && o.encodeInline(v1, depth, parent_key) {
+		return true
+	}
 	last_parent := ""
 	open__brace := false
-	for i, n := 0, v1.NumField(); i < n; i++ {
-		this_key := v1.Type().Field(i).Name
-		if !isPublic(this_key) {
+	tag := o.tag
+	defer func() { o.tag = tag }()
+	for _, i := range o.fieldOrder(v1.Type()) {
+		this_key := fieldKey(v1.Type().Field(i))
+		if this_key == "" {
 			continue
 		}
 		if parent_key != "" {
@@ -259,28 +485,89 @@ func (o *Encoder) encodeStruct(v1 reflect.Value, depth int, parent_key string) b
 			}
 			if parent_key != o.previous_key && last_parent != parent_key {
 				o.previous_key = parent_key
-				o.write_kv(depth, parent_key, "{")
+				o.openBlock(depth, parent_key)
 				open__brace = true
 				last_parent = parent_key
 			}
 		}
+		if parent_key == "" && o.encodeSection(v1.Field(i), this_key) {
+			continue
+		}
+		if o.template && o.encodeTemplateField(v1.Type().Field(i), v1.Field(i), depth+1) {
+			continue
+		}
+		if o.encrypt != nil && o.encodeEncryptedField(v1.Type().Field(i), v1.Field(i), depth+1) {
+			continue
+		}
+		if o.encodePercentField(v1.Type().Field(i), v1.Field(i), depth+1) {
+			continue
+		}
+		// the tag of the field being encoded
+		o.tag = v1.Type().Field(i).Tag
 		if !o.encodeTraverseStruct(v1.Field(i), depth+1, this_key) {
 			continue
 		}
 	}
 	if open__brace && parent_key != "" {
-		o.write(depth, "}\n")
+		o.closeBlock(depth)
 		open__brace = false
 	}
 	return true
 }
 
+// Return the indexes of the fields of a struct type in the order they are
+// encoded.
+func (o *Encoder) fieldOrder(t reflect.Type) []int {
+	order := make([]int, t.NumField())
+	for i := range order {
+		order[i] = i
+	}
+	if o.isOption(ENCODE_SORTED_FIELDS) {
+		sort.SliceStable(order, func(i, j int) bool {
+			return o.keyCase(fieldKey(t.Field(order[i]))) < o.keyCase(fieldKey(t.Field(order[j])))
+		})
+	}
+	return order
+}
+
+// Return an unsigned integer with the shortest exact decimal or binary
+// suffix, eg. 2000 == 2K, 1048576 == 1Mi. The number is returned unchanged
+// if no suffix is shorter.
+func abbreviate(u uint64) string {
+	best := strconv.FormatUint(u, 10)
+	if u == 0 {
+		return best
+	}
+	d := uint64(1)
+	for i, c := range "KMGTPE" {
+		d *= 1000
+		if u%d == 0 {
+			if s := strconv.FormatUint(u/d, 10) + string(c); len(s) < len(best) {
+				best = s
+			}
+		}
+		shift := uint(10 * (i + 1))
+		if u%(1<<shift) == 0 {
+			if s := strconv.FormatUint(u>>shift, 10) + string(c) + "i"; len(s) < len(best) {
+				best = s
+			}
+		}
+	}
+	return best
+}
+
 func isZero(v reflect.Value) bool {
 	z := reflect.Zero(v.Type())
 	return v.Interface() == z.Interface()
 }
 
 func isZeroStruct(v reflect.Value) bool {
+	if isBigType(v.Type()) {
+		return isZeroBig(v)
+	}
+	if isTextType(v.Type()) {
+		return formatText(v) == ""
+	}
 	switch v.Kind() {
 	case reflect.Func, reflect.Map, reflect.Slice:
 		return v.IsNil()
@@ -300,7 +587,7 @@ func isZeroStruct(v reflect.Value) bool {
 }
 
 // Break long lines at word boundaries
-func encodeMultiline(parent_key, str string) string {
+func (o *Encoder) encodeMultiline(parent_key, str string) string {
 	var ar []string
 	var i, n int
 	width := multi_line_width - (len(parent_key) + 3)
@@ -322,20 +609,22 @@ func encodeMultiline(parent_key, str string) string {
 				n++
 			}
 		}
-		ar = append(ar, quote(str[i:n]))
+		ar = append(ar, o.quote(str[i:n]))
 		i = n
 	}
-	ar = append(ar, quote(str[i:]))
+	ar = append(ar, o.quote(str[i:]))
 	indent := strings.Repeat(" ", len(parent_key)+3)
 	return strings.Join(ar, "\\\n"+indent)
 }
 
-func needs_heredoc(str string) bool {
-	// if string has more than 3 newlines
-	if strings.Count(str, "\n") > 3 {
-		return true
-	}
-	return false
+// Return true if a string has more newlines than the heredoc threshold.
+func (o *Encoder) needsHeredoc(str string) bool {
+	return !o.noHeredocs() && strings.Count(str, lf) > o.heredocLines
+}
+
+// Heredocs are disabled by a negative threshold, or by SetQuoteStrings.
+func (o *Encoder) noHeredocs() bool {
+	return o.heredocLines < 0 || o.quoteAll
 }
 
 func output_heredoc(str string) string {
@@ -352,18 +641,43 @@ func output_heredoc(str string) string {
 }
 
 func (o *Encoder) write_kv(depth int, key string, v interface{}) {
-	key = setKeyCase(o.options, key)
+	key = o.keyCase(key)
+	if o.isOption(ENCODE_FLAT) && len(o.path) > 0 {
+		key = strings.Join(o.path, ".") + "." + key
+	}
 	o.write(depth, fmt.Sprintf("%s = %v\n", key, v))
 }
 
+// Open the block of a nested struct or map. With the ENCODE_FLAT option, the
+// key is added to the prefix of the keys which follow instead.
+func (o *Encoder) openBlock(depth int, key string) {
+	if o.isOption(ENCODE_FLAT) {
+		o.path = append(o.path, o.keyCase(key))
+		return
+	}
+	o.write_kv(depth, key, "{")
+}
+
+// Close the block opened by openBlock.
+func (o *Encoder) closeBlock(depth int) {
+	if o.isOption(ENCODE_FLAT) {
+		o.path = o.path[:len(o.path)-1]
+		return
+	}
+	o.write(depth, "}\n")
+}
+
 func (o *Encoder) write(depth int, s string) {
 	indent := ""
-	for i := depth; i > 1; i-- {
+	for i := depth; i > 1 && !o.isOption(ENCODE_FLAT); i-- {
 		indent += "  "
 	}
-	_, err := o.writer.Write([]byte(indent + s))
-	if err != nil {
-		o.appendErr("%s", err)
+	if o.werr != nil {
+		return
+	}
+	if _, err := io.WriteString(o.writer, indent+s); err != nil {
+		o.werr = err
+		o.errs = append(o.errs, err)
 	}
 }
 
@@ -371,11 +685,20 @@ func (o *Encoder) isOption(opt int) bool {
 	return opt == opt&o.options
 }
 
-func quote(s string) string {
+// Quote a string if it contains characters which must be escaped, or leading
+// or trailing space, or if SetQuoteStrings is used. Non-ASCII characters are
+// escaped unless SetUTF8 is used.
+func (o *Encoder) quote(s string) string {
 	if len(s) == 0 {
 		return ""
 	}
 	q := strconv.QuoteToASCII(s)
+	if o.utf8 {
+		q = strconv.Quote(s)
+	}
+	if o.quoteAll {
+		return q
+	}
 	l := len(q)
 	if q[1:l-1] != s {
 		// return quoted string
@@ -393,19 +716,11 @@ func quote(s string) string {
 
 // Horked from unicode package
 func toUpper(s string) string {
-	z := []byte(s)
-	for i := 0; i < len(z); i++ {
-		z[i] = upper(z[i])
-	}
-	return string(z)
+	return strings.Map(upper, s)
 }
 
-// Horked from unicode package
-func upper(r byte) byte {
-	if 'a' <= r && r <= 'z' {
-		r -= 'a' - 'A'
-	}
-	return r
+func upper(r rune) rune {
+	return unicode.ToUpper(r)
 }
 
 func isStructPtr(x interface{}) bool {
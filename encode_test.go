@@ -10,6 +10,11 @@ import (
 	"log"
 	"time"
 	"bytes"
+	"bufio"
+	"regexp"
+	"strings"
+	"io"
+	"net"
 	"testing"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -184,16 +189,74 @@ Float64 = 1.7976931348623157e+308
 
 }
 
+func TestEncode_Abbreviations(t *testing.T) {
+
+	Convey("Encode integers with abbreviations", t, func() {
+		x := struct {
+			A int
+			B int64
+			C uint32
+			D int
+			E int
+			F uint64
+			G time.Duration
+		}{2000, 1 << 20, 1024000, -3000, 1234, 1 << 63, time.Second}
+		cfg := `A = 2K
+B = 1Mi
+C = 1024K
+D = -3K
+E = 1234
+F = 8Ei
+G = 1s
+`
+		b1, err := Encode(x, ENCODE_ABBREVIATIONS)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, cfg)
+		var y struct {
+			A int
+			B int64
+			C uint32
+			D int
+			E int
+			F uint64
+			G time.Duration
+		}
+		So(Decode(&y, b1), ShouldBeNil)
+		So(y.B, ShouldEqual, x.B)
+		So(y.F, ShouldEqual, x.F)
+	})
+
+}
+
+func TestEncode_Complex(t *testing.T) {
+
+	Convey("Encode complex numbers", t, func() {
+		x := struct {
+			C64  complex64
+			C128 complex128
+			Zero complex128
+		}{3 + 4i, -1.5e-3 - 2i, 0}
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "C64 = 3+4i\nC128 = -0.0015-2i\n")
+		y := x
+		y.C64, y.C128 = 0, 0
+		So(Decode(&y, b1), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+}
+
 func TestEncode_ForceErrors(t *testing.T) {
 
 	var xStruct struct {
-		Cplx complex128
+		Ch chan int
 	}
 
-	Convey("Attempt to encode complex number", t, func() {
+	Convey("Attempt to encode a channel", t, func() {
 		_, err := Encode(xStruct)
 		So(err, ShouldNotBeNil)
-		So(err.Error(), ShouldEqual, "Cannot encode type (complex128)")
+		So(err.Error(), ShouldEqual, "Cannot encode type (chan)")
 	})
 
 	Convey("Force a write error", t, func() {
@@ -414,6 +477,31 @@ func TestEncode_Maps(t *testing.T) {
 		So(string(b1), ShouldEqual, cfg)
 	})
 
+	Convey("Encode a map of interface values", t, func() {
+		x := map[string]interface{}{
+			"Key1": "String1",
+			"Map1": map[string]interface{}{
+				"Key2": 42.0,
+				"Key3": true,
+			},
+		}
+		cfg := "Key1 = String1\n" +
+			"Map1 = {\n" +
+			"  Key2 = 42\n" +
+			"  Key3 = True\n" +
+			"}\n"
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, cfg)
+	})
+
+	Convey("Force error: Encode a nil interface value", t, func() {
+		x := map[string]interface{}{"Key1": nil}
+		_, err := Encode(x)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Cannot encode nil value (Key1)")
+	})
+
 	Convey("Encode A Map of Time Values", t, func() {
 		x := struct {
 			Map1 timeMap
@@ -558,3 +646,307 @@ func TestEncodeToFile(t *testing.T) {
 	})
 
 }
+
+func TestEncode_Flat(t *testing.T) {
+
+	type T struct {
+		Name   string
+		Server struct {
+			Host string
+			Tls  struct {
+				Port int
+			}
+		}
+		Limits map[string]int
+	}
+	var x T
+	x.Name = "app"
+	x.Server.Host = "example.com"
+	x.Server.Tls.Port = 443
+	x.Limits = map[string]int{"a b": 1, "max": 2}
+
+	Convey("Encode nested structs and maps as dotted keys", t, func() {
+		b, err := Encode(x, ENCODE_FLAT)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "Name = app\nServer.Host = example.com\nServer.Tls.Port = 443\nLimits.\"a b\" = 1\nLimits.max = 2\n")
+		var y T
+		So(Decode(&y, b), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+	Convey("Encode dotted keys in snake case", t, func() {
+		b, err := Encode(x, ENCODE_FLAT|ENCODE_SNAKE_CASE)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldContainSubstring, "server.tls.port = 443\n")
+	})
+
+}
+
+// countWriter counts the calls to Write.
+type countWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n++
+	return w.Buffer.Write(p)
+}
+
+func TestEncode_Buffered(t *testing.T) {
+
+	m := make(map[string]int)
+	for i := 1; i <= 1000; i++ {
+		m[fmt.Sprintf("Key%04d", i)] = i
+	}
+
+	Convey("Lines are written through a buffer", t, func() {
+		var w countWriter
+		So(NewEncoder(m).ToStream(&w), ShouldBeNil)
+		So(w.Len(), ShouldEqual, 13893)
+		So(w.n, ShouldBeLessThan, 5)
+	})
+
+	Convey("A buffered writer is flushed by the caller", t, func() {
+		var w countWriter
+		bw := bufio.NewWriter(&w)
+		o := NewEncoder(map[string]int{"A": 1})
+		So(o.ToStream(bw), ShouldBeNil)
+		So(NewEncoder(map[string]int{"B": 2}).ToStream(bw), ShouldBeNil)
+		So(w.Len(), ShouldEqual, 0)
+		So(o.Flush(), ShouldBeNil)
+		So(w.String(), ShouldEqual, "A = 1\nB = 2\n")
+	})
+
+	Convey("Force error: Nothing is written after a write error", t, func() {
+		var w countWriter
+		o := NewEncoder(m)
+		err := o.ToStream(io.MultiWriter(&w, failWriter{}))
+		So(err.Error(), ShouldEqual, io.ErrClosedPipe.Error())
+		So(w.n, ShouldEqual, 1)
+		So(o.Flush(), ShouldEqual, io.ErrClosedPipe)
+	})
+
+}
+
+func TestEncode_SortedFields(t *testing.T) {
+
+	type T struct {
+		Zone   string
+		Name   string
+		Server struct {
+			Port int
+			Host string
+		}
+		MaxConns int
+	}
+	x := T{Zone: "z", Name: "Rick", MaxConns: 10}
+	x.Server.Port = 80
+	x.Server.Host = "localhost"
+
+	Convey("Encode struct fields sorted by key", t, func() {
+		b, err := Encode(x, ENCODE_SORTED_FIELDS)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "MaxConns = 10\nName = Rick\nServer = {\n  Host = localhost\n  Port = 80\n}\nZone = z\n")
+	})
+
+	Convey("Fields are sorted by their converted keys", t, func() {
+		y := struct {
+			MaxConns int
+			Maxage   int
+		}{10, 1}
+		b, err := Encode(y, ENCODE_SORTED_FIELDS)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "MaxConns = 10\nMaxage = 1\n")
+		b, err = Encode(y, ENCODE_SORTED_FIELDS|ENCODE_LOWER_CASE)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "maxage = 1\nmaxconns = 10\n")
+	})
+
+}
+
+func TestEncode_AlignValues(t *testing.T) {
+
+	type T struct {
+		Name   string
+		Server struct {
+			Host     string
+			MaxConns int
+			Limits   map[string]int
+		}
+		Text     string
+		List     string
+		LongName int
+	}
+	x := T{Name: "Rick", LongName: 1}
+	x.Server.Host = "localhost"
+	x.Server.MaxConns = 10
+	x.Server.Limits = map[string]int{"Min": 1, "Maximum": 2}
+	x.Text = strings.Repeat("line one\n", 6)
+	x.List = strings.Repeat("word ", 30) + "end"
+
+	Convey("Align the values of each block", t, func() {
+		var b []byte
+		err := NewEncoder(x).SetAlignValues(true).ToBytes(&b)
+		So(err, ShouldBeNil)
+		// the terminating code of a heredoc varies
+		out := regexp.MustCompile(`__\w+__`).ReplaceAllString(string(b), "END")
+		So(out, ShouldEqual, `Name     = Rick
+Server   = {
+  Host     = localhost
+  MaxConns = 10
+  Limits   = {
+    Maximum = 2
+    Min     = 1
+  }
+}
+Text     = <<END
+line one
+line one
+line one
+line one
+line one
+line one
+
+END
+List     = word word word word word word word word word word word word word word word\
+           " word word word word word word word word word word word word word word word"\
+           " end"
+LongName = 1
+`)
+		var y T
+		So(Decode(&y, b), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+	Convey("Align the values of a template", t, func() {
+		b, err := NewEncoder(struct {
+			Name     string `comment:"Your name"`
+			MaxConns int
+		}{}).SetAlignValues(true).Template()
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "# Your name\nName     = \"\"\nMaxConns = 0\n")
+	})
+
+}
+
+func TestEncode_Cycles(t *testing.T) {
+
+	Convey("A map may appear more than once", t, func() {
+		shared := map[string]interface{}{"Port": 80}
+		m := map[string]interface{}{"A": shared, "B": shared}
+		b, err := Encode(m)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "A = {\n  Port = 80\n}\nB = {\n  Port = 80\n}\n")
+	})
+
+	Convey("Force error: Map contains itself", t, func() {
+		m := map[string]interface{}{"Name": "Rick"}
+		m["Self"] = map[string]interface{}{"Parent": m}
+		_, err := Encode(m)
+		So(err.Error(), ShouldEqual, "Cannot encode a map which contains itself (Parent)")
+	})
+
+	Convey("Force error: Nesting is too deep", t, func() {
+		m := map[string]interface{}{"Port": 80}
+		for i := 0; i < 150; i++ {
+			m = map[string]interface{}{"A": m}
+		}
+		_, err := Encode(m)
+		So(err.Error(), ShouldEqual, "Nesting exceeds limit of 100 (A)")
+	})
+
+}
+
+func TestEncode_UTF8(t *testing.T) {
+
+	type T struct {
+		Greeting string
+		Padded   string
+		Control  string
+	}
+	x := T{"Grüße, 世界", " Grüße ", "Grüße\t世界"}
+
+	Convey("Non-ASCII characters are escaped by default", t, func() {
+		b, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, `Greeting = "Gr\u00fc\u00dfe, \u4e16\u754c"
+Padded = " Gr\u00fc\u00dfe "
+Control = "Gr\u00fc\u00dfe\t\u4e16\u754c"
+`)
+	})
+
+	Convey("Printable characters are written as is with SetUTF8", t, func() {
+		var b []byte
+		err := NewEncoder(x).SetUTF8(true).ToBytes(&b)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "Greeting = Grüße, 世界\nPadded = \" Grüße \"\nControl = \"Grüße\\t世界\"\n")
+		var y T
+		So(Decode(&y, b), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+}
+
+func TestEncode_QuoteStrings(t *testing.T) {
+
+	type T struct {
+		Name    string
+		Port    string
+		Comment string
+		Addr    net.IP
+		Count   int
+		Text    string
+	}
+	x := T{"Rick", "8080", "a # b", net.ParseIP("10.0.0.1"), 3, strings.Repeat("line\n", 12)}
+
+	Convey("Quote every string value", t, func() {
+		var b []byte
+		err := NewEncoder(x).SetQuoteStrings(true).ToBytes(&b)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, `Name = "Rick"
+Port = "8080"
+Comment = "a # b"
+Addr = "10.0.0.1"
+Count = 3
+Text = "line\nline\nline\nline\nline\nline\nline\nline\nline\nline\nline\nline\n"
+`)
+		var y T
+		So(Decode(&y, b), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+}
+
+func TestEncode_HeredocThreshold(t *testing.T) {
+
+	x := struct {
+		Short string
+		Long  string
+	}{"one\ntwo", "1\n2\n3\n4\n5"}
+	heredoc := regexp.MustCompile(`__\w+__`)
+
+	Convey("Only strings with more than 3 newlines are heredocs by default", t, func() {
+		b, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(heredoc.ReplaceAllString(string(b), "END"), ShouldEqual, "Short = \"one\\ntwo\"\nLong = <<END\n1\n2\n3\n4\n5\nEND\n")
+	})
+
+	Convey("A threshold of 0 writes every multi-line string as a heredoc", t, func() {
+		var bs []byte
+		err := NewEncoder(x).SetHeredocThreshold(0).ToBytes(&bs)
+		So(err, ShouldBeNil)
+		So(heredoc.ReplaceAllString(string(bs), "END"), ShouldEqual, "Short = <<END\none\ntwo\nEND\nLong = <<END\n1\n2\n3\n4\n5\nEND\n")
+	})
+
+	Convey("A negative threshold disables heredocs", t, func() {
+		var bs []byte
+		err := NewEncoder(x).SetHeredocThreshold(-1).ToBytes(&bs)
+		So(err, ShouldBeNil)
+		So(string(bs), ShouldEqual, "Short = \"one\\ntwo\"\nLong = \"1\\n2\\n3\\n4\\n5\"\n")
+		var y struct{ Short, Long string }
+		So(Decode(&y, bs), ShouldBeNil)
+		So(y.Long, ShouldEqual, x.Long)
+	})
+
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// A ParseError is an error in a source, along with its position and the
+// dotted key it concerns, when they are known. Errors in a file are reported
+// as file:line: key: message, eg.
+//
+//	db.conf:14: Database.Pool.Max: Overflow
+//
+// and other errors as key: message at line N.
+type ParseError struct {
+	File string // Name of the source file, or empty if unknown
+	Line int    // Line number in source, or zero if unknown
+	Key  string // Dotted key, or empty if unknown
+	Msg  string // Description of the error
+	Err  error  // Underlying error, such as ErrOverflow, or nil
+}
+
+func (e *ParseError) Error() string {
+	return formatPosition(e.File, e.Line, e.Key, e.Msg)
+}
+
+// Format a message with the position and key it concerns, as file:line: key:
+// message, or key: message at line N when the file is unknown. Used by both
+// ParseError and Warning.
+func formatPosition(file string, line int, key, msg string) string {
+	s := msg
+	if key != "" {
+		s = key + ": " + s
+	}
+	if file == "" {
+		if line > 0 {
+			s += fmt.Sprintf(" at line %d", line)
+		}
+		return s
+	}
+	if line > 0 {
+		return fmt.Sprintf("%s:%d: %s", file, line, s)
+	}
+	return file + ": " + s
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// The classes of errors found in a source. Each is reported within a
+// ParseError, which gives its position, and may be tested with errors.Is, eg.
+//
+//	if errors.Is(err, config.ErrDuplicateKey) {
+var (
+	ErrDuplicateKey        = errors.New("Duplicate key")
+	ErrExtraField          = errors.New("Extra field")
+	ErrInvalidKey          = errors.New("Invalid key")
+	ErrMissingBrace        = errors.New("Missing closing brace")
+	ErrOverflow            = errors.New("Overflow")
+	ErrUnterminatedHeredoc = errors.New("No terminating heredoc code")
+)
+
+// multiError is a list of errors reported as one. Each error remains
+// available to errors.Is and errors.As.
+type multiError struct {
+	msg  string
+	errs []error
+}
+
+func (e *multiError) Error() string {
+	return e.msg
+}
+
+func (e *multiError) Unwrap() []error {
+	return e.errs
+}
+
+// ErrNotExist is returned, wrapped, by the file functions of this package when
+// a file does not exist. It wraps os.ErrNotExist, so either may be tested with
+// errors.Is.
+var ErrNotExist = fmt.Errorf("config file does not exist: %w", os.ErrNotExist)
+
+// notExistError wraps ErrNotExist along with the original *os.PathError.
+type notExistError struct {
+	err error
+}
+
+func (e *notExistError) Error() string {
+	return e.err.Error()
+}
+
+func (e *notExistError) Unwrap() []error {
+	return []error{ErrNotExist, e.err}
+}
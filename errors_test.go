@@ -0,0 +1,220 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrNotExist(t *testing.T) {
+
+	missing := filepath.Join(TEMP_DIR, "GOTEST_CONFIG_MISSING.conf")
+
+	Convey("Missing files wrap ErrNotExist and os.ErrNotExist", t, func() {
+		var x struct{ Name string }
+		err := DecodeFile(missing, &x)
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+		So(errors.Is(err, os.ErrNotExist), ShouldBeTrue)
+		var pe *os.PathError
+		So(errors.As(err, &pe), ShouldBeTrue)
+		So(pe.Path, ShouldEqual, missing)
+
+		_, err = ParseFile(missing)
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+	})
+
+	Convey("DecodeFileIfExists treats a missing file as a no-op", t, func() {
+		x := struct{ Name string }{"Rick"}
+		So(DecodeFileIfExists(missing, &x), ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = Morty"))
+		So(DecodeFileIfExists(tempfile, &x), ShouldBeNil)
+		So(x.Name, ShouldEqual, "Morty")
+
+		writeFile(tempfile, []byte("Color = green"))
+		So(DecodeFileIfExists(tempfile, &x), ShouldNotBeNil)
+	})
+
+}
+
+func TestParseError(t *testing.T) {
+
+	type T struct {
+		Database struct {
+			Pool struct {
+				Max int8
+			}
+		}
+	}
+
+	Convey("Errors name the key and line", t, func() {
+		var x T
+		err := Decode(&x, "Database {\n  Pool {\n    Max = 200\n  }\n}")
+		So(err.Error(), ShouldEqual, "Database.Pool.Max: Overflow at line 3")
+		var pe *ParseError
+		So(errors.As(err, &pe), ShouldBeTrue)
+		So(*pe, ShouldResemble, ParseError{"", 3, "Database.Pool.Max", "Overflow", ErrOverflow})
+	})
+
+	Convey("Errors in a file name the file", t, func() {
+		tempfile1 := createTempFile("GOTEST_CONFIG")
+		tempfile2 := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile1)
+		defer os.Remove(tempfile2)
+		var x T
+
+		writeFile(tempfile1, []byte("Database {\n  Pool {\n    Max = 200\n  }\n}"))
+		err := DecodeFile(tempfile1, &x)
+		So(err.Error(), ShouldEqual, tempfile1+":3: Database.Pool.Max: Overflow")
+
+		writeFile(tempfile1, []byte("Database {\n  Pool {\n    Max = 1 +\n  }\n}"))
+		writeFile(tempfile2, []byte("include "+tempfile1))
+		err = DecodeFile(tempfile2, &x)
+		So(err.Error(), ShouldContainSubstring, tempfile1+":3: Database.Pool.Max: Invalid expression")
+
+		writeFile(tempfile1, []byte("A = 1\nA = 2"))
+		_, err = ParseFile(tempfile1)
+		So(err.Error(), ShouldEqual, tempfile1+":2: Duplicate key")
+	})
+
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestErrorWrapping(t *testing.T) {
+
+	missing := filepath.Join(TEMP_DIR, "GOTEST_CONFIG_MISSING.conf")
+
+	Convey("Errors in included files keep the underlying error", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = Rick\ninclude "+missing))
+
+		var x struct{ Name string }
+		err := DecodeFile(tempfile, &x)
+		So(err, ShouldNotBeNil)
+		So(errors.Is(err, os.ErrNotExist), ShouldBeTrue)
+		var pe *os.PathError
+		So(errors.As(err, &pe), ShouldBeTrue)
+		So(pe.Path, ShouldEqual, missing)
+
+		_, err = ParseFile(tempfile)
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+	})
+
+	Convey("Write errors of the encoder are returned unchanged", t, func() {
+		x := struct{ Name string }{"Rick"}
+		err := NewEncoder(x).ToStream(failWriter{})
+		So(errors.Is(err, io.ErrClosedPipe), ShouldBeTrue)
+	})
+
+}
+
+func TestSentinelErrors(t *testing.T) {
+
+	type T struct {
+		Name  string
+		Small int8
+		Block struct {
+			Text string
+		}
+	}
+
+	Convey("Errors may be tested by class", t, func() {
+		srcs := []struct {
+			src  string
+			err  error
+			line int
+		}{
+			{"Name = a\nName = b", ErrDuplicateKey, 2},
+			{"Name = a\nColor = green", ErrExtraField, 2},
+			{"Name = a\n.Name = b", ErrInvalidKey, 2},
+			{"Name = a\nBlock {\n  Text = b", ErrMissingBrace, 2},
+			{"Small = 300", ErrOverflow, 1},
+			{"Name = <<END\nb\nc", ErrUnterminatedHeredoc, 3},
+		}
+		for _, s := range srcs {
+			var x T
+			err := Decode(&x, s.src)
+			So(errors.Is(err, s.err), ShouldBeTrue)
+			var pe *ParseError
+			So(errors.As(err, &pe), ShouldBeTrue)
+			So(pe.Line, ShouldEqual, s.line)
+		}
+	})
+
+}
+
+func TestErrorModes(t *testing.T) {
+
+	type T struct {
+		Name  string
+		Small int8
+		Large int8
+	}
+
+	parse := "Name = a\nName = b\n.Bad = c\nName = d"
+	decode := "Small = 300\nLarge = 400\nColor = green\nShape = round"
+
+	Convey("The parser reports every error by default", t, func() {
+		_, err := Parse(parse)
+		So(err.Error(), ShouldEqual, "Duplicate key at line 2\nInvalid key at line 3\nDuplicate key at line 4")
+	})
+
+	Convey("The parser stops at the first error with FAIL_FAST", t, func() {
+		_, err := Parse(parse, FAIL_FAST)
+		So(err.Error(), ShouldEqual, "Duplicate key at line 2")
+		var x T
+		So(Decode(&x, parse, FAIL_FAST).Error(), ShouldEqual, "Duplicate key at line 2")
+	})
+
+	Convey("The parser stops at the error limit", t, func() {
+		_, err := NewParser().SetLimits(Limits{MaxErrors: 2}).Parse([]byte(parse))
+		So(err.Error(), ShouldEqual, "Duplicate key at line 2\nInvalid key at line 3")
+	})
+
+	Convey("The decoder stops at the first error in a value by default", t, func() {
+		var x T
+		So(Decode(&x, decode).Error(), ShouldEqual, "Small: Overflow at line 1")
+	})
+
+	Convey("The decoder reports every error with COLLECT_ERRORS", t, func() {
+		var x T
+		err := Decode(&x, decode, COLLECT_ERRORS)
+		So(err.Error(), ShouldEqual, "Small: Overflow at line 1\nLarge: Overflow at line 2\nExtra field (Color) at line 3\nExtra field (Shape) at line 4")
+		So(errors.Is(err, ErrOverflow), ShouldBeTrue)
+		So(errors.Is(err, ErrExtraField), ShouldBeTrue)
+
+		Convey("Errors in the source are reported too", func() {
+			err := Decode(&x, "Small = 300\nName = a\nName = b", COLLECT_ERRORS)
+			So(err.Error(), ShouldEqual, "Duplicate key at line 3\nSmall: Overflow at line 1")
+		})
+
+		Convey("Up to the error limit", func() {
+			err := NewDecoder(&x, COLLECT_ERRORS).SetLimits(Limits{MaxErrors: 3}).DecodeString(decode)
+			So(err.Error(), ShouldEqual, "Small: Overflow at line 1\nLarge: Overflow at line 2\nExtra field (Color) at line 3")
+			err = NewDecoder(&x, COLLECT_ERRORS|FAIL_FAST).DecodeString(decode)
+			So(err.Error(), ShouldEqual, "Small: Overflow at line 1")
+		})
+	})
+
+	Convey("Extra fields are limited by FAIL_FAST", t, func() {
+		var x T
+		So(Decode(&x, "Color = green\nShape = round", FAIL_FAST).Error(), ShouldEqual, "Extra field (Color) at line 1")
+	})
+
+}
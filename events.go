@@ -0,0 +1,280 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// BEGIN_BLOCK indicates the opening brace of a block.
+	BEGIN_BLOCK = iota + 1
+
+	// END_BLOCK indicates the closing brace of a block.
+	END_BLOCK
+
+	// KEY_VALUE indicates a key and its value.
+	KEY_VALUE
+
+	// COMMENT indicates a comment, on a line of its own or after a value.
+	COMMENT
+
+	// INCLUDE indicates an include statement.
+	INCLUDE
+)
+
+var errInvalidData = errors.New("Invalid data")
+
+// An Event is a single element of a source, reported by ParseEvents.
+type Event struct {
+	Kind  int    // BEGIN_BLOCK, END_BLOCK, KEY_VALUE, COMMENT or INCLUDE
+	Line  int    // Line number in source
+	Depth int    // Number of enclosing blocks
+	Key   string // Dotted key of a block or value, or prefix of an included file
+	Value string // Value of a key, text of a comment or name of an included file
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case BEGIN_BLOCK:
+		return fmt.Sprintf("%d: %s {", e.Line, e.Key)
+	case END_BLOCK:
+		return fmt.Sprintf("%d: } %s", e.Line, e.Key)
+	case KEY_VALUE:
+		return fmt.Sprintf("%d: %s = %q", e.Line, e.Key, e.Value)
+	case COMMENT:
+		return fmt.Sprintf("%d: # %s", e.Line, e.Value)
+	}
+	if e.Key != "" {
+		return fmt.Sprintf("%d: include %s as %s", e.Line, e.Value, e.Key)
+	}
+	return fmt.Sprintf("%d: include %s", e.Line, e.Value)
+}
+
+// ParseEvents reads a source and calls fn for each element of it, in order,
+// without building a map of its keys. See Parser.ParseEvents.
+func ParseEvents(r io.Reader, fn func(Event) error) error {
+	return NewParser().ParseEvents(r, fn)
+}
+
+// ParseEvents reads a source and calls fn for each element of it, in order,
+// without building a map of its keys. Parsing stops at the first error,
+// including an error returned by fn, which is returned.
+//
+// Values are reported as written: included files are not read, and
+// references, profiles, conditions and anchors are not applied. The
+// @profile, @if and anchor sections are reported as blocks whose key is the
+// directive, eg. "@profile production", and which do not prefix the keys
+// within them. The keys of an inline block are reported in sorted order.
+func (o *Parser) ParseEvents(r io.Reader, fn func(Event) error) error {
+	br, err := gunzip(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	o.reader = br
+	if o.limits.MaxBytes > 0 {
+		o.reader = bufio.NewReader(&limitedReader{r: br, max: o.limits.MaxBytes})
+	}
+	e := eventParser{Parser: o, fn: fn}
+	for {
+		b, err := o.readLine()
+		if err != nil && (err != io.EOF || len(b) == 0) {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		o.lineno++
+		s, cmt := string(b), ""
+		if i := strings.IndexByte(s, '#'); i >= 0 {
+			s, cmt = s[:i], trim(s[i+1:])
+		}
+		if s = trim(s); s != "" {
+			if err := e.line(s); err != nil {
+				return err
+			}
+		}
+		if cmt != "" {
+			if err := e.emit(COMMENT, "", cmt); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if n := len(e.blocks); n > 0 {
+		return &ParseError{File: o.filename, Line: e.blocks[n-1].line, Msg: ErrMissingBrace.Error(), Err: ErrMissingBrace}
+	}
+	return nil
+}
+
+// The state of ParseEvents
+type eventParser struct {
+	*Parser
+	fn     func(Event) error
+	blocks []eventBlock
+}
+
+type eventBlock struct {
+	key    string // Key of the block, or the directive of a section
+	prefix string // Prefix of the keys within the block
+	line   int
+}
+
+func (e *eventParser) emit(kind int, key, val string) error {
+	return e.fn(Event{kind, e.lineno, len(e.blocks), key, val})
+}
+
+// Return the prefix of the keys of the current block
+func (e *eventParser) prefix() string {
+	if n := len(e.blocks); n > 0 {
+		return e.blocks[n-1].prefix
+	}
+	return ""
+}
+
+func (e *eventParser) begin(key, val string, section bool) error {
+	prefix := e.prefix()
+	if !section {
+		key = prefix + key
+		prefix = key + "."
+	}
+	if err := e.fn(Event{BEGIN_BLOCK, e.lineno, len(e.blocks), key, val}); err != nil {
+		return err
+	}
+	e.blocks = append(e.blocks, eventBlock{key, prefix, e.lineno})
+	return nil
+}
+
+func (e *eventParser) fail(err error) error {
+	return &ParseError{File: e.filename, Line: e.lineno, Msg: err.Error(), Err: err}
+}
+
+// Report the elements of one line of the source
+func (e *eventParser) line(s string) error {
+	m := getMatches()
+	defer putMatches(m)
+	switch {
+	case findSubmatch(include, s, m):
+		return e.emit(INCLUDE, m.a[2], strings.Trim(m.a[1], `"`))
+
+	case findSubmatch(profile, s, m), findSubmatch(condition, s, m), findSubmatch(anchor, s, m):
+		return e.begin(trim(strings.TrimSuffix(s, "{")), "", true)
+
+	case findSubmatch(quoted_key, s, m):
+		key, err := quotedKeyPath(m.a[1])
+		if err != nil {
+			return e.fail(err)
+		}
+		return e.value(key, m.a[2])
+
+	case findSubmatch(alias, s, m):
+		if m.a[3] != "" {
+			return e.begin(m.a[1], "*"+m.a[2], false)
+		}
+		return e.emit(KEY_VALUE, e.prefix()+m.a[1], "*"+m.a[2])
+
+	case findSubmatch(inline_block, s, m):
+		return e.inline(m.a[1], m.a[2])
+
+	case findSubmatch(open_brace, s, m):
+		return e.begin(m.a[1], "", false)
+
+	case findSubmatch(close_brace, s, m):
+		n := len(e.blocks)
+		if n == 0 {
+			return e.fail(errInvalidData)
+		}
+		b := e.blocks[n-1]
+		e.blocks = e.blocks[:n-1]
+		return e.emit(END_BLOCK, b.key, "")
+
+	case findSubmatch(heredoc, s, m):
+		key, line := m.a[1], e.lineno
+		val, err := e.readHereDoc(m.a[2])
+		if err == nil {
+			val, err = unquote(val)
+		}
+		if err != nil {
+			return e.fail(err)
+		}
+		return e.fn(Event{KEY_VALUE, line, len(e.blocks), e.prefix() + key, val})
+
+	case findSubmatch(multiline, s, m):
+		key, line := m.a[1], e.lineno
+		val, err := unquote(e.readMultiLine(m.a[2]))
+		if len(e.errs) > 0 {
+			return e.errs[0]
+		}
+		if err != nil {
+			return e.fail(err)
+		}
+		return e.fn(Event{KEY_VALUE, line, len(e.blocks), e.prefix() + key, val})
+
+	case findSubmatch(keyval, s, m):
+		if badKey(m.a[1]) {
+			return e.fail(ErrInvalidKey)
+		}
+		return e.value(m.a[1], m.a[2])
+	}
+	return e.fail(errInvalidData)
+}
+
+// Report a key and value, or the opening of a block
+func (e *eventParser) value(key, val string) error {
+	switch {
+	case val == "":
+		return e.fail(errInvalidData)
+	case val == "{":
+		return e.begin(key, "", false)
+	case len(val) > 1 && val[0] == '{' && val[len(val)-1] == '}':
+		return e.inline(key, val[1:len(val)-1])
+	}
+	val, err := parseValue(val)
+	if err != nil {
+		return e.fail(err)
+	}
+	if val == null_value {
+		val = ""
+	}
+	return e.emit(KEY_VALUE, e.prefix()+key, val)
+}
+
+// Report an inline block as a block and its keys
+func (e *eventParser) inline(key, s string) error {
+	m := make(fMap)
+	if err := e.inlineKeys(m, "", s); err != nil {
+		return e.fail(err)
+	}
+	if len(e.errs) > 0 {
+		return e.errs[0]
+	}
+	if err := e.begin(key, "", false); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		val := m[k].val
+		if val == null_value {
+			val = ""
+		}
+		if err := e.emit(KEY_VALUE, e.prefix()+k, val); err != nil {
+			return err
+		}
+	}
+	b := e.blocks[len(e.blocks)-1]
+	e.blocks = e.blocks[:len(e.blocks)-1]
+	return e.emit(END_BLOCK, b.key, "")
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseEvents(t *testing.T) {
+
+	src := `# Sample
+Name = "Rick Sanchez"   # the name
+include other.conf
+Server {
+  Host = localhost
+  Limits = { Min = 1, Max = ~ }
+  Text = <<END
+line
+END
+}
+@profile dev {
+  Debug = true
+}
+`
+
+	collect := func(src string) ([]string, error) {
+		var a []string
+		err := ParseEvents(strings.NewReader(src), func(e Event) error {
+			a = append(a, e.String())
+			return nil
+		})
+		return a, err
+	}
+
+	Convey("Events are reported in order", t, func() {
+		a, err := collect(src)
+		So(err, ShouldBeNil)
+		So(a, ShouldResemble, []string{
+			`1: # Sample`,
+			`2: Name = "Rick Sanchez"`,
+			`2: # the name`,
+			`3: include other.conf`,
+			`4: Server {`,
+			`5: Server.Host = "localhost"`,
+			`6: Server.Limits {`,
+			`6: Server.Limits.Max = ""`,
+			`6: Server.Limits.Min = "1"`,
+			`6: } Server.Limits`,
+			`7: Server.Text = "line"`,
+			`10: } Server`,
+			`11: @profile dev {`,
+			`12: Debug = "true"`,
+			`13: } @profile dev`,
+		})
+	})
+
+	Convey("Events carry their depth", t, func() {
+		var depths []int
+		ParseEvents(strings.NewReader("A {\n  B {\n    C = 1\n  }\n}\nD = 2"), func(e Event) error {
+			depths = append(depths, e.Depth)
+			return nil
+		})
+		So(depths, ShouldResemble, []int{0, 1, 2, 1, 0, 0})
+	})
+
+	Convey("An error from the callback stops parsing", t, func() {
+		stop := errors.New("stop")
+		n := 0
+		err := ParseEvents(strings.NewReader(src), func(e Event) error {
+			if n++; e.Kind == INCLUDE {
+				return stop
+			}
+			return nil
+		})
+		So(err, ShouldEqual, stop)
+		So(n, ShouldEqual, 4)
+	})
+
+	Convey("An include into a key prefix", t, func() {
+		a, err := collect("include backend.conf as Database")
+		So(err, ShouldBeNil)
+		So(a, ShouldResemble, []string{"1: include backend.conf as Database"})
+	})
+
+	Convey("Force error: Invalid sources", t, func() {
+		_, err := collect("A = 1\nServer {\n  B = 2")
+		So(err.Error(), ShouldEqual, "Missing closing brace at line 2")
+		So(errors.Is(err, ErrMissingBrace), ShouldBeTrue)
+
+		_, err = collect("A = 1\n}")
+		So(err.Error(), ShouldEqual, "Invalid data at line 2")
+
+		_, err = collect("A = 1\n.B = 2")
+		So(errors.Is(err, ErrInvalidKey), ShouldBeTrue)
+
+		_, err = collect("A = <<END\nline")
+		So(errors.Is(err, ErrUnterminatedHeredoc), ShouldBeTrue)
+	})
+
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var exprNumber = regexp.MustCompile(`^(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?`)
+
+// An expression parser for numeric values, eg. 4 * 1024 * 1024. Arithmetic
+// is exact, using rational numbers.
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+// Evaluate the value of an integer or float field if it is an arithmetic
+// expression, that is, if it contains spaces or parentheses and consists of
+// numbers and the operators +, -, * and /. Other values, such as numbers with
+// a unit suffix, are returned unchanged.
+func evalExpr(v1 reflect.Value, val string) (string, error) {
+	switch v1.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return val, nil
+	}
+	if v1.Type() == durationType || !strings.ContainsAny(val, " \t()") {
+		return val, nil
+	}
+	tokens, ok := exprTokens(val)
+	if !ok {
+		return val, nil
+	}
+	p := &exprParser{tokens: tokens}
+	r, err := p.sum()
+	if err == nil && p.pos < len(p.tokens) {
+		err = errors.New("Invalid expression")
+	}
+	if err != nil {
+		return val, err
+	}
+	if v1.Kind() == reflect.Float32 || v1.Kind() == reflect.Float64 {
+		f, _ := r.Float64()
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	}
+	if !r.IsInt() {
+		return val, errors.New("Expression result is not an integer")
+	}
+	return r.Num().String(), nil
+}
+
+// Split an expression into numbers, operators and parentheses.
+func exprTokens(s string) ([]string, bool) {
+	var tokens []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if strings.IndexByte("+-*/()", s[0]) >= 0 {
+			tokens = append(tokens, s[:1])
+			s = s[1:]
+			continue
+		}
+		n := exprNumber.FindString(s)
+		if n == "" {
+			return nil, false
+		}
+		tokens = append(tokens, n)
+		s = s[len(n):]
+	}
+	return tokens, true
+}
+
+func (o *exprParser) peek() string {
+	if o.pos < len(o.tokens) {
+		return o.tokens[o.pos]
+	}
+	return ""
+}
+
+// sum = product {("+" | "-") product}
+func (o *exprParser) sum() (*big.Rat, error) {
+	r, err := o.product()
+	for err == nil && (o.peek() == "+" || o.peek() == "-") {
+		op := o.tokens[o.pos]
+		o.pos++
+		var y *big.Rat
+		if y, err = o.product(); err != nil {
+			break
+		}
+		if op == "+" {
+			r.Add(r, y)
+		} else {
+			r.Sub(r, y)
+		}
+	}
+	return r, err
+}
+
+// product = factor {("*" | "/") factor}
+func (o *exprParser) product() (*big.Rat, error) {
+	r, err := o.factor()
+	for err == nil && (o.peek() == "*" || o.peek() == "/") {
+		op := o.tokens[o.pos]
+		o.pos++
+		var y *big.Rat
+		if y, err = o.factor(); err != nil {
+			break
+		}
+		if op == "*" {
+			r.Mul(r, y)
+		} else if y.Sign() == 0 {
+			err = errors.New("Division by zero")
+		} else {
+			r.Quo(r, y)
+		}
+	}
+	return r, err
+}
+
+// factor = ["-" | "+"] (number | "(" sum ")")
+func (o *exprParser) factor() (*big.Rat, error) {
+	switch t := o.peek(); t {
+	case "-", "+":
+		o.pos++
+		r, err := o.factor()
+		if err == nil && t == "-" {
+			r.Neg(r)
+		}
+		return r, err
+	case "(":
+		o.pos++
+		r, err := o.sum()
+		if err == nil && o.peek() != ")" {
+			err = errors.New("Missing closing parenthesis in expression")
+		}
+		o.pos++
+		return r, err
+	case "", "*", "/", ")":
+		return nil, errors.New("Invalid expression")
+	default:
+		o.pos++
+		r, ok := new(big.Rat).SetString(t)
+		if !ok {
+			return nil, errors.New("Invalid expression")
+		}
+		return r, nil
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExpressions(t *testing.T) {
+
+	type T struct {
+		CacheSize int
+		Base      int
+		Timeout   uint16
+		Ratio     float64
+		Offset    int8
+	}
+
+	Convey("Decode arithmetic expressions", t, func() {
+		var x T
+		err := Decode(&x, `
+			CacheSize = 4 * 1024 * 1024
+			Base = 10
+			Timeout = ${Base} + 30
+			Ratio = (1 + 2) / 4
+			Offset = -(2 * 3)
+		`)
+		So(err, ShouldBeNil)
+		So(x, ShouldResemble, T{4194304, 10, 40, 0.75, -6})
+	})
+
+	Convey("Force error: Invalid expressions", t, func() {
+		var x T
+		So(Decode(&x, "CacheSize = 4 * x").Error(), ShouldContainSubstring, "at line 1")
+		So(Decode(&x, "CacheSize = 4 *").Error(), ShouldEqual, "CacheSize: Invalid expression at line 1")
+		So(Decode(&x, "CacheSize = (4 * 2").Error(), ShouldEqual, "CacheSize: Missing closing parenthesis in expression at line 1")
+		So(Decode(&x, "CacheSize = 4 / 0").Error(), ShouldEqual, "CacheSize: Division by zero at line 1")
+		So(Decode(&x, "CacheSize = 3 / 2").Error(), ShouldEqual, "CacheSize: Expression result is not an integer at line 1")
+		So(Decode(&x, "Offset = 100 * 2").Error(), ShouldEqual, "Offset: Overflow at line 1")
+	})
+
+}
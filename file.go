@@ -0,0 +1,163 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const backup_time_fmt = "20060102-150405.000"
+
+// SetBackups causes ToFile to keep up to n timestamped copies of the file it
+// overwrites, named eg. app.conf.20180601-120000.000.bak. The oldest copies
+// are removed. If n is zero, the BACKUP_FILE option keeps a single copy named
+// app.conf.bak.
+func (o *Encoder) SetBackups(n int) *Encoder {
+	o.backups = n
+	return o
+}
+
+// Apply the SECURE_FILE and SECURE_DIR options to the target of ToFile.
+func (o *Encoder) checkSecure(filename string) error {
+	if o.isOption(SECURE_FILE) {
+		if fi, err := os.Lstat(filename); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			return errors.New("refusing to write through a symbolic link")
+		}
+	}
+	if o.isOption(SECURE_DIR) {
+		fi, err := os.Stat(filepath.Dir(filename))
+		if err != nil {
+			return err
+		}
+		if fi.Mode().Perm()&0006 != 0 {
+			return errors.New("directory is accessible to other users")
+		}
+	}
+	return nil
+}
+
+// Copy an existing file to its backup name before it is overwritten. If keep
+// is greater than zero, timestamped backups are rotated.
+func backupFile(filename string, mode os.FileMode, keep int) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if keep <= 0 {
+		return writeFileAtomic(filename+".bak", data, mode)
+	}
+	name := filename + "." + time.Now().Format(backup_time_fmt) + ".bak"
+	if err = writeFileAtomic(name, data, mode); err != nil {
+		return err
+	}
+	matches, err := filepath.Glob(filename + ".*.bak")
+	if err != nil {
+		return err
+	}
+	// only names with a backup timestamp, not eg. app.conf.mine.bak
+	var old []string
+	for _, f := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(f, filename+"."), ".bak")
+		if _, err := time.Parse(backup_time_fmt, stamp); err == nil {
+			old = append(old, f)
+		}
+	}
+	sort.Strings(old)
+	for len(old) > keep {
+		os.Remove(old[0])
+		old = old[1:]
+	}
+	return nil
+}
+
+// Write data to a temporary file in the same directory as filename, sync it
+// to disk and rename it over filename. The directory is synced afterward so
+// that the rename itself is durable. The owner and group of an existing file
+// are kept where possible.
+func writeFileAtomic(filename string, data []byte, mode os.FileMode) (err error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	fh, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpname := fh.Name()
+	defer func() {
+		if err != nil {
+			fh.Close()
+			os.Remove(tmpname)
+		}
+	}()
+	if fi, e := os.Stat(filename); e == nil {
+		copyOwner(fh, fi)
+	}
+	// We don't care if chmod returns an error. Just ignore it.
+	fh.Chmod(mode)
+	if _, err = fh.Write(data); err != nil {
+		return err
+	}
+	if err = fh.Sync(); err != nil {
+		return err
+	}
+	if err = fh.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpname, filename); err != nil {
+		return err
+	}
+	if d, e := os.Open(dir); e == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Expand a leading ~ or ~user to a home directory, and $VAR or ${VAR} to the
+// value of an environment variable.
+func expandPath(path string) (string, error) {
+	return expandPathVars(path, nil)
+}
+
+// Expand a path as expandPath does, taking the value of a variable from vars
+// if it is set there, and from the environment otherwise.
+func expandPathVars(path string, vars map[string]string) (string, error) {
+	var home string
+	if strings.HasPrefix(path, "~") {
+		name := path[1:]
+		rest := ""
+		if i := strings.IndexAny(name, `/\`); i >= 0 {
+			name, rest = name[:i], name[i:]
+		}
+		if name == "" {
+			h, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			home = h
+		} else {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			home = u.HomeDir
+		}
+		path = rest
+	}
+	return home + os.Expand(path, func(name string) string {
+		if s, ok := vars[name]; ok {
+			return s
+		}
+		return os.Getenv(name)
+	}), nil
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+
+	Convey("Replace a file without leaving temporary files behind", t, func() {
+		dir, err := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+
+		So(EncodeToFile(struct{ Name string }{"Morty"}, filename, OVERWRITE_FILE), ShouldBeNil)
+		bs, _ := ioutil.ReadFile(filename)
+		So(string(bs), ShouldEqual, "Name = Morty\n")
+		files, _ := ioutil.ReadDir(dir)
+		So(len(files), ShouldEqual, 1)
+	})
+
+	Convey("Leave the target untouched if encoding fails", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+
+		x := struct{ Name interface{} }{}
+		So(EncodeToFile(x, filename, OVERWRITE_FILE), ShouldNotBeNil)
+		bs, _ := ioutil.ReadFile(filename)
+		So(string(bs), ShouldEqual, "Name = Rick\n")
+		files, _ := ioutil.ReadDir(dir)
+		So(len(files), ShouldEqual, 1)
+	})
+
+	Convey("Set the file mode", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		So(EncodeToFile(struct{ Name string }{"Rick"}, filename), ShouldBeNil)
+		fi, _ := os.Stat(filename)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0644))
+
+		So(NewEncoder(struct{ Name string }{"Rick"}, OVERWRITE_FILE).SetFileMode(0600).ToFile(filename), ShouldBeNil)
+		fi, _ = os.Stat(filename)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+	})
+
+	Convey("An overwritten file keeps its permissions", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "secrets.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+		os.Chmod(filename, 0600)
+		So(EncodeToFile(struct{ Name string }{"Morty"}, filename, OVERWRITE_FILE), ShouldBeNil)
+		fi, _ := os.Stat(filename)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+	})
+
+	Convey("Force error: Missing directory", t, func() {
+		err := writeFileAtomic(filepath.Join(TEMP_DIR, "GOTEST_MISSING", "app.conf"), []byte("x"), 0644)
+		So(err, ShouldNotBeNil)
+		So(strings.Contains(err.Error(), "GOTEST_MISSING"), ShouldBeTrue)
+	})
+
+}
+
+func TestBackupFile(t *testing.T) {
+
+	type cfg struct{ Name string }
+
+	Convey("Copy the existing file to .bak before overwriting", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+
+		So(EncodeToFile(cfg{"Morty"}, filename, OVERWRITE_FILE|BACKUP_FILE), ShouldBeNil)
+		bs, _ := ioutil.ReadFile(filename + ".bak")
+		So(string(bs), ShouldEqual, "Name = Rick\n")
+		So(EncodeToFile(cfg{"Summer"}, filename, OVERWRITE_FILE|BACKUP_FILE), ShouldBeNil)
+		bs, _ = ioutil.ReadFile(filename + ".bak")
+		So(string(bs), ShouldEqual, "Name = Morty\n")
+	})
+
+	Convey("Rotate timestamped backups", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+
+		for _, name := range []string{"Morty", "Summer", "Beth"} {
+			time.Sleep(2 * time.Millisecond)
+			So(NewEncoder(cfg{name}, OVERWRITE_FILE).SetBackups(2).ToFile(filename), ShouldBeNil)
+		}
+		baks, _ := filepath.Glob(filename + ".*.bak")
+		So(len(baks), ShouldEqual, 2)
+		bs, _ := ioutil.ReadFile(baks[0])
+		So(string(bs), ShouldEqual, "Name = Morty\n")
+		bs, _ = ioutil.ReadFile(baks[1])
+		So(string(bs), ShouldEqual, "Name = Summer\n")
+	})
+
+	Convey("Files which are not backups are not rotated", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+		writeFile(filename+".mine.bak", []byte("Name = Jerry\n"))
+
+		for _, name := range []string{"Morty", "Summer"} {
+			time.Sleep(2 * time.Millisecond)
+			So(NewEncoder(cfg{name}, OVERWRITE_FILE).SetBackups(1).ToFile(filename), ShouldBeNil)
+		}
+		So(fileExists(filename+".mine.bak"), ShouldBeTrue)
+		baks, _ := filepath.Glob(filename + ".2*.bak")
+		So(len(baks), ShouldEqual, 1)
+		bs, _ := ioutil.ReadFile(baks[0])
+		So(string(bs), ShouldEqual, "Name = Morty\n")
+	})
+
+	Convey("No backup is made of a new file", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		So(EncodeToFile(cfg{"Rick"}, filename, BACKUP_FILE), ShouldBeNil)
+		So(fileExists(filename+".bak"), ShouldBeFalse)
+	})
+
+}
+
+func TestSecureFile(t *testing.T) {
+
+	type cfg struct{ Password string }
+
+	Convey("Write secret files with 0600 permissions", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Password = old\n"))
+		os.Chmod(filename, 0644)
+
+		err := NewEncoder(cfg{"new"}, OVERWRITE_FILE|BACKUP_FILE|SECURE_FILE|SECURE_DIR).SetFileMode(0644).ToFile(filename)
+		So(err, ShouldBeNil)
+		fi, _ := os.Stat(filename)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+		fi, _ = os.Stat(filename + ".bak")
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+	})
+
+	Convey("Force error: Symbolic links and open directories", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		target := filepath.Join(dir, "target.conf")
+		link := filepath.Join(dir, "app.conf")
+		writeFile(target, []byte("Password = old\n"))
+		So(os.Symlink(target, link), ShouldBeNil)
+
+		err := EncodeToFile(cfg{"new"}, link, OVERWRITE_FILE|SECURE_FILE)
+		So(err.Error(), ShouldEqual, "refusing to write through a symbolic link")
+		So(EncodeToFile(cfg{"new"}, link, OVERWRITE_FILE), ShouldBeNil)
+		fi, _ := os.Lstat(link)
+		So(fi.Mode()&os.ModeSymlink, ShouldNotEqual, 0)
+		bs, _ := ioutil.ReadFile(target)
+		So(string(bs), ShouldEqual, "Password = new\n")
+
+		os.Chmod(dir, 0755)
+		err = EncodeToFile(cfg{"new"}, filepath.Join(dir, "new.conf"), SECURE_DIR)
+		So(err.Error(), ShouldEqual, "directory is accessible to other users")
+	})
+
+}
+
+func TestExpandPath(t *testing.T) {
+
+	Convey("Expand home directories and environment variables", t, func() {
+		t.Setenv("HOME", "/home/rick")
+		t.Setenv("GOTEST_APP", "portal")
+		p, err := expandPath("~/.app/local.conf")
+		So(err, ShouldBeNil)
+		So(p, ShouldEqual, "/home/rick/.app/local.conf")
+		p, _ = expandPath("~")
+		So(p, ShouldEqual, "/home/rick")
+		p, _ = expandPath("$HOME/${GOTEST_APP}.conf")
+		So(p, ShouldEqual, "/home/rick/portal.conf")
+		p, _ = expandPath("/etc/app.conf")
+		So(p, ShouldEqual, "/etc/app.conf")
+		u, err := user.Current()
+		if err == nil {
+			p, err = expandPath("~" + u.Username + "/app.conf")
+			So(err, ShouldBeNil)
+			So(p, ShouldEqual, filepath.Join(u.HomeDir, "app.conf"))
+		}
+		_, err = expandPath("~gotest_no_such_user/app.conf")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Expand file names and include directives with EXPAND_PATHS", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		t.Setenv("HOME", dir)
+		writeFile(filepath.Join(dir, "local.conf"), []byte("Port = 81"))
+		writeFile(filepath.Join(dir, "app.conf"), []byte("Name = Rick\ninclude ~/local.conf"))
+
+		var x struct {
+			Name string
+			Port int
+		}
+		So(DecodeFile("~/app.conf", &x, EXPAND_PATHS), ShouldBeNil)
+		So(x.Port, ShouldEqual, 81)
+		smap, err := ParseFile("$HOME/app.conf", EXPAND_PATHS)
+		So(err, ShouldBeNil)
+		So(smap["Port"], ShouldEqual, "81")
+		So(DecodeFile("~/app.conf", &x), ShouldNotBeNil)
+	})
+
+	Convey("Expand variables set by SetVars in include directives", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		t.Setenv("GOTEST_DIR", dir)
+		t.Setenv("env", "dev")
+		writeFile(filepath.Join(dir, "prod.conf"), []byte("Port = 443"))
+		writeFile(filepath.Join(dir, "dev.conf"), []byte("Port = 8080"))
+		writeFile(filepath.Join(dir, "app.conf"), []byte("Name = Rick\ninclude ${GOTEST_DIR}/${env}.conf"))
+
+		var x struct {
+			Name string
+			Port int
+		}
+		So(DecodeFile(filepath.Join(dir, "app.conf"), &x, EXPAND_PATHS), ShouldBeNil)
+		So(x.Port, ShouldEqual, 8080)
+		d := NewDecoder(&x, EXPAND_PATHS).SetVars(map[string]string{"env": "prod"})
+		So(d.DecodeFile(filepath.Join(dir, "app.conf")), ShouldBeNil)
+		So(x.Port, ShouldEqual, 443)
+		x.Port = 0
+		So(d.DecodeFiles(filepath.Join(dir, "app.conf")), ShouldBeNil)
+		So(x.Port, ShouldEqual, 443)
+	})
+
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var fileModeType = reflect.TypeOf(os.FileMode(0))
+
+// Unix octal bits of the special file modes
+var specialModes = []struct {
+	bit  uint64
+	mode os.FileMode
+}{
+	{04000, os.ModeSetuid},
+	{02000, os.ModeSetgid},
+	{01000, os.ModeSticky},
+}
+
+// Parse an octal file mode, eg. 0644, 644, 0o755 or 4755. The setuid, setgid
+// and sticky bits are supported.
+func parseFileMode(s string) (interface{}, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0o"), "0O")
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 07777 {
+		return nil, errors.New("Invalid file mode")
+	}
+	mode := os.FileMode(n) & os.ModePerm
+	for _, m := range specialModes {
+		if n&m.bit != 0 {
+			mode |= m.mode
+		}
+	}
+	return mode, nil
+}
+
+// Format a file mode in octal, eg. 0644. Other than the setuid, setgid and
+// sticky bits, file type bits are not written.
+func formatFileMode(x interface{}) string {
+	mode := x.(os.FileMode)
+	n := uint64(mode & os.ModePerm)
+	for _, m := range specialModes {
+		if mode&m.mode != 0 {
+			n |= m.bit
+		}
+	}
+	return fmt.Sprintf("%04o", n)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFileMode(t *testing.T) {
+
+	type cfg struct {
+		Mode    os.FileMode
+		DirMode os.FileMode
+		Exec    *os.FileMode
+		Shared  os.FileMode
+	}
+
+	Convey("Decode file modes in octal", t, func() {
+		var x cfg
+		So(Decode(&x, "Mode = 0644\nDirMode = 755\nExec = 0o4755\nShared = 1777"), ShouldBeNil)
+		So(x.Mode, ShouldEqual, os.FileMode(0644))
+		So(x.DirMode, ShouldEqual, os.FileMode(0755))
+		So(*x.Exec, ShouldEqual, os.ModeSetuid|0755)
+		So(x.Shared, ShouldEqual, os.ModeSticky|0777)
+	})
+
+	Convey("Encode file modes in octal", t, func() {
+		exec := os.ModeSetgid | 0750
+		x := cfg{Mode: 0600, DirMode: os.ModeDir | 0700, Exec: &exec}
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "Mode = 0600\nDirMode = 0700\nExec = 2750\n")
+	})
+
+	Convey("Force error: Invalid file modes", t, func() {
+		for _, src := range []string{"Mode = 0648", "Mode = 17777", "Mode = rw-r--r--"} {
+			var x cfg
+			err := Decode(&x, src)
+			So(err.Error(), ShouldEqual, "Mode: Invalid file mode at line 1")
+		}
+	})
+
+}
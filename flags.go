@@ -0,0 +1,126 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BindFlags registers a flag for every scalar field of the supplied struct
+// pointer, including types decoded from a single value such as url.URL,
+// net.IP, *time.Location and *big.Int. Flag names are the dotted field keys in snake case, eg.
+// Server.MaxConns == -server.max_conns, and the current field values are used
+// as flag defaults. Setting a flag assigns the field directly, using the same
+// conversions as the decoder.
+//
+// The flag usage text is taken from the field's usage tag, or its comment tag
+// if it has no usage tag. A zero valued field with a default tag is set to the
+// default value. An enum tag holds a comma separated list of accepted values,
+// which is added to the usage text and enforced when the flag is set.
+//
+//	type Config struct {
+//		Level string `usage:"Log level" default:"info" enum:"debug,info,warn"`
+//	}
+//
+// To let flags override values decoded from a file, decode the file first
+// and parse the flags afterward, or use a Loader with AddFlags.
+func BindFlags(fs *flag.FlagSet, x interface{}) {
+	if !isStructPtr(x) {
+		panic("Expecting pointer to a struct")
+	}
+	bindFlags(fs, reflect.ValueOf(x).Elem(), "")
+}
+
+func bindFlags(fs *flag.FlagSet, v1 reflect.Value, parent_key string) {
+	t := v1.Type()
+	for i := 0; i < v1.NumField(); i++ {
+		f := t.Field(i)
+		key := fieldKey(f)
+		if key == "" {
+			continue
+		}
+		if parent_key != "" {
+			key = parent_key + "." + key
+		}
+		fv := v1.Field(i)
+		switch kind := fv.Kind(); {
+		case isSingleFlag(fv.Type()):
+		case kind == reflect.Struct:
+			if !isTimeType(fv.Type()) {
+				bindFlags(fs, fv, key)
+				continue
+			}
+		case kind == reflect.Map || kind == reflect.Slice || kind == reflect.Array ||
+			kind == reflect.Interface || kind == reflect.Ptr || kind == reflect.Func || kind == reflect.Chan:
+			continue
+		}
+		usage := f.Tag.Get("usage")
+		if usage == "" {
+			usage = f.Tag.Get("comment")
+		}
+		var enum []string
+		if e := f.Tag.Get("enum"); e != "" {
+			for _, s := range strings.Split(e, ",") {
+				enum = append(enum, trim(s))
+			}
+			usage += " (one of: " + strings.Join(enum, ", ") + ")"
+		}
+		if def, ok := f.Tag.Lookup("default"); ok && isZeroStruct(fv) {
+			if err := setScalar(fv, def); err != nil {
+				panic("Invalid default tag for " + key + ": " + err.Error())
+			}
+		}
+		fs.Var(&fieldFlag{fv, enum}, flagName(key), trim(usage))
+	}
+}
+
+// Return true if a field of type t is set by a single flag, although it is a
+// struct, slice or pointer, eg. url.URL, net.IP, *time.Location or *big.Int.
+// These are the types which structKeys treats as a single key.
+func isSingleFlag(t reflect.Type) bool {
+	return isBigType(t) || isTextType(t) || t == locationType
+}
+
+// fieldFlag is a flag.Value which sets a struct field.
+type fieldFlag struct {
+	v    reflect.Value
+	enum []string
+}
+
+func (f *fieldFlag) String() string {
+	if !f.v.IsValid() {
+		return ""
+	}
+	switch t := f.v.Type(); {
+	case isBigType(t):
+		return formatBig(f.v)
+	case isTextType(t):
+		return formatText(f.v)
+	case t == locationType && f.v.IsNil():
+		return ""
+	}
+	return fmt.Sprint(f.v.Interface())
+}
+
+func (f *fieldFlag) Set(s string) error {
+	if len(f.enum) > 0 {
+		ok := false
+		for _, e := range f.enum {
+			ok = ok || e == s
+		}
+		if !ok {
+			return errors.New("must be one of: " + strings.Join(f.enum, ", "))
+		}
+	}
+	return setScalar(f.v, s)
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.v.IsValid() && f.v.Kind() == reflect.Bool
+}
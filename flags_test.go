@@ -0,0 +1,130 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"flag"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBindFlags(t *testing.T) {
+
+	type cfgX struct {
+		Name   string
+		Debug  bool
+		Server struct {
+			Host     string
+			MaxConns int
+		}
+		Tags map[string]string
+		priv int
+	}
+
+	Convey("Register a flag for every field", t, func() {
+		var x cfgX
+		x.Server.MaxConns = 10
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		BindFlags(fs, &x)
+		So(fs.Lookup("name"), ShouldNotBeNil)
+		So(fs.Lookup("debug"), ShouldNotBeNil)
+		So(fs.Lookup("server.host"), ShouldNotBeNil)
+		So(fs.Lookup("server.max_conns").DefValue, ShouldEqual, "10")
+		So(fs.Lookup("tags"), ShouldBeNil)
+		So(fs.Lookup("priv"), ShouldBeNil)
+
+		err := fs.Parse([]string{"-debug", "-name", "Rick", "-server.max_conns=2K"})
+		So(err, ShouldBeNil)
+		So(x.Debug, ShouldBeTrue)
+		So(x.Name, ShouldEqual, "Rick")
+		So(x.Server.MaxConns, ShouldEqual, 2000)
+	})
+
+	Convey("Flags override decoded file values", t, func() {
+		var x cfgX
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		BindFlags(fs, &x)
+		So(Decode(&x, "Name = Rick\nServer.Host = localhost"), ShouldBeNil)
+		So(fs.Parse([]string{"-server.host=citadel"}), ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+		So(x.Server.Host, ShouldEqual, "citadel")
+	})
+
+	Convey("Types decoded from a single value have a single flag", t, func() {
+		type cfgY struct {
+			Endpoint url.URL
+			Addr     net.IP
+			Zone     *time.Location
+			Total    *big.Int
+		}
+		var x cfgY
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		BindFlags(fs, &x)
+		So(fs.Lookup("endpoint.host"), ShouldBeNil)
+		So(fs.Lookup("endpoint").DefValue, ShouldEqual, "")
+		So(fs.Lookup("zone").DefValue, ShouldEqual, "")
+		err := fs.Parse([]string{"-endpoint=https://example.com/api", "-addr=10.0.0.1",
+			"-zone=UTC", "-total=123456789012345678901234567890"})
+		So(err, ShouldBeNil)
+		So(x.Endpoint.Host, ShouldEqual, "example.com")
+		So(x.Addr.String(), ShouldEqual, "10.0.0.1")
+		So(x.Zone, ShouldEqual, time.UTC)
+		So(x.Total.String(), ShouldEqual, "123456789012345678901234567890")
+
+		var y cfgY
+		So(NewLoader().AddFlags(fs).Load(&y), ShouldBeNil)
+		So(y.Endpoint, ShouldResemble, x.Endpoint)
+		So(y.Addr.String(), ShouldEqual, "10.0.0.1")
+		So(y.Zone, ShouldEqual, time.UTC)
+		So(y.Total.String(), ShouldEqual, x.Total.String())
+	})
+
+	Convey("Force error: Invalid flag value", t, func() {
+		var x cfgX
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		BindFlags(fs, &x)
+		fs.SetOutput(nullWriter{})
+		err := fs.Parse([]string{"-server.max_conns=many"})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Usage text, defaults and enum values from tags", t, func() {
+		var x struct {
+			Level string `usage:"Log level" default:"info" enum:"debug, info, warn"`
+			Port  int    `comment:"TCP port" default:"8080"`
+			Host  string `default:"localhost"`
+		}
+		x.Host = "citadel"
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(nullWriter{})
+		BindFlags(fs, &x)
+		So(fs.Lookup("level").Usage, ShouldEqual, "Log level (one of: debug, info, warn)")
+		So(fs.Lookup("level").DefValue, ShouldEqual, "info")
+		So(fs.Lookup("port").Usage, ShouldEqual, "TCP port")
+		So(x.Port, ShouldEqual, 8080)
+		So(x.Host, ShouldEqual, "citadel")
+
+		So(fs.Parse([]string{"-level=debug"}), ShouldBeNil)
+		So(x.Level, ShouldEqual, "debug")
+		So(fs.Parse([]string{"-level=loud"}), ShouldNotBeNil)
+	})
+
+	Convey("Force panic: Not a struct pointer", t, func() {
+		var x cfgX
+		fn := func() {
+			BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), x)
+		}
+		So(fn, ShouldPanic)
+	})
+
+}
+
+type nullWriter struct{}
+
+func (nullWriter) Write(b []byte) (int, error) { return len(b), nil }
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Format returns the supplied configuration source in canonical form: one
+// "key = value" assignment per line, two space indentation inside braces, and
+// no more than one consecutive blank line. Comments, multi-line strings and
+// heredoc content are retained. The source is parsed first and any parse
+// errors are returned.
+func Format(src []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(src)) == 0 {
+		return []byte{}, nil
+	}
+	if _, err := NewParser().Parse(src); err != nil {
+		return nil, err
+	}
+	f := &formatter{lines: strings.Split(string(src), lf)}
+	f.format()
+	return f.buf.Bytes(), nil
+}
+
+type formatter struct {
+	buf   bytes.Buffer
+	lines []string
+	n     int
+	depth int
+	blank bool
+}
+
+func (f *formatter) format() {
+	m := matches{make([]string, 0, 0)}
+	for ; f.n < len(f.lines); f.n++ {
+		s, cmt := splitComment(f.lines[f.n])
+		if s == "" && cmt == "" {
+			f.blank = f.buf.Len() > 0
+			continue
+		}
+		switch {
+		case s == "":
+			f.writeLine("", cmt)
+		case findSubmatch(include, s, &m):
+			f.writeLine("include "+trim(s[len("include"):]), cmt)
+		case findSubmatch(profile, s, &m), findSubmatch(condition, s, &m), findSubmatch(anchor, s, &m):
+			f.writeLine(s, cmt)
+			f.depth++
+		case findSubmatch(quoted_key, s, &m):
+			f.writeLine(m.a[1]+" = "+m.a[2], cmt)
+			if m.a[2] == "{" {
+				f.depth++
+			}
+		case findSubmatch(alias, s, &m):
+			f.writeLine(m.a[1]+" = *"+m.a[2]+strings.Replace(m.a[3], "{", " {", 1), cmt)
+			if m.a[3] != "" {
+				f.depth++
+			}
+		case findSubmatch(inline_block, s, &m) && findSubmatch(keyval, s, &m):
+			// an inline block, or a value which is enclosed in braces
+			f.writeLine(m.a[1]+" = "+m.a[2], cmt)
+		case findSubmatch(open_brace, s, &m):
+			f.writeLine(m.a[1]+" = {", cmt)
+			f.depth++
+		case findSubmatch(close_brace, s, &m):
+			if f.depth > 0 {
+				f.depth--
+			}
+			f.writeLine("}", cmt)
+		case findSubmatch(heredoc, s, &m):
+			f.writeLine(m.a[1]+" = <<"+m.a[2], cmt)
+			f.copyHereDoc(m.a[2])
+		case findSubmatch(multiline, s, &m):
+			f.writeLine(m.a[1]+" = "+m.a[2]+`\`, cmt)
+			f.copyMultiLine(len(m.a[1]) + 3)
+		case findSubmatch(keyval, s, &m):
+			f.writeLine(m.a[1]+" = "+m.a[2], cmt)
+		default:
+			f.writeLine(s, cmt)
+		}
+	}
+}
+
+// Copy heredoc content verbatim up to and including the terminating code.
+func (f *formatter) copyHereDoc(code string) {
+	for f.n++; f.n < len(f.lines); f.n++ {
+		s := rtrim(f.lines[f.n])
+		if trim(s) == code {
+			f.buf.WriteString(f.indent() + code + lf)
+			return
+		}
+		f.buf.WriteString(s + lf)
+	}
+}
+
+// Copy the continuation lines of a multi-line value, aligned with the first
+// character of the value.
+func (f *formatter) copyMultiLine(width int) {
+	m := matches{make([]string, 0, 0)}
+	pad := strings.Repeat(" ", width)
+	for f.n++; f.n < len(f.lines); f.n++ {
+		s, cmt := splitComment(f.lines[f.n])
+		if s == "" {
+			if cmt != "" {
+				f.writeLine("", cmt)
+			}
+			continue
+		}
+		f.writeLine(pad+s, cmt)
+		if !findSubmatch(multiline_cont, s, &m) {
+			return
+		}
+	}
+}
+
+func (f *formatter) writeLine(s, cmt string) {
+	if f.blank {
+		f.buf.WriteString(lf)
+		f.blank = false
+	}
+	if cmt != "" {
+		if s != "" {
+			s += " "
+		}
+		s += "#" + cmt
+	}
+	f.buf.WriteString(f.indent() + s + lf)
+}
+
+func (f *formatter) indent() string {
+	return strings.Repeat("  ", f.depth)
+}
+
+// Split a line into its trimmed content and its trailing comment text. A #
+// within a quoted string does not begin a comment.
+func splitComment(s string) (string, string) {
+	var cmt string
+	if i := commentIndex([]byte(s)); i >= 0 {
+		cmt = rtrim(s[i+1:])
+		s = s[:i]
+	}
+	return trim(s), cmt
+}
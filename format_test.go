@@ -0,0 +1,163 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io/ioutil"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFormat(t *testing.T) {
+
+	Convey("Format assignments, braces and comments", t, func() {
+		cfg := `
+		# Heading comment
+		Key1:    String1      # trailing comment
+		Key2     String2
+
+
+		Map1 {
+		Key3 = 3
+		      Map2:{
+		Key4 = 4
+		}
+		}
+		`
+		expected := `# Heading comment
+Key1 = String1 # trailing comment
+Key2 = String2
+
+Map1 = {
+  Key3 = 3
+  Map2 = {
+    Key4 = 4
+  }
+}
+`
+		b, err := Format([]byte(cfg))
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, expected)
+	})
+
+	Convey("Format multi-line strings and heredocs", t, func() {
+		cfg := `MultiLine:  Fun facts \
+			about this one
+	Content = <<_END
+  <article>
+    Text
+  </article>
+		_END
+`
+		expected := `MultiLine = Fun facts \
+            about this one
+Content = <<_END
+  <article>
+    Text
+  </article>
+_END
+`
+		b, err := Format([]byte(cfg))
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, expected)
+	})
+
+	Convey("Formatted example file decodes to the same values", t, func() {
+		src, err := ioutil.ReadFile(example_conf_file)
+		So(err, ShouldBeNil)
+		b, err := Format(src)
+		So(err, ShouldBeNil)
+		var x, y testConfigX
+		So(Decode(&x, src), ShouldBeNil)
+		So(Decode(&y, b), ShouldBeNil)
+		So(x, ShouldResemble, y)
+
+		Convey("Formatting is idempotent", func() {
+			b2, err := Format(b)
+			So(err, ShouldBeNil)
+			So(string(b2), ShouldEqual, string(b))
+		})
+	})
+
+	Convey("Format inline blocks and values enclosed in braces", t, func() {
+		cfg := `L = { Min = 1, Max = 2 }
+Server { Host = example.com; Tls = { Port = 443 } }
+Fmt = {level}
+`
+		expected := `L = { Min = 1, Max = 2 }
+Server = { Host = example.com; Tls = { Port = 443 } }
+Fmt = {level}
+`
+		b, err := Format([]byte(cfg))
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, expected)
+		m1, err := Parse(cfg)
+		So(err, ShouldBeNil)
+		m2, err := Parse(b)
+		So(err, ShouldBeNil)
+		So(m2, ShouldResemble, m1)
+		So(m2["L.Max"], ShouldEqual, "2")
+	})
+
+	Convey("Format the bodies of anchors, profiles, conditions and quoted keys", t, func() {
+		cfg := `&base {
+Port = 80
+}
+Web = *base {
+Host = a
+}
+@profile prod {
+Debug = false
+}
+@if env == "prod" {
+Level = warn
+}
+"my block" = {
+A = 1
+}
+`
+		expected := `&base {
+  Port = 80
+}
+Web = *base {
+  Host = a
+}
+@profile prod {
+  Debug = false
+}
+@if env == "prod" {
+  Level = warn
+}
+"my block" = {
+  A = 1
+}
+`
+		b, err := Format([]byte(cfg))
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, expected)
+	})
+
+	Convey("A # within a quoted value is not a comment", t, func() {
+		cfg := "K = \"a#b\"   # comment\nL = \"c # d\"\n"
+		b, err := Format([]byte(cfg))
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "K = \"a#b\" # comment\nL = \"c # d\"\n")
+		m, err := Parse(b)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"K": "a#b", "L": "c # d"})
+	})
+
+	Convey("Force error: Invalid source", t, func() {
+		_, err := Format([]byte("Key1={Key=2"))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Empty source", t, func() {
+		b, err := Format([]byte("  \n"))
+		So(err, ShouldBeNil)
+		So(len(b), ShouldEqual, 0)
+	})
+
+}
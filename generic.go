@@ -0,0 +1,82 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"reflect"
+	"sort"
+)
+
+// DecodeAs decodes a string, byte slice or io.Reader into a new value of type
+// T, which must be a struct or a string-keyed map. Decoder options are
+// optional.
+//
+//	cfg, err := config.DecodeAs[Config](src)
+func DecodeAs[T any](src interface{}, options ...int) (T, error) {
+	var x T
+	err := Decode(decodeTarget(&x), src, options...)
+	return x, err
+}
+
+// DecodeFileAs decodes the supplied file into a new value of type T, which
+// must be a struct or a string-keyed map. Decoder options are optional.
+func DecodeFileAs[T any](filename string, options ...int) (T, error) {
+	var x T
+	err := DecodeFile(filename, decodeTarget(&x), options...)
+	return x, err
+}
+
+// ParseMap parses a string, byte slice or io.Reader and converts every value to
+// type V using the same rules as the decoder, eg. ParseMap[int] accepts 10K.
+// Unlike decoding into a map, a value which cannot be converted is reported
+// as an error. Blocks produce dotted keys. Parser options are optional, and
+// may include STRICT_NUMBERS.
+func ParseMap[V any](src interface{}, options ...int) (map[string]V, error) {
+	p := NewParser(options...)
+	p.reader = bufio.NewReader(toReader(src))
+	vmap, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(vmap))
+	for k := range vmap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	m := make(map[string]V, len(vmap))
+	var errs []error
+	for _, k := range keys {
+		var val V
+		v1 := reflect.ValueOf(&val).Elem()
+		err := setScalar(v1, vmap[k].val)
+		if isOption(STRICT_NUMBERS, p.options) {
+			if e := checkStrictNumber(v1, vmap[k].val); e != nil {
+				err = e
+			}
+		}
+		if err != nil {
+			errs = append(errs, &ParseError{File: vmap[k].src, Line: vmap[k].no, Key: k, Msg: err.Error(), Err: err})
+			continue
+		}
+		if isOption(PARSE_LOWER_CASE, p.options) {
+			m[toLower(k)] = val
+		} else {
+			m[k] = val
+		}
+	}
+	return m, getErrors(errs)
+}
+
+// Return the value to be passed to NewDecoder for a pointer to a new value.
+// Maps are allocated and passed by value.
+func decodeTarget[T any](x *T) interface{} {
+	v1 := reflect.ValueOf(x).Elem()
+	if v1.Kind() == reflect.Map {
+		v1.Set(reflect.MakeMap(v1.Type()))
+		return v1.Interface()
+	}
+	return x
+}
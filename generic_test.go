@@ -0,0 +1,78 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDecodeAs(t *testing.T) {
+
+	type cfg struct {
+		Name string
+		Port int
+	}
+
+	Convey("Decode into a new struct", t, func() {
+		x, err := DecodeAs[cfg]("name = Rick\nport = 80", IGNORE_CASE)
+		So(err, ShouldBeNil)
+		So(x, ShouldResemble, cfg{"Rick", 80})
+	})
+
+	Convey("Decode into a new map", t, func() {
+		m, err := DecodeAs[map[string]int]([]byte("A = 1\nB = 2K"))
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]int{"A": 1, "B": 2000})
+	})
+
+	Convey("Decode a file into a new struct", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = Morty"))
+		x, err := DecodeFileAs[cfg](tempfile)
+		So(err, ShouldBeNil)
+		So(x.Name, ShouldEqual, "Morty")
+	})
+
+	Convey("Force error: Extra fields", t, func() {
+		_, err := DecodeAs[cfg]("Color = green")
+		So(err, ShouldNotBeNil)
+	})
+
+}
+
+func TestParseMap(t *testing.T) {
+
+	Convey("Parse to a map of integers", t, func() {
+		m, err := ParseMap[int]("A = 1\nB = 2K\nC {\n D = 1,000\n}")
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]int{"A": 1, "B": 2000, "C.D": 1000})
+	})
+
+	Convey("Parse to a map of durations with lower case keys", t, func() {
+		m, err := ParseMap[time.Duration]("Timeout = 1m", PARSE_LOWER_CASE)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]time.Duration{"timeout": time.Minute})
+	})
+
+	Convey("Force error: Abbreviations with STRICT_NUMBERS", t, func() {
+		m, err := ParseMap[int]("A = 1\nB = 2K\nC = 1,000", STRICT_NUMBERS)
+		So(err.Error(), ShouldEqual, "B: Invalid numeric literal at line 2\nC: Invalid numeric literal at line 3")
+		So(m, ShouldResemble, map[string]int{"A": 1})
+	})
+
+	Convey("Force error: Values which cannot be converted", t, func() {
+		m, err := ParseMap[int8]("A = 1\nB = 200\nC = green")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "B: Overflow at line 2\nC: strconv.Atoi: parsing \"green\": invalid syntax at line 3")
+		So(m, ShouldResemble, map[string]int8{"A": 1})
+		_, err = ParseMap[int]("A = {")
+		So(err, ShouldNotBeNil)
+	})
+
+}
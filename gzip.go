@@ -0,0 +1,30 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+)
+
+// The first two bytes of a gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Return a reader which decompresses the supplied reader if it begins with
+// the gzip magic bytes. Otherwise the reader is returned unchanged. Parser
+// limits are applied to the decompressed data.
+func gunzip(r *bufio.Reader) (*bufio.Reader, error) {
+	b, _ := r.Peek(len(gzipMagic))
+	if !bytes.Equal(b, gzipMagic) {
+		return r, nil
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot decompress input: %w", err)
+	}
+	return bufio.NewReader(zr), nil
+}
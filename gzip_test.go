@@ -0,0 +1,68 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func gzipBytes(bs []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(bs)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestGzip(t *testing.T) {
+
+	type cfg struct {
+		Name string
+		Port int
+	}
+	src := gzipBytes([]byte("Name = alpha\nPort = 8080\n"))
+
+	Convey("Decode gzip compressed bytes", t, func() {
+		var x cfg
+		So(Decode(&x, src), ShouldBeNil)
+		So(x, ShouldResemble, cfg{"alpha", 8080})
+	})
+
+	Convey("Decode and parse a gzip compressed file", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf.gz")
+		writeFile(filename, src)
+		var x cfg
+		So(DecodeFile(filename, &x), ShouldBeNil)
+		So(x, ShouldResemble, cfg{"alpha", 8080})
+		m, err := ParseFile(filename)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"Name": "alpha", "Port": "8080"})
+	})
+
+	Convey("Limits apply to the decompressed data", t, func() {
+		var x cfg
+		err := NewDecoder(&x).SetLimits(Limits{MaxBytes: 10}).DecodeBytes(src)
+		So(err.Error(), ShouldEqual, "Input exceeds limit of 10 bytes")
+	})
+
+	Convey("Force error: Corrupt gzip data", t, func() {
+		var x cfg
+		err := Decode(&x, []byte{0x1f, 0x8b, 0x00})
+		So(err.Error(), ShouldStartWith, "Cannot decompress input: ")
+		bs := append([]byte{}, src...)
+		bs[len(bs)-1] ^= 0xff
+		err = Decode(&x, bs)
+		So(err, ShouldNotBeNil)
+	})
+
+}
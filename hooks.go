@@ -0,0 +1,149 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A DecodeHook converts the raw string value of a key to the target type.
+// It returns false if it does not handle the target type, in which case the
+// next hook, and finally the built-in conversion, is tried. The returned value
+// must be assignable or convertible to the target type.
+type DecodeHook func(target reflect.Type, raw string) (interface{}, bool, error)
+
+// An EncodeHook returns the textual form of a value. It returns false if it
+// does not handle the value, in which case the next hook, and finally the
+// built-in encoding, is tried. Text containing line feeds is written as a
+// heredoc, unless heredocs are disabled. Empty text is only written with the
+// ENCODE_ZERO_VALUES option.
+type EncodeHook func(v reflect.Value) (string, bool, error)
+
+// WithDecodeHook adds a hook to be consulted before the built-in conversion of
+// each value. Hooks are tried in the order they were added. A hook may claim
+// any type, including structs, slices and pointers, which would otherwise be
+// traversed or rejected.
+func (o *Decoder) WithDecodeHook(fn DecodeHook) *Decoder {
+	o.hooks = append(o.hooks, fn)
+	return o
+}
+
+// Assign a string value using the decode hooks or the built-in conversion.
+func (o *Decoder) setValue(v1 reflect.Value, val string) error {
+	if claimed, err := o.runHooks(v1, val); claimed {
+		return err
+	}
+	if err := o.checkScheme(v1, val); err != nil {
+		return err
+	}
+	if isTimeType(v1.Type()) {
+		return o.setTime(v1, val)
+	}
+	val, err := evalExpr(v1, val)
+	if err != nil {
+		return err
+	}
+	if isOption(STRICT_NUMBERS, o.options) {
+		if err := checkStrictNumber(v1, val); err != nil {
+			return err
+		}
+	} else if isOption(ALLOW_INT_PERCENT, o.options) {
+		val = intPercent(v1, val)
+	}
+	return setScalar(v1, val)
+}
+
+// Offer the value of a struct, slice, array, map or pointer key to the decode
+// hooks. Returns true if a hook claimed it.
+func (o *Decoder) hookValue(v1 reflect.Value, key string) (bool, error) {
+	if len(o.hooks) == 0 || key == "" || !v1.IsValid() || !v1.CanSet() {
+		return false, nil
+	}
+	switch v1.Kind() {
+	case reflect.Struct:
+		if isTimeType(v1.Type()) {
+			return false, nil
+		}
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr:
+	default:
+		return false, nil
+	}
+	vs := o.lookup(key)
+	if vs == nil {
+		return false, nil
+	}
+	claimed, err := o.runHooks(v1, vs.val)
+	if !claimed {
+		return false, nil
+	}
+	vs.isDefined = true
+	if err != nil {
+		return true, o.failValue(key, err, vs.no)
+	}
+	o.markSet(key)
+	return true, nil
+}
+
+func (o *Decoder) runHooks(v1 reflect.Value, val string) (bool, error) {
+	t := v1.Type()
+	for _, fn := range o.hooks {
+		x, ok, err := fn(t, val)
+		if err != nil {
+			return true, err
+		}
+		if !ok {
+			continue
+		}
+		o.debug("config: decode hook", "type", t.String())
+		rv := reflect.ValueOf(x)
+		switch {
+		case !rv.IsValid():
+			rv = reflect.Zero(t)
+		case rv.Type().AssignableTo(t):
+		case rv.Type().ConvertibleTo(t):
+			rv = rv.Convert(t)
+		default:
+			return true, fmt.Errorf("Decode hook returned %v for %v", rv.Type(), t)
+		}
+		v1.Set(rv)
+		return true, nil
+	}
+	return false, nil
+}
+
+// WithEncodeHook adds a hook to be consulted before the built-in encoding of
+// each value. Hooks are tried in the order they were added. A hook may claim
+// any type, including structs, slices and pointers, which would otherwise be
+// traversed or rejected.
+func (o *Encoder) WithEncodeHook(fn EncodeHook) *Encoder {
+	o.hooks = append(o.hooks, fn)
+	return o
+}
+
+// Offer a value to the encode hooks. Returns true if a hook claimed it, along
+// with the result of writing it.
+func (o *Encoder) encodeHook(v1 reflect.Value, depth int, parent_key string) (bool, bool) {
+	if len(o.hooks) == 0 || parent_key == "" {
+		return false, false
+	}
+	for _, fn := range o.hooks {
+		s, ok, err := fn(v1)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%w (%s)", err, parent_key))
+			return true, false
+		}
+		if !ok {
+			continue
+		}
+		if strings.Contains(s, lf) && !o.noHeredocs() {
+			o.write_kv(depth, parent_key, output_heredoc(s))
+			return true, true
+		}
+		return true, o.encodeString(reflect.ValueOf(s), depth, parent_key)
+	}
+	return false, false
+}
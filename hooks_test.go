@@ -0,0 +1,124 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testLevel int
+
+func TestDecodeHooks(t *testing.T) {
+
+	levels := func(target reflect.Type, raw string) (interface{}, bool, error) {
+		if target != reflect.TypeOf(testLevel(0)) {
+			return nil, false, nil
+		}
+		switch toLower(raw) {
+		case "low":
+			return 1, true, nil
+		case "high":
+			return 2, true, nil
+		}
+		return nil, true, errors.New("Invalid level")
+	}
+	networks := func(target reflect.Type, raw string) (interface{}, bool, error) {
+		if target != reflect.TypeOf(&net.IPNet{}) {
+			return nil, false, nil
+		}
+		_, n, err := net.ParseCIDR(raw)
+		return n, true, err
+	}
+	lists := func(target reflect.Type, raw string) (interface{}, bool, error) {
+		if target.Kind() != reflect.Slice || target.Elem().Kind() != reflect.String {
+			return nil, false, nil
+		}
+		return strings.Split(raw, ","), true, nil
+	}
+
+	type cfg struct {
+		Name    string
+		Level   testLevel
+		Network *net.IPNet
+		Tags    []string
+	}
+
+	Convey("Convert values with decode hooks", t, func() {
+		var x cfg
+		err := NewDecoder(&x).
+			WithDecodeHook(levels).
+			WithDecodeHook(networks).
+			WithDecodeHook(lists).
+			DecodeString("Name = Rick\nLevel = high\nNetwork = 10.0.0.0/8\nTags = a,b")
+		So(err, ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+		So(x.Level, ShouldEqual, 2)
+		So(x.Network.String(), ShouldEqual, "10.0.0.0/8")
+		So(x.Tags, ShouldResemble, []string{"a", "b"})
+	})
+
+	Convey("Force error: Hook errors and mismatched types", t, func() {
+		var x struct{ Level testLevel }
+		err := NewDecoder(&x).WithDecodeHook(levels).DecodeString("Level = medium")
+		So(err.Error(), ShouldEqual, "Level: Invalid level at line 1")
+
+		var y struct{ Level testLevel }
+		err = NewDecoder(&y).WithDecodeHook(func(reflect.Type, string) (interface{}, bool, error) {
+			return "high", true, nil
+		}).DecodeString("Level = high")
+		So(err.Error(), ShouldEqual, "Level: Decode hook returned string for config.testLevel at line 1")
+	})
+
+}
+
+func TestEncodeHooks(t *testing.T) {
+
+	networks := func(v reflect.Value) (string, bool, error) {
+		n, ok := v.Interface().(*net.IPNet)
+		if !ok {
+			return "", false, nil
+		}
+		return n.String(), true, nil
+	}
+	lists := func(v reflect.Value) (string, bool, error) {
+		if v.Kind() != reflect.Slice {
+			return "", false, nil
+		}
+		return strings.Join(v.Interface().([]string), "\n"), true, nil
+	}
+
+	Convey("Render values with encode hooks", t, func() {
+		_, n, _ := net.ParseCIDR("10.0.0.0/8")
+		x := struct {
+			Name    string
+			Network *net.IPNet
+			Tags    []string
+		}{"Rick", n, []string{"a", "b"}}
+		var bs []byte
+		err := NewEncoder(x).WithEncodeHook(networks).WithEncodeHook(lists).ToBytes(&bs)
+		So(err, ShouldBeNil)
+		So(string(bs), ShouldStartWith, "Name = Rick\nNetwork = 10.0.0.0/8\nTags = <<")
+		So(string(bs), ShouldContainSubstring, "\na\nb\n")
+
+		var y struct{ Tags string }
+		So(Decode(&y, bs[strings.Index(string(bs), "Tags"):]), ShouldBeNil)
+		So(y.Tags, ShouldEqual, "a\nb")
+	})
+
+	Convey("Force error: Hook errors", t, func() {
+		x := struct{ Tags []string }{[]string{"a"}}
+		var bs []byte
+		err := NewEncoder(x).WithEncodeHook(func(reflect.Value) (string, bool, error) {
+			return "", true, errors.New("Cannot render")
+		}).ToBytes(&bs)
+		So(err.Error(), ShouldEqual, "Cannot render (Tags)")
+	})
+
+}
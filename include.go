@@ -0,0 +1,65 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io"
+)
+
+// An IncludeResolver opens the file named by an include directive, where from
+// is the name of the including file and path is the name as written, after
+// the expansion of the EXPAND_PATHS option. It may read from any store, such
+// as a zip archive or a database.
+type IncludeResolver func(from, path string) (io.ReadCloser, error)
+
+// SetIncludeResolver sets a function to open included files, in place of
+// reading them from the file system. The file passed to DecodeFile is still
+// read from the file system. Included files are neither decrypted nor
+// locked. Their signatures are verified as usual, but a signature must be in
+// a footer, as a sidecar file is read from the file system. See WithVerifier.
+//
+//	zr, _ := zip.OpenReader("bundle.zip")
+//	d.SetIncludeResolver(func(from, path string) (io.ReadCloser, error) {
+//		return zr.Open(path)
+//	})
+func (o *Decoder) SetIncludeResolver(fn func(from, path string) (io.ReadCloser, error)) *Decoder {
+	o.resolver = fn
+	return o
+}
+
+// Report whether a file included from the named file is opened by the
+// include resolver.
+func (o *Decoder) resolved(from string) bool {
+	return from != "" && o.resolver != nil
+}
+
+// Return a key of an included file under the prefix given by its include
+// directive, eg. include backend.conf as Database
+func includeKey(prefix, key string) string {
+	switch {
+	case prefix == "":
+		return key
+	case key == "":
+		return prefix
+	}
+	return prefix + "." + key
+}
+
+// Return a field map with every key placed under the supplied prefix.
+func prefixKeys(prefix string, m fMap) fMap {
+	pm := make(fMap, len(m))
+	for k, v := range m {
+		pm[includeKey(prefix, k)] = v
+	}
+	return pm
+}
+
+// Open a file, or a file included from the named file.
+func (o *Decoder) openFile(from, filename string, c Cipher) (io.ReadCloser, error) {
+	if o.resolved(from) {
+		return o.resolver(from, filename)
+	}
+	return openFile(filename, c)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncludeResolver(t *testing.T) {
+
+	type T struct {
+		Name   string
+		Server struct {
+			Host string
+			Port int
+		}
+	}
+
+	bundle := map[string]string{
+		"server.conf": "include port.conf\nServer.Host = citadel",
+		"port.conf":   "Server.Port = 8080",
+	}
+	var calls []string
+	resolver := func(from, path string) (io.ReadCloser, error) {
+		calls = append(calls, from+" > "+path)
+		s, ok := bundle[path]
+		if !ok {
+			return nil, errors.New("Not in bundle (" + path + ")")
+		}
+		return ioutil.NopCloser(strings.NewReader(s)), nil
+	}
+
+	tempfile := createTempFile("GOTEST_CONFIG")
+	defer os.Remove(tempfile)
+	writeFile(tempfile, []byte("Name = Rick\ninclude server.conf"))
+
+	Convey("Included files are opened by the resolver", t, func() {
+		calls = nil
+		var x T
+		So(NewDecoder(&x).SetIncludeResolver(resolver).DecodeFile(tempfile), ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+		So(x.Server.Host, ShouldEqual, "citadel")
+		So(x.Server.Port, ShouldEqual, 8080)
+		So(calls, ShouldResemble, []string{tempfile + " > server.conf", "server.conf > port.conf"})
+	})
+
+	Convey("The resolver is used by DecodeFiles", t, func() {
+		calls = nil
+		var x T
+		So(NewDecoder(&x).SetIncludeResolver(resolver).DecodeFiles(tempfile), ShouldBeNil)
+		So(x.Server.Port, ShouldEqual, 8080)
+		So(len(calls), ShouldEqual, 2)
+	})
+
+	Convey("Force error: Resolver cannot open a file", t, func() {
+		delete(bundle, "port.conf")
+		defer func() { bundle["port.conf"] = "Server.Port = 8080" }()
+		var x T
+		err := NewDecoder(&x).SetIncludeResolver(resolver).DecodeFile(tempfile)
+		So(err.Error(), ShouldContainSubstring, "Not in bundle (port.conf)")
+	})
+
+}
+
+func TestIncludeAs(t *testing.T) {
+
+	type DB struct {
+		Host string
+		Pool struct {
+			Max int
+		}
+	}
+	type T struct {
+		Name     string
+		Database DB
+		Cache    struct {
+			Backend DB
+		}
+	}
+
+	dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+	defer os.RemoveAll(dir)
+	backend := filepath.Join(dir, "backend.conf")
+	pool := filepath.Join(dir, "pool.conf")
+	cache := filepath.Join(dir, "cache.conf")
+	app := filepath.Join(dir, "app.conf")
+	writeFile(pool, []byte("Max = 10"))
+	writeFile(backend, []byte("Host = db\ninclude "+pool+" as Pool"))
+	writeFile(cache, []byte("include \""+backend+"\" as Backend"))
+	writeFile(app, []byte("Name = Rick\ninclude "+backend+" as Database\ninclude "+cache+" as Cache"))
+
+	Convey("Decode included files under a key prefix", t, func() {
+		var x T
+		So(DecodeFile(app, &x), ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+		So(x.Database.Host, ShouldEqual, "db")
+		So(x.Database.Pool.Max, ShouldEqual, 10)
+		So(x.Cache.Backend.Host, ShouldEqual, "db")
+		So(x.Cache.Backend.Pool.Max, ShouldEqual, 10)
+
+		Convey("With DecodeFiles", func() {
+			var x T
+			So(DecodeFiles([]string{app}, &x), ShouldBeNil)
+			So(x.Cache.Backend.Pool.Max, ShouldEqual, 10)
+		})
+	})
+
+	Convey("Parse included files under a key prefix", t, func() {
+		smap, err := ParseFile(app)
+		So(err, ShouldBeNil)
+		So(smap, ShouldResemble, StringMap{
+			"Name":                   "Rick",
+			"Database.Host":          "db",
+			"Database.Pool.Max":      "10",
+			"Cache.Backend.Host":     "db",
+			"Cache.Backend.Pool.Max": "10",
+		})
+	})
+
+	Convey("Force error: Invalid key prefix", t, func() {
+		_, err := Parse("Name = a\ninclude backend.conf as .Database")
+		So(err.Error(), ShouldEqual, "Invalid key at line 2")
+	})
+
+}
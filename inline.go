@@ -0,0 +1,152 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// A key and value within an inline block
+var inlinePair = regexp.MustCompile(`^((?:[\pL\pN_\-\.]|"(?:[^"\\]|\\.)*")+)\s*[=:\s]\s*(.*)$`)
+
+// The first key of the body of an inline block, and its equals sign or the
+// brace of a nested block
+var inlineStart = regexp.MustCompile(`^\s*(?:[\pL\pN_\-\.]|"(?:[^"\\]|\\.)*")+\s*[={]`)
+
+// Parse an inline block, a block written on one line with its keys separated
+// by commas or semicolons, eg. Limits = { Min = 1, Max = 10 }. Inline blocks
+// may be nested. A value containing a comma, semicolon or brace must be
+// quoted. A value enclosed in braces which does not begin with a key and an
+// equals sign or a nested block, such as {level} or {"a": 1}, is a plain
+// value.
+func (o *Parser) parseInline(fieldMap fMap, key, s string) {
+	emap := make(fMap)
+	// the keys of the line's own trace entry are prefixed too
+	n := len(o.trace) - 1
+	if err := o.inlineKeys(emap, "", s); err != nil {
+		o.appendErr(err, o.lineno)
+		return
+	}
+	o.addBlock(fieldMap, key, emap, o.lineno)
+	o.traceBlock(n, key)
+}
+
+// Report whether the text between the braces of a value is the body of an
+// inline block: empty, or beginning with key = value or a nested block.
+func isInlineBody(s string) bool {
+	return trim(s) == "" || inlineStart.MatchString(s)
+}
+
+// Add the keys of the body of an inline block to a field map.
+func (o *Parser) inlineKeys(m fMap, prefix, s string) error {
+	for _, pair := range splitInline(s) {
+		pair = trim(pair)
+		if pair == "" {
+			continue
+		}
+		a := inlinePair.FindStringSubmatch(pair)
+		if a == nil {
+			return errors.New("Invalid inline block")
+		}
+		var segs []string
+		for _, k := range splitKey(a[1]) {
+			segs = append(segs, keySegment(k))
+		}
+		key := prefix + strings.Join(segs, ".")
+		if badKey(key) {
+			return ErrInvalidKey
+		}
+		val := a[2]
+		if strings.HasPrefix(val, "{") && strings.HasSuffix(val, "}") {
+			if err := o.inlineKeys(m, key+".", val[1:len(val)-1]); err != nil {
+				return err
+			}
+			continue
+		}
+		if !o.addKey(m, key, o.lineno) {
+			continue
+		}
+		val, err := parseValue(val)
+		if err != nil {
+			return err
+		}
+		m[key] = &v{val, o.lineno, false, 0, o.filename}
+		o.traceKey(key, val)
+		o.countKey()
+	}
+	return nil
+}
+
+// Split the body of an inline block at the commas and semicolons which are
+// not within quotes or a nested block.
+func splitInline(s string) []string {
+	var a []string
+	var quoted bool
+	var depth, start int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case '{':
+			if !quoted {
+				depth++
+			}
+		case '}':
+			if !quoted {
+				depth--
+			}
+		case ',', ';':
+			if !quoted && depth == 0 {
+				a = append(a, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(a, s[start:])
+}
+
+// Write a struct or map as an inline block if it contains only scalar values
+// which need no quoting, and fits within the width of a line. Returns false
+// if it was not written.
+func (o *Encoder) encodeInline(v1 reflect.Value, depth int, parent_key string) bool {
+	if parent_key == "" || o.isOption(ENCODE_FLAT) || o.template {
+		return false
+	}
+	var buf bytes.Buffer
+	sub := *o
+	sub.writer = &buf
+	sub.errs = nil
+	sub.options &^= ENCODE_INLINE_BLOCKS
+	sub.previous_key = ""
+	if v1.Kind() == reflect.Map {
+		sub.encodeMap(v1, 0, "")
+	} else {
+		sub.encodeStruct(v1, 0, "")
+	}
+	if sub.errs != nil || buf.Len() == 0 {
+		return false
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), lf), lf)
+	for _, s := range lines {
+		i := strings.Index(s, " = ")
+		if i < 0 || strings.ContainsAny(s[i+3:], `{};,"<`) || strings.HasPrefix(s, "#") {
+			return false
+		}
+	}
+	s := "{ " + strings.Join(lines, ", ") + " }"
+	if len(s)+len(parent_key)+3+2*depth > multi_line_width {
+		return false
+	}
+	o.write_kv(depth, parent_key, s)
+	return true
+}
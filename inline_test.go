@@ -0,0 +1,63 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInlineBlocks(t *testing.T) {
+
+	Convey("Parse inline blocks", t, func() {
+		m, err := Parse(`
+			Limits = { Min = 1, Max = 10 }
+			Server { Host = example.com; Tls = { Port = 443 }; Name = "a, b" }
+			"my block" = { A = 1 }
+			Empty = {}
+		`)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{
+			"Limits.Min": "1", "Limits.Max": "10",
+			"Server.Host": "example.com", "Server.Tls.Port": "443", "Server.Name": "a, b",
+			"my block.A": "1",
+		})
+	})
+
+	type limits struct{ Min, Max int }
+	type T struct {
+		Name   string
+		Limits limits
+		Ports  map[string]int
+		Notes  struct{ Text string }
+	}
+
+	Convey("Encode inline blocks", t, func() {
+		x := T{Name: "app", Limits: limits{1, 10}, Ports: map[string]int{"http": 80, "https": 443}}
+		x.Notes.Text = "a, b"
+		b, err := Encode(x, ENCODE_INLINE_BLOCKS)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldContainSubstring, "Limits = { Min = 1, Max = 10 }\n")
+		So(string(b), ShouldContainSubstring, "Ports = { http = 80, https = 443 }\n")
+		So(string(b), ShouldContainSubstring, "Notes = {\n")
+		var y T
+		So(Decode(&y, b), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+	Convey("A value enclosed in braces which is not a block is a plain value", t, func() {
+		m, err := Parse("Fmt = {level}\nJson = {\"a\": 1}\n\"my key\" = {x}")
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"Fmt": "{level}", "Json": `{"a": 1}`, "my key": "{x}"})
+	})
+
+	Convey("Force error: Invalid inline block", t, func() {
+		_, err := Parse("Limits = { Min = 1, Max }")
+		So(err.Error(), ShouldStartWith, "Invalid inline block at line 1")
+		_, err = Parse("Limits = { Min = 1, Min = 2 }")
+		So(err.Error(), ShouldStartWith, "Duplicate key at line 1")
+	})
+
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// ToJSON parses a string, a byte slice, an io.Reader or an already parsed
+// StringMap and returns the equivalent JSON object. Brace blocks become nested
+// objects. Values that are plain JSON numbers or the words true and false
+// (in any case) are emitted as numbers and booleans, everything else as
+// strings.
+func ToJSON(src interface{}) ([]byte, error) {
+	smap, ok := src.(StringMap)
+	if !ok {
+		var err error
+		if smap, err = Parse(src); err != nil {
+			return nil, err
+		}
+	}
+	tree, err := nest(smap, jsonValue)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+// FromJSON converts a JSON object to configuration format. Nested objects are
+// encoded as brace blocks. Zero values are retained. Arrays and null values are
+// not supported.
+func FromJSON(bs []byte) ([]byte, error) {
+	m, err := unmarshalJSON(bs)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(m, ENCODE_ZERO_VALUES)
+}
+
+// DecodeJSON will accept a JSON object and decode it into the supplied struct
+// or map exactly as if it had been supplied in configuration format. Null
+// values are ignored.
+func (o *Decoder) DecodeJSON(bs []byte) error {
+	o.reset()
+	m, err := unmarshalJSON(bs)
+	if err != nil {
+		return err
+	}
+	o.parser = NewParser()
+	o.fieldMap = make(fMap)
+	if err = flattenJSON(o.fieldMap, "", m); err != nil {
+		return err
+	}
+	return o.assign()
+}
+
+func unmarshalJSON(bs []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(bs))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Flatten a decoded JSON object into dotted field map keys
+func flattenJSON(fieldMap fMap, parent_key string, m map[string]interface{}) error {
+	for k, val := range m {
+		key := k
+		if parent_key != "" {
+			key = parent_key + "." + k
+		}
+		switch t := val.(type) {
+		case map[string]interface{}:
+			if err := flattenJSON(fieldMap, key, t); err != nil {
+				return err
+			}
+		case json.Number:
+			fieldMap[key] = &v{val: t.String()}
+		case string:
+			fieldMap[key] = &v{val: t}
+		case bool:
+			fieldMap[key] = &v{val: strconv.FormatBool(t)}
+		case nil:
+		default:
+			return errors.New("type array not allowed (" + key + ")")
+		}
+	}
+	return nil
+}
+
+func jsonValue(s string) interface{} {
+	switch toLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if jsonNumber.MatchString(s) {
+		return json.Number(s)
+	}
+	return s
+}
+
+// Rebuild the dotted keys of a string map into nested maps, converting each
+// value with the supplied function. Keys are added in sorted order. A key which
+// conflicts with one already added, such as A.B after A, is skipped, and the
+// first such conflict is returned as an error along with the tree.
+func nest(smap StringMap, conv func(string) interface{}) (map[string]interface{}, error) {
+	var err error
+	tree := make(map[string]interface{})
+	keys := make([]string, 0, len(smap))
+	for k := range smap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+next:
+	for _, key := range keys {
+		node := tree
+		path := splitKey(key)
+		for _, k := range path[:len(path)-1] {
+			switch child := node[k].(type) {
+			case nil:
+				m := make(map[string]interface{})
+				node[k] = m
+				node = m
+			case map[string]interface{}:
+				node = child
+			default:
+				if err == nil {
+					err = errors.New("Key conflict (" + key + ")")
+				}
+				continue next
+			}
+		}
+		k := path[len(path)-1]
+		if _, ok := node[k]; ok {
+			if err == nil {
+				err = errors.New("Key conflict (" + key + ")")
+			}
+			continue
+		}
+		node[k] = conv(smap[key])
+	}
+	return tree, err
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestToJSON(t *testing.T) {
+
+	cfg := `
+		Name    = Rick
+		Age     = 70
+		Ratio   = -1.5e3
+		Zip     = 01234
+		Active  = True
+		Server {
+			Host = localhost
+			Port = 8080
+		}
+	`
+
+	Convey("Convert a configuration to nested JSON", t, func() {
+		b, err := ToJSON(cfg)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, `{"Active":true,"Age":70,"Name":"Rick","Ratio":-1.5e3,`+
+			`"Server":{"Host":"localhost","Port":8080},"Zip":"01234"}`)
+	})
+
+	Convey("Convert a parsed string map", t, func() {
+		b, err := ToJSON(StringMap{"A.B": "1", "C": "no"})
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, `{"A":{"B":1},"C":"no"}`)
+	})
+
+	Convey("Force error: Parse error", t, func() {
+		_, err := ToJSON("Key1={Key=2")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Force error: Key conflict", t, func() {
+		_, err := ToJSON("A = 1\nA.B = 2")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "Key conflict")
+	})
+
+}
+
+func TestFromJSON(t *testing.T) {
+
+	src := []byte(`{
+		"Name": "Rick",
+		"Count": 9007199254740993,
+		"Active": false,
+		"Server": {"Host": "localhost", "Port": 8080}
+	}`)
+
+	Convey("Convert a JSON object to configuration format", t, func() {
+		b, err := FromJSON(src)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "Active = False\n"+
+			"Count = 9007199254740993\n"+
+			"Name = Rick\n"+
+			"Server = {\n"+
+			"  Host = localhost\n"+
+			"  Port = 8080\n"+
+			"}\n")
+	})
+
+	Convey("Decode JSON into a struct", t, func() {
+		var x struct {
+			Name   string
+			Count  int64
+			Active bool
+			Server struct {
+				Host string
+				Port int
+			}
+		}
+		err := NewDecoder(&x).DecodeJSON(src)
+		So(err, ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+		So(x.Count, ShouldEqual, 9007199254740993)
+		So(x.Server.Port, ShouldEqual, 8080)
+	})
+
+	Convey("Decode JSON with snake case option", t, func() {
+		var x struct{ MaxConns int }
+		err := NewDecoder(&x, ALLOW_SNAKE_CASE).DecodeJSON([]byte(`{"max_conns": 5}`))
+		So(err, ShouldBeNil)
+		So(x.MaxConns, ShouldEqual, 5)
+	})
+
+	Convey("Force error: Extra field in JSON", t, func() {
+		var x struct{ Key1 int }
+		err := NewDecoder(&x).DecodeJSON([]byte(`{"Key2": 5}`))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Extra field (Key2)")
+	})
+
+	Convey("Errors and warnings of an earlier decode are not reported", t, func() {
+		var x struct{ Key1, Key2 int }
+		d := NewDecoder(&x, LAST_KEY_WINS)
+		So(d.DecodeString("Key1 = 1\nKey1 = x"), ShouldNotBeNil)
+		So(d.Warnings(), ShouldHaveLength, 1)
+		So(d.DecodeJSON([]byte(`{"Key1": 5}`)), ShouldBeNil)
+		So(d.Warnings(), ShouldHaveLength, 0)
+		So(x.Key1, ShouldEqual, 5)
+	})
+
+	Convey("Force error: JSON arrays are not allowed", t, func() {
+		_, err := FromJSON([]byte(`{"Key1": [1, 2]}`))
+		So(err, ShouldNotBeNil)
+		var x struct{ Key1 int }
+		err = NewDecoder(&x).DecodeJSON([]byte(`{"Key1": [1, 2]}`))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Force error: Invalid JSON", t, func() {
+		_, err := FromJSON([]byte(`{`))
+		So(err, ShouldNotBeNil)
+	})
+
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parse a key in double quotes, which may contain spaces and other
+// characters not allowed in a bare key, followed by a value or an opening
+// brace, eg. "cache-control header" = no-cache. A dotted key may contain
+// quoted segments, eg. Hosts."my host" = 10.0.0.1. A quoted segment which
+// contains a dot or a quote remains quoted in the parsed key path, so that it
+// is not taken to be a nested key.
+func (o *Parser) parseQuotedKey(fieldMap fMap, raw, val string, depth int) {
+	key, err := quotedKeyPath(raw)
+	if err != nil {
+		o.appendErr(err, o.lineno)
+		return
+	}
+	switch val {
+	case "":
+		o.appendError("Invalid data", o.lineno)
+	case "{":
+		o.parseBlock(fieldMap, key, depth)
+	default:
+		if len(val) > 1 && val[0] == '{' && val[len(val)-1] == '}' && isInlineBody(val[1:len(val)-1]) {
+			o.parseInline(fieldMap, key, val[1:len(val)-1])
+			return
+		}
+		if !o.addKey(fieldMap, key, o.lineno) {
+			return
+		}
+		val, err := parseValue(val)
+		if err != nil {
+			o.appendErr(err, o.lineno)
+			return
+		}
+		fieldMap[key] = &v{val, o.lineno, false, 0, o.filename}
+		o.traceKey(key, val)
+		o.countKey()
+	}
+}
+
+// Return the key path of a key written with quoted segments.
+func quotedKeyPath(raw string) (string, error) {
+	var a []string
+	for raw != "" {
+		k := raw
+		if i := keyIndex(raw); i >= 0 {
+			k, raw = raw[:i], raw[i+1:]
+		} else {
+			raw = ""
+		}
+		if k[0] == '"' {
+			var err error
+			if k, err = strconv.Unquote(k); err != nil {
+				return "", ErrInvalidKey
+			}
+		}
+		a = append(a, keySegment(k))
+	}
+	return strings.Join(a, "."), nil
+}
+
+// Return a key as one segment of a key path, quoting it if it contains a
+// dot or a quote.
+func keySegment(k string) string {
+	if k == "" || strings.ContainsAny(k, `."`) {
+		return strconv.Quote(k)
+	}
+	return k
+}
+
+// Return the index of the first dot in a key path which is not within a
+// quoted segment, or -1.
+func keyIndex(k string) int {
+	quoted := false
+	for i := 0; i < len(k); i++ {
+		switch k[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case '.':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Split a key path into its segments, removing the quotes of quoted
+// segments.
+func splitKey(k string) []string {
+	var a []string
+	for {
+		i := keyIndex(k)
+		if i < 0 {
+			return append(a, unquoteKey(k))
+		}
+		a = append(a, unquoteKey(k[:i]))
+		k = k[i+1:]
+	}
+}
+
+// Remove the quotes of a quoted key segment.
+func unquoteKey(k string) string {
+	if len(k) > 1 && k[0] == '"' && keyIndex(k) < 0 {
+		if s, err := strconv.Unquote(k); err == nil {
+			return s
+		}
+	}
+	return k
+}
+
+// A key which may be written without quotes
+var bareKey = regexp.MustCompile(`^[\pL\pN_\-]+$`)
+
+// Return a map key as it is written by the encoder. A key which is not a
+// bare key, such as one containing a space, a dot, =, # or a quote, is quoted
+// so that it parses back to the same key. A # is escaped, as the parser
+// would take it to start a comment.
+func encodeKey(k string) string {
+	if bareKey.MatchString(k) && !badKey(k) {
+		return k
+	}
+	return strings.Replace(strconv.Quote(k), "#", `\x23`, -1)
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strings"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQuotedKeys(t *testing.T) {
+
+	src := `
+		"cache-control header" = no-cache
+		Hosts {
+			"example.com" = 10.0.0.1
+			"my host" = 10.0.0.2
+		}
+		Servers {
+			"web 1" {
+				Port = 80
+			}
+			"api.internal" = {
+				Port = 81
+			}
+		}
+	`
+
+	Convey("Parse quoted keys", t, func() {
+		m, err := Parse(src)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{
+			"cache-control header":       "no-cache",
+			`Hosts."example.com"`:        "10.0.0.1",
+			"Hosts.my host":              "10.0.0.2",
+			"Servers.web 1.Port":         "80",
+			`Servers."api.internal".Port`: "81",
+		})
+	})
+
+	type server struct{ Port int }
+	type T struct {
+		Hosts   map[string]string
+		Servers map[string]server
+	}
+
+	Convey("Decode quoted keys to maps", t, func() {
+		var x T
+		So(Decode(&x, src[strings.Index(src, "Hosts"):]), ShouldBeNil)
+		So(x.Hosts, ShouldResemble, map[string]string{"example.com": "10.0.0.1", "my host": "10.0.0.2"})
+		So(x.Servers, ShouldResemble, map[string]server{"web 1": {80}, "api.internal": {81}})
+
+		m := make(map[string]interface{})
+		So(Decode(m, src), ShouldBeNil)
+		So(m["Hosts"], ShouldResemble, map[string]interface{}{"example.com": "10.0.0.1", "my host": "10.0.0.2"})
+	})
+
+	Convey("Encode quoted keys", t, func() {
+		x := T{
+			Hosts:   map[string]string{"example.com": "10.0.0.1", "my host": "10.0.0.2"},
+			Servers: map[string]server{"web 1": {80}},
+		}
+		b, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldContainSubstring, `"example.com" = 10.0.0.1`)
+		So(string(b), ShouldContainSubstring, `"my host" = 10.0.0.2`)
+		var y T
+		So(Decode(&y, b), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+	Convey("Force error: Invalid quoted keys", t, func() {
+		_, err := Parse(`"a b"`)
+		So(err.Error(), ShouldStartWith, "Invalid data at line 1")
+	})
+
+}
+
+func TestUnicodeKeys(t *testing.T) {
+
+	type T struct {
+		Ärger      int
+		GrößeMax   string
+		Einstellung map[string]string
+	}
+
+	Convey("Decode Unicode keys", t, func() {
+		var x T
+		err := Decode(&x, `
+			Ärger = 1
+			GrößeMax = xl
+			Einstellung {
+				设置 = 值
+				Ñandú = ave
+			}
+		`)
+		So(err, ShouldBeNil)
+		So(x, ShouldResemble, T{1, "xl", map[string]string{"设置": "值", "Ñandú": "ave"}})
+	})
+
+	Convey("Decode Unicode keys in snake and lower case", t, func() {
+		var x T
+		So(Decode(&x, "ärger = 2\ngröße_max = s", ALLOW_SNAKE_CASE), ShouldBeNil)
+		So(x.Ärger, ShouldEqual, 2)
+		So(x.GrößeMax, ShouldEqual, "s")
+		var y T
+		So(Decode(&y, "ärger = 3\ngrößemax = m", IGNORE_CASE), ShouldBeNil)
+		So(y.Ärger, ShouldEqual, 3)
+		So(y.GrößeMax, ShouldEqual, "m")
+	})
+
+	Convey("Convert Unicode case", t, func() {
+		So(isPublic("Ärger"), ShouldBeTrue)
+		So(isPublic("ärger"), ShouldBeFalse)
+		So(toSnakeCase("GrößeMax"), ShouldEqual, "größe_max")
+		So(toLower("ÄRGER"), ShouldEqual, "ärger")
+		So(toUpper("ärger"), ShouldEqual, "ÄRGER")
+	})
+
+}
+
+func TestEncodeUnusualKeys(t *testing.T) {
+
+	Convey("Encode unusual map keys so that they parse back", t, func() {
+		type T struct {
+			M map[string]string
+			S map[string]struct{ A int }
+		}
+		x := T{
+			M: map[string]string{
+				"a b": "1", "x=y": "2", "c#d": "3", ".lead": "4", "trail.": "5",
+				`q"uote`: "6", "": "7", "_": "8", "tab\there": "9", "back\\slash": "10",
+				"plain": "11", "Ümlaut": "12",
+			},
+			S: map[string]struct{ A int }{"x = y # z": {1}, "a.b": {2}},
+		}
+		b, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldContainSubstring, `"c\x23d" = 3`)
+		So(string(b), ShouldContainSubstring, "  plain = 11")
+		So(string(b), ShouldContainSubstring, "  Ümlaut = 12")
+		var y T
+		So(Decode(&y, b), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+}
+
+func TestQuotedKeyPaths(t *testing.T) {
+
+	Convey("Parse dotted keys with quoted segments", t, func() {
+		m, err := Parse(`
+			Hosts."my host" = 10.0.0.1
+			Hosts."example.com".Port = 80
+			"a b".c = 1
+		`)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{
+			"Hosts.my host":              "10.0.0.1",
+			`Hosts."example.com".Port`:   "80",
+			"a b.c":                      "1",
+		})
+	})
+
+	Convey("Force error: Invalid escape in a quoted key", t, func() {
+		_, err := Parse(`A."\q" = 1`)
+		So(err.Error(), ShouldStartWith, "Invalid key at line 1")
+	})
+
+}
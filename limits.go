@@ -0,0 +1,198 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Limits restrict the resources used to parse untrusted input. A zero value
+// for any field means no limit. Parsing stops at the first limit exceeded.
+type Limits struct {
+	MaxBytes        int64 // Total size of the input
+	MaxLineLength   int   // Length of any line, including heredoc lines
+	MaxKeys         int   // Number of key/value pairs
+	MaxDepth        int   // Brace nesting depth
+	MaxHeredoc      int   // Size of any heredoc body
+	MaxHeredocTotal int64 // Combined size of all heredoc bodies
+	MaxIncludes     int   // Number of included files, including nested includes
+	MaxIncludeBytes int64 // Combined size of all included files
+	MaxErrors       int   // Number of errors reported before parsing stops
+}
+
+// SafeLimits are the limits applied by the SAFE_MODE option.
+var SafeLimits = Limits{
+	MaxBytes:        1 << 20,
+	MaxLineLength:   4096,
+	MaxKeys:         10000,
+	MaxDepth:        32,
+	MaxHeredoc:      64 << 10,
+	MaxHeredocTotal: 256 << 10,
+	MaxIncludes:     16,
+	MaxIncludeBytes: 4 << 20,
+}
+
+// SetLimits sets the limits applied while parsing, replacing those set by the
+// SAFE_MODE option.
+func (o *Parser) SetLimits(l Limits) *Parser {
+	o.limits = l
+	return o
+}
+
+// SetLimits sets the limits applied while parsing, replacing those set by the
+// SAFE_MODE option.
+func (o *Decoder) SetLimits(l Limits) *Decoder {
+	o.limits = l
+	return o
+}
+
+// Return a new Parser with the limits of the Decoder.
+func (o *Decoder) newParser() *Parser {
+	p := NewParser(o.options & parserOptions)
+	p.limits = o.limits
+	p.profile = o.profile
+	p.vars = o.vars
+	p.refs = o.refs
+	p.logger = o.logger
+	p.tracing = o.tracing
+	return p
+}
+
+// Read the next line, enforcing the line length limit. The line is only
+// valid until the next read, as it may refer to the buffer of the reader.
+func (o *Parser) readLine() ([]byte, error) {
+	max := o.limits.MaxLineLength
+	line := o.line[:0]
+	for {
+		b, err := o.reader.ReadSlice('\n')
+		if err != bufio.ErrBufferFull && len(line) == 0 {
+			// the whole line is in the buffer of the reader
+			line = b
+		} else {
+			line = append(line, b...)
+			o.line = line
+		}
+		if max > 0 && len(trimNewline(line)) > max {
+			return nil, newError(fmt.Sprintf("Line exceeds limit of %d bytes", max), o.lineno+1)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// Stop parsing once the error limit is reached. The last error becomes the
+// reason parsing stopped, so that it is still reported.
+func (o *Parser) countError() {
+	if max := maxErrors(o.options, o.limits); max > 0 && len(o.errs) >= max && o.abort == nil {
+		o.abort = o.errs[len(o.errs)-1]
+		o.errs = o.errs[:len(o.errs)-1]
+	}
+}
+
+// Return the error limit, which is 1 under the FAIL_FAST option.
+func maxErrors(options int, l Limits) int {
+	if isOption(FAIL_FAST, options) {
+		return 1
+	}
+	return l.MaxErrors
+}
+
+// errMaxErrors stops the decoder once the error limit is reached. It is never
+// returned to the caller.
+var errMaxErrors = errors.New("Error limit reached")
+
+// Count a key, enforcing the key limit.
+func (o *Parser) countKey() {
+	o.nkeys++
+	if max := o.limits.MaxKeys; max > 0 && o.nkeys > max && o.abort == nil {
+		o.abort = newError(fmt.Sprintf("Number of keys exceeds limit of %d", max), o.lineno)
+	}
+}
+
+// Enforce the heredoc limits for a heredoc body of the given size which began
+// at the given line.
+func (o *Parser) checkHeredoc(size int, lineno int) error {
+	if max := o.limits.MaxHeredoc; max > 0 && size > max {
+		o.abort = newError(fmt.Sprintf("Heredoc exceeds limit of %d bytes", max), lineno)
+	} else if max := o.limits.MaxHeredocTotal; max > 0 && o.heredocBytes+int64(size) > max {
+		o.abort = newError(fmt.Sprintf("Heredocs exceed combined limit of %d bytes", max), lineno)
+	}
+	return o.abort
+}
+
+// includeState counts the files included while reading one file.
+type includeState struct {
+	n     int
+	bytes int64
+}
+
+// Count an included file, enforcing the limit on the number of files.
+func (st *includeState) add(filename string, l Limits) error {
+	st.n++
+	if max := l.MaxIncludes; max > 0 && st.n > max {
+		return errors.New(fmt.Sprintf("Number of included files exceeds limit of %d (%s)", max, filename))
+	}
+	return nil
+}
+
+// Return a reader which counts the bytes read from an included file, once it
+// has been opened, enforcing the combined size limit of included files.
+func (st *includeState) count(r io.Reader, filename string, l Limits) io.Reader {
+	if l.MaxIncludeBytes <= 0 {
+		return r
+	}
+	return &includeReader{r: r, st: st, filename: filename, max: l.MaxIncludeBytes}
+}
+
+// includeReader returns an error once the included files have supplied more
+// than max bytes.
+type includeReader struct {
+	r        io.Reader
+	st       *includeState
+	filename string
+	max      int64
+}
+
+func (o *includeReader) Read(p []byte) (int, error) {
+	n, err := o.r.Read(p)
+	o.st.bytes += int64(n)
+	if o.st.bytes > o.max {
+		return 0, errors.New(fmt.Sprintf("Included files exceed combined limit of %d bytes (%s)", o.max, o.filename))
+	}
+	return n, err
+}
+
+func trimNewline(b []byte) []byte {
+	if n := len(b); n > 0 && b[n-1] == '\n' {
+		b = b[:n-1]
+		if n := len(b); n > 0 && b[n-1] == '\r' {
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
+// limitedReader returns an error once more than max bytes have been read.
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+}
+
+func (o *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > o.max-o.n+1 {
+		p = p[:o.max-o.n+1]
+	}
+	n, err := o.r.Read(p)
+	o.n += int64(n)
+	if o.n > o.max {
+		return 0, errors.New(fmt.Sprintf("Input exceeds limit of %d bytes", o.max))
+	}
+	return n, err
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLimits(t *testing.T) {
+
+	Convey("Input within limits is parsed", t, func() {
+		l := Limits{MaxBytes: 100, MaxLineLength: 20, MaxKeys: 3, MaxDepth: 2}
+		smap, err := NewParser().SetLimits(l).Parse([]byte("A = 1\nB {\n C {\n  D = 2\n }\n}\nE = <<X\nline\nX\n"))
+		So(err, ShouldBeNil)
+		So(len(smap), ShouldEqual, 3)
+	})
+
+	Convey("Force error: Exceed each limit", t, func() {
+		p := NewParser().SetLimits(Limits{MaxBytes: 10})
+		_, err := p.Parse([]byte("A = 1\nB = 2\nC = 3\n"))
+		So(err.Error(), ShouldEqual, "Input exceeds limit of 10 bytes")
+
+		p = NewParser().SetLimits(Limits{MaxLineLength: 10})
+		_, err = p.Parse([]byte("A = 1\nB = " + strings.Repeat("x", 5000) + "\nC = 3\n"))
+		So(err.Error(), ShouldEqual, "Line exceeds limit of 10 bytes at line 2")
+
+		p = NewParser().SetLimits(Limits{MaxLineLength: 10})
+		_, err = p.Parse([]byte("A = <<X\n" + strings.Repeat("x", 50) + "\nX\n"))
+		So(err.Error(), ShouldEqual, "Line exceeds limit of 10 bytes at line 2")
+
+		p = NewParser().SetLimits(Limits{MaxKeys: 2})
+		_, err = p.Parse([]byte("A = 1\nB = 2\nC = 3\nD = 4\n"))
+		So(err.Error(), ShouldEqual, "Number of keys exceeds limit of 2 at line 3")
+
+		p = NewParser().SetLimits(Limits{MaxDepth: 1})
+		_, err = p.Parse([]byte("A {\n B {\n  C = 1\n }\n}\n"))
+		So(err.Error(), ShouldEqual, "Nesting exceeds limit of 1 at line 2")
+	})
+
+	Convey("Force error: Exceed the heredoc limits", t, func() {
+		doc := "A = <<X\n" + strings.Repeat("abcdefghi\n", 5) + "X\n"
+		_, err := NewParser().SetLimits(Limits{MaxHeredoc: 49}).Parse([]byte(doc))
+		So(err, ShouldBeNil)
+		_, err = NewParser().SetLimits(Limits{MaxHeredoc: 48}).Parse([]byte("B = 1\n" + doc))
+		So(err.Error(), ShouldEqual, "Heredoc exceeds limit of 48 bytes at line 2")
+
+		doc2 := doc + strings.Replace(doc, "A", "B", 1)
+		_, err = NewParser().SetLimits(Limits{MaxHeredocTotal: 98}).Parse([]byte(doc2))
+		So(err, ShouldBeNil)
+		_, err = NewParser().SetLimits(Limits{MaxHeredocTotal: 97}).Parse([]byte(doc2))
+		So(err.Error(), ShouldEqual, "Heredocs exceed combined limit of 97 bytes at line 8")
+	})
+
+	Convey("SAFE_MODE applies SafeLimits to the parser and decoder", t, func() {
+		So(NewParser(SAFE_MODE).limits, ShouldResemble, SafeLimits)
+		var x struct{ Name string }
+		err := Decode(&x, "Name = "+strings.Repeat("x", 5000), SAFE_MODE)
+		So(err.Error(), ShouldEqual, "Line exceeds limit of 4096 bytes at line 1")
+		m := make(map[string]string)
+		err = Decode(m, strings.Repeat("A {\n", 40), SAFE_MODE)
+		So(err.Error(), ShouldEqual, "Nesting exceeds limit of 32 at line 33")
+	})
+
+}
+
+func TestIncludeLimits(t *testing.T) {
+
+	dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.conf")
+	b := filepath.Join(dir, "b.conf")
+	c := filepath.Join(dir, "c.conf")
+	loop := filepath.Join(dir, "loop.conf")
+	writeFile(a, []byte("A = 1\ninclude "+b))
+	writeFile(b, []byte("B = 2\ninclude "+c))
+	writeFile(c, []byte("C = 3"))
+	writeFile(loop, []byte("L = 1\ninclude "+loop))
+
+	type cfg struct{ A, B, C, L int }
+
+	Convey("Nested includes within limits", t, func() {
+		var x cfg
+		o := NewDecoder(&x).SetLimits(Limits{MaxIncludes: 2, MaxIncludeBytes: 100})
+		So(o.DecodeFile(a), ShouldBeNil)
+		So(x, ShouldResemble, cfg{1, 2, 3, 0})
+		So(o.DecodeFile(a), ShouldBeNil)
+	})
+
+	Convey("Force error: Exceed the include limits", t, func() {
+		var x cfg
+		err := NewDecoder(&x).SetLimits(Limits{MaxIncludes: 1}).DecodeFile(a)
+		So(err.Error(), ShouldStartWith, "Number of included files exceeds limit of 1 ("+c+")")
+		fi, _ := os.Stat(b)
+		n := fi.Size() + 2
+		err = NewDecoder(&x).SetLimits(Limits{MaxIncludeBytes: n}).DecodeFile(a)
+		So(err.Error(), ShouldStartWith, fmt.Sprintf("Included files exceed combined limit of %d bytes (%s)", n, c))
+		_, err = ParseFile(loop, SAFE_MODE)
+		So(err.Error(), ShouldContainSubstring, "Number of included files exceeds limit of 16")
+		err = NewDecoder(&x, SAFE_MODE).DecodeFile(loop)
+		So(err.Error(), ShouldContainSubstring, "Number of included files exceeds limit of 16")
+		err = NewLoader(SAFE_MODE).AddFile(loop).Load(&x)
+		So(err.Error(), ShouldContainSubstring, "Number of included files exceeds limit of 16")
+	})
+
+	Convey("Force error: Count the bytes of expanded and resolved includes", t, func() {
+		big := filepath.Join(dir, "big.conf")
+		writeFile(big, []byte("B = 2\n"+strings.Repeat("# padding\n", 20)))
+		top := filepath.Join(dir, "top.conf")
+		writeFile(top, []byte("A = 1\ninclude ${dir}/big.conf"))
+		limits := Limits{MaxIncludeBytes: 100}
+		msg := "Included files exceed combined limit of 100 bytes ("
+
+		var x cfg
+		d := NewDecoder(&x, EXPAND_PATHS).SetVars(map[string]string{"dir": dir}).SetLimits(limits)
+		So(d.DecodeFile(top).Error(), ShouldStartWith, msg+big+")")
+		_, err := d.DecodeAll(top)
+		So(err.Error(), ShouldStartWith, msg+big+")")
+
+		writeFile(top, []byte("A = 1\ninclude bundled.conf"))
+		resolver := func(from, path string) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(strings.Repeat("# padding\n", 20))), nil
+		}
+		err = NewDecoder(&x).SetIncludeResolver(resolver).SetLimits(limits).DecodeFile(top)
+		So(err.Error(), ShouldStartWith, msg+"bundled.conf)")
+	})
+
+}
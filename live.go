@@ -0,0 +1,115 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Live holds the current value of a decoded configuration. Get may be called
+// concurrently with Replace, Watch and ReloadOnHUP without locking. The value
+// returned by Get must be treated as read only; publish changes with Replace.
+type Live[T any] struct {
+	p    atomic.Pointer[T]
+	mu   sync.Mutex
+	subs []subscription
+}
+
+// A subscription is a function to be called when the value of a key changes.
+type subscription struct {
+	key string
+	fn  func(old, new string)
+}
+
+// NewLive returns a Live holding x.
+func NewLive[T any](x *T) *Live[T] {
+	o := &Live[T]{}
+	o.p.Store(x)
+	return o
+}
+
+// Get returns the current value.
+func (o *Live[T]) Get() *T {
+	return o.p.Load()
+}
+
+// Replace publishes a new value and returns the previous one. Functions
+// registered with OnChange are called before Replace returns.
+func (o *Live[T]) Replace(x *T) *T {
+	old := o.p.Swap(x)
+	o.mu.Lock()
+	subs := o.subs
+	o.mu.Unlock()
+	if len(subs) > 0 {
+		m1, m2 := flatten(old), flatten(x)
+		for _, sub := range subs {
+			if m1[sub.key] != m2[sub.key] {
+				sub.fn(m1[sub.key], m2[sub.key])
+			}
+		}
+	}
+	return old
+}
+
+// OnChange registers a function to be called whenever a replacement changes
+// the value of the given dotted key, such as "Database.Host". The old and new
+// values are supplied as they would appear in an encoded configuration file.
+// A key which is missing or empty has the value "".
+func (o *Live[T]) OnChange(key string, fn func(old, new string)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	subs := make([]subscription, len(o.subs), len(o.subs)+1)
+	copy(subs, o.subs)
+	o.subs = append(subs, subscription{key, fn})
+}
+
+// Watch replaces the current value whenever the supplied file changes. A deep
+// copy of the value held when Watch is called supplies the defaults for every
+// reload, and every reload decodes into a fresh copy of them, so that values
+// returned by Get are never modified.
+// If the file cannot be decoded, onError is called (if not nil) and the
+// current value is kept. See Watch.
+func (o *Live[T]) Watch(filename string, onError func(error), options ...int) (*Watcher, error) {
+	return o.WatchWith(NewPollNotifier(time.Second), filename, onError, options...)
+}
+
+// WatchWith is like Watch but uses the supplied Notifier to detect changes.
+func (o *Live[T]) WatchWith(n Notifier, filename string, onError func(error), options ...int) (*Watcher, error) {
+	var defaults T
+	if x := o.Get(); x != nil {
+		defaults = deepCopy(reflect.ValueOf(x).Elem()).Interface().(T)
+	}
+	return WatchWith(n, filename, &defaults, func(x T, err error) {
+		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
+			return
+		}
+		o.Replace(&x)
+	}, options...)
+}
+
+// ReloadOnHUP replaces the current value whenever the process receives
+// SIGHUP. See ReloadOnHUP.
+func (o *Live[T]) ReloadOnHUP(filename string, onError func(error), options ...int) func() {
+	return reloadOnHUP(filename, func(x *T) { o.Replace(x) }, onError, options...)
+}
+
+// Return the dotted key/value pairs of a value as they would be encoded.
+func flatten(x interface{}) StringMap {
+	if x == nil || reflect.ValueOf(x).IsNil() {
+		return StringMap{}
+	}
+	bs, err := Encode(x, ENCODE_ZERO_VALUES)
+	if err != nil {
+		return StringMap{}
+	}
+	smap, _ := Parse(bs)
+	return smap
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLive(t *testing.T) {
+
+	type cfgX struct {
+		Name string
+		Port int
+	}
+
+	Convey("Get and Replace", t, func() {
+		first := &cfgX{Name: "Rick"}
+		l := NewLive(first)
+		So(l.Get(), ShouldEqual, first)
+		second := &cfgX{Name: "Morty"}
+		So(l.Replace(second), ShouldEqual, first)
+		So(l.Get(), ShouldEqual, second)
+	})
+
+	Convey("Concurrent readers during replacement", t, func() {
+		l := NewLive(&cfgX{Port: 1})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					_ = l.Get().Port
+				}
+			}()
+		}
+		for j := 0; j < 1000; j++ {
+			l.Replace(&cfgX{Port: j})
+		}
+		wg.Wait()
+		So(l.Get().Port, ShouldEqual, 999)
+	})
+
+	Convey("Notify subscribers of changed keys", t, func() {
+		type database struct {
+			Host string
+			Port int
+		}
+		type cfgY struct {
+			Name     string
+			Database database
+		}
+		l := NewLive(&cfgY{Name: "Rick", Database: database{"localhost", 5432}})
+		var changes []string
+		l.OnChange("Database.Host", func(old, new string) {
+			changes = append(changes, old+" -> "+new)
+		})
+		l.OnChange("Database.Port", func(old, new string) {
+			changes = append(changes, old+" -> "+new)
+		})
+		l.Replace(&cfgY{Name: "Morty", Database: database{"citadel", 5432}})
+		So(changes, ShouldResemble, []string{"localhost -> citadel"})
+		l.Replace(&cfgY{Name: "Morty", Database: database{"citadel", 5432}})
+		So(len(changes), ShouldEqual, 1)
+		l.Replace(&cfgY{})
+		So(changes[1:], ShouldResemble, []string{"citadel -> ", "5432 -> 0"})
+	})
+
+	Convey("Replace the value when the file changes", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = Rick"))
+
+		first := &cfgX{Port: 80}
+		l := NewLive(first)
+		errs := make(chan error, 1)
+		w, err := l.WatchWith(NewPollNotifier(5*time.Millisecond), tempfile, func(err error) { errs <- err })
+		So(err, ShouldBeNil)
+		defer w.Stop()
+
+		replaceFile(tempfile, []byte("Name = Morty"))
+		for i := 0; i < 200 && l.Get() == first; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		So(l.Get().Name, ShouldEqual, "Morty")
+		So(l.Get().Port, ShouldEqual, 80)
+
+		second := l.Get()
+		replaceFile(tempfile, []byte("Port = many"))
+		So(<-errs, ShouldNotBeNil)
+		So(l.Get(), ShouldEqual, second)
+	})
+
+	Convey("Reloads do not modify the maps of earlier values", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = Rick"))
+
+		type cfgZ struct {
+			Tags map[string]string
+		}
+		first := &cfgZ{Tags: map[string]string{"A": "1"}}
+		l := NewLive(first)
+		w, err := l.WatchWith(NewPollNotifier(5*time.Millisecond), tempfile, nil)
+		So(err, ShouldBeNil)
+		defer w.Stop()
+
+		replaceFile(tempfile, []byte("Tags = { B = 2 }"))
+		for i := 0; i < 200 && l.Get() == first; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		second := l.Get()
+		So(second.Tags, ShouldResemble, map[string]string{"A": "1", "B": "2"})
+
+		replaceFile(tempfile, []byte("Tags = { B = 3, C = 4 }"))
+		for i := 0; i < 200 && l.Get() == second; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		So(l.Get().Tags, ShouldResemble, map[string]string{"A": "1", "B": "3", "C": "4"})
+		So(second.Tags, ShouldResemble, map[string]string{"A": "1", "B": "2"})
+		So(first.Tags, ShouldResemble, map[string]string{"A": "1"})
+	})
+
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	layer_file = iota
+	layer_env
+	layer_flags
+)
+
+// A Loader decodes a struct from several layered sources: the values already
+// present in the struct (defaults), configuration files, environment
+// variables and command line flags. Sources are applied in the order they
+// were added, so that each source overrides the ones before it. After
+// loading, Source reports where each final value came from.
+//
+//	l := config.NewLoader(config.ALLOW_SNAKE_CASE)
+//	l.AddFile("/etc/app.conf").AddFile("app.conf").AddEnv("APP_").AddFlags(flag.CommandLine)
+//	err := l.Load(&cfg)
+type Loader struct {
+	options  int
+	layers   []layer
+	sources  map[string]string
+	cipher   Cipher
+	verify   VerifyFunc
+	warnings []Warning
+}
+
+type layer struct {
+	kind int
+	name string
+	fs   *flag.FlagSet
+}
+
+// NewLoader returns a new Loader. Decoder options are optional.
+func NewLoader(options ...int) *Loader {
+	o := &Loader{}
+	if len(options) > 0 {
+		o.options = options[0]
+	}
+	return o
+}
+
+// WithCipher sets a Cipher to decrypt every file layer, including included
+// files. See Decoder.WithCipher.
+func (o *Loader) WithCipher(c Cipher) *Loader {
+	o.cipher = c
+	return o
+}
+
+// WithVerifier sets a function to verify every file layer, including included
+// files, before it is parsed. See Decoder.WithVerifier.
+func (o *Loader) WithVerifier(fn VerifyFunc) *Loader {
+	o.verify = fn
+	return o
+}
+
+// AddFile adds a configuration file, including any files it includes.
+func (o *Loader) AddFile(filename string) *Loader {
+	o.layers = append(o.layers, layer{kind: layer_file, name: filename})
+	return o
+}
+
+// AddEnv adds environment variables with the supplied prefix. The variable
+// for a key is the prefix followed by the key in upper snake case with dots
+// replaced by underscores, eg. Server.MaxConns == APP_SERVER_MAX_CONNS.
+func (o *Loader) AddEnv(prefix string) *Loader {
+	o.layers = append(o.layers, layer{kind: layer_env, name: prefix})
+	return o
+}
+
+// AddFlags adds the flags of a parsed FlagSet. Only flags which were set on
+// the command line are used. The flag for a key is the key in snake case,
+// eg. Server.MaxConns == server.max_conns.
+func (o *Loader) AddFlags(fs *flag.FlagSet) *Loader {
+	o.layers = append(o.layers, layer{kind: layer_flags, fs: fs})
+	return o
+}
+
+// Load decodes all sources into the supplied struct pointer.
+func (o *Loader) Load(x interface{}) error {
+	d := NewDecoder(x, o.options).WithCipher(o.cipher).WithVerifier(o.verify)
+	defer func() { o.warnings = d.warnings }()
+	keys := structKeys(reflect.TypeOf(x), "", nil)
+	canon := make(map[string]string)
+	for _, k := range keys {
+		canon[k] = k
+		if isOption(ALLOW_SNAKE_CASE, o.options) {
+			canon[toSnakeCase(k)] = k
+		}
+		if isOption(ALLOW_KEBAB_CASE, o.options) {
+			canon[toKebabCase(k)] = k
+		}
+		if isOption(ALLOW_SCREAMING_SNAKE_CASE, o.options) {
+			canon[toScreamingSnakeCase(k)] = k
+		}
+		if isOption(IGNORE_CASE, o.options) {
+			canon[toLower(k)] = k
+		}
+	}
+	fieldMap := make(fMap)
+	for _, l := range o.layers {
+		m, err := l.fieldMap(keys, d)
+		if err != nil {
+			return err
+		}
+		for k, v := range m {
+			if c, ok := canon[k]; ok {
+				k = c
+			}
+			fieldMap[k] = v
+		}
+	}
+	o.sources = make(map[string]string)
+	for _, k := range keys {
+		o.sources[k] = "default"
+	}
+	for k, v := range fieldMap {
+		o.sources[k] = v.src
+	}
+	d.parser = NewParser()
+	d.fieldMap = fieldMap
+	return d.assign()
+}
+
+// Source returns the name of the source which supplied the final value of
+// a key: "default", a file name, "env NAME" or "flag -name". An empty
+// string is returned for unknown keys.
+func (o *Loader) Source(key string) string {
+	return o.sources[key]
+}
+
+// Warnings returns the warnings of the most recent load, in the order they
+// were found. See Decoder.Warnings.
+func (o *Loader) Warnings() []Warning {
+	return o.warnings
+}
+
+// Sources returns the source of every key. See Source.
+func (o *Loader) Sources() map[string]string {
+	return o.sources
+}
+
+func (l layer) fieldMap(keys []string, d *Decoder) (fMap, error) {
+	m := make(fMap)
+	switch l.kind {
+	case layer_file:
+		return parseFileFieldMap("", l.name, d, &includeState{})
+	case layer_env:
+		for _, k := range keys {
+			name := l.name + envName(k)
+			if s, ok := os.LookupEnv(name); ok {
+				m[k] = &v{val: s, src: "env " + name}
+			}
+		}
+	case layer_flags:
+		names := make(map[string]string)
+		for _, k := range keys {
+			names[flagName(k)] = k
+		}
+		l.fs.Visit(func(f *flag.Flag) {
+			if k, ok := names[f.Name]; ok {
+				m[k] = &v{val: f.Value.String(), src: "flag -" + f.Name}
+			}
+		})
+	}
+	return m, nil
+}
+
+// Parse a file and the files it includes to a field map, applying the limits,
+// parser options, lock, cipher and verifier of the decoder, and adding the
+// warnings and trace of the parser to those of the decoder. from is the name
+// of the including file, or empty.
+func parseFileFieldMap(from, filename string, d *Decoder, st *includeState) (fMap, error) {
+	var err error
+	if isOption(EXPAND_PATHS, d.options) {
+		if filename, err = expandPathVars(filename, d.vars); err != nil {
+			return nil, err
+		}
+	}
+	unlock, err := d.lockSource(from, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	fh, err := d.openSource(from, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	p := d.newParser()
+	p.filename = filename
+	var r io.Reader = fh
+	if from != "" {
+		r = st.count(fh, filename, d.limits)
+	}
+	p.reader = bufio.NewReader(r)
+	m, err := p.parse()
+	d.warnings = append(d.warnings, p.warnings...)
+	d.trace = append(d.trace, p.trace...)
+	if err != nil {
+		return nil, err
+	}
+	d.addRefs(m)
+	for i, f := range p.include {
+		if err := st.add(f, d.limits); err != nil {
+			return nil, err
+		}
+		im, err := parseFileFieldMap(filename, f, d, st)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range im {
+			m[includeKey(p.includeAs[i], k)] = v
+		}
+	}
+	return m, nil
+}
+
+// Return the dotted keys of every scalar field in a struct type, including
+// types decoded from a single value such as net.IP. Maps and other dynamic
+// types are skipped.
+func structKeys(t reflect.Type, parent_key string, keys []string) []string {
+	if isBigType(t) || isTextType(t) || t == locationType {
+		return append(keys, parent_key)
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return structKeys(t.Elem(), parent_key, keys)
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Interface, reflect.Func, reflect.Chan:
+		return keys
+	case reflect.Struct:
+		if isTimeType(t) {
+			break
+		}
+		for i := 0; i < t.NumField(); i++ {
+			key := fieldKey(t.Field(i))
+			if key == "" {
+				continue
+			}
+			if parent_key != "" {
+				key = parent_key + "." + key
+			}
+			keys = structKeys(t.Field(i).Type, key, keys)
+		}
+		return keys
+	}
+	return append(keys, parent_key)
+}
+
+// Convert a dotted key to an environment variable name,
+// eg. Server.MaxConns -> SERVER_MAX_CONNS
+func envName(k string) string {
+	return toUpper(strings.Replace(flagName(k), ".", "_", -1))
+}
+
+// Convert a dotted key to a flag name, eg. Server.MaxConns -> server.max_conns
+func flagName(k string) string {
+	a := strings.Split(k, ".")
+	for i := range a {
+		a[i] = toSnakeCase(a[i])
+	}
+	return strings.Join(a, ".")
+}
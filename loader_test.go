@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"flag"
+	"os"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoader(t *testing.T) {
+
+	type cfgX struct {
+		Name    string
+		Debug   bool
+		Retries int
+		Server  struct {
+			Host     string
+			MaxConns int
+		}
+	}
+
+	tempfile1 := createTempFile("GOTEST_CONFIG")
+	tempfile2 := createTempFile("GOTEST_CONFIG")
+	defer os.Remove(tempfile1)
+	defer os.Remove(tempfile2)
+	writeFile(tempfile1, []byte("Name = Rick\nserver {\n  host = localhost\n  max_conns = 10\n}\n"))
+	writeFile(tempfile2, []byte("Server.Host = citadel\n"))
+
+	Convey("Load defaults, files, environment and flags", t, func() {
+		os.Setenv("GOTEST_SERVER_MAX_CONNS", "20")
+		defer os.Unsetenv("GOTEST_SERVER_MAX_CONNS")
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Bool("debug", false, "")
+		fs.Int("server.max_conns", 0, "")
+		fs.Int("retries", 0, "")
+		So(fs.Parse([]string{"-debug", "-server.max_conns=30"}), ShouldBeNil)
+
+		x := cfgX{Retries: 3}
+		l := NewLoader(ALLOW_SNAKE_CASE)
+		l.AddFile(tempfile1).AddFile(tempfile2).AddEnv("GOTEST_").AddFlags(fs)
+		err := l.Load(&x)
+		So(err, ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+		So(x.Debug, ShouldBeTrue)
+		So(x.Retries, ShouldEqual, 3)
+		So(x.Server.Host, ShouldEqual, "citadel")
+		So(x.Server.MaxConns, ShouldEqual, 30)
+
+		So(l.Source("Name"), ShouldEqual, tempfile1)
+		So(l.Source("Retries"), ShouldEqual, "default")
+		So(l.Source("Server.Host"), ShouldEqual, tempfile2)
+		So(l.Source("Server.MaxConns"), ShouldEqual, "flag -server.max_conns")
+		So(l.Source("Debug"), ShouldEqual, "flag -debug")
+	})
+
+	Convey("Environment overrides files", t, func() {
+		os.Setenv("GOTEST_SERVER_MAX_CONNS", "20")
+		defer os.Unsetenv("GOTEST_SERVER_MAX_CONNS")
+		var x cfgX
+		l := NewLoader(ALLOW_SNAKE_CASE).AddFile(tempfile1).AddEnv("GOTEST_")
+		So(l.Load(&x), ShouldBeNil)
+		So(x.Server.MaxConns, ShouldEqual, 20)
+		So(l.Source("Server.MaxConns"), ShouldEqual, "env GOTEST_SERVER_MAX_CONNS")
+	})
+
+	Convey("Decrypt and verify file layers, and report their warnings", t, func() {
+		c, _ := NewAESGCM([]byte("0123456789abcdef"))
+		tempfile3 := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile3)
+		bs, _ := c.Encrypt(AppendChecksum([]byte("Name = Morty\nName = Summer\n")))
+		writeFile(tempfile3, bs)
+
+		var x cfgX
+		l := NewLoader(LAST_KEY_WINS).WithCipher(c).WithVerifier(VerifySHA256).AddFile(tempfile3)
+		So(l.Load(&x), ShouldBeNil)
+		So(x.Name, ShouldEqual, "Summer")
+		So(l.Warnings(), ShouldHaveLength, 1)
+		So(l.Warnings()[0].String(), ShouldEqual, tempfile3+":2: Name: Duplicate key")
+
+		err := NewLoader().WithVerifier(VerifySHA256).AddFile(tempfile1).Load(&x)
+		So(err.Error(), ShouldEqual, "No signature found ("+tempfile1+")")
+	})
+
+	Convey("Force error: Missing file", t, func() {
+		var x cfgX
+		err := NewLoader().AddFile("non existent file.conf").Load(&x)
+		So(err, ShouldNotBeNil)
+	})
+
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Locate returns the configuration files for the named application which
+// exist in the standard locations, in order of precedence:
+//
+//	$XDG_CONFIG_HOME/app/app.conf (default ~/.config/app/app.conf)
+//	~/.app.conf
+//	$XDG_CONFIG_DIRS/app/app.conf (default /etc/xdg/app/app.conf)
+//	/etc/app/app.conf
+//	/etc/app.conf
+func Locate(appName string) []string {
+	var found []string
+	for _, f := range searchPaths(appName) {
+		if fi, err := os.Stat(f); err == nil && !fi.IsDir() {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// Return every candidate path searched by Locate.
+func searchPaths(appName string) []string {
+	name := appName + ".conf"
+	home, _ := os.UserHomeDir()
+	var paths []string
+	xdgHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgHome == "" && home != "" {
+		xdgHome = filepath.Join(home, ".config")
+	}
+	if xdgHome != "" {
+		paths = append(paths, filepath.Join(xdgHome, appName, name))
+	}
+	if home != "" {
+		paths = append(paths, filepath.Join(home, "."+name))
+	}
+	xdgDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if xdgDirs == "" {
+		xdgDirs = "/etc/xdg"
+	}
+	for _, dir := range filepath.SplitList(xdgDirs) {
+		if dir != "" {
+			paths = append(paths, filepath.Join(dir, appName, name))
+		}
+	}
+	return append(paths, filepath.Join("/etc", appName, name), filepath.Join("/etc", name))
+}
+
+// DecodeFirst decodes the first of the supplied files which exists into x and
+// returns its name. See Decoder.DecodeFirst.
+func DecodeFirst(x interface{}, paths ...string) (string, error) {
+	return NewDecoder(x).DecodeFirst(paths...)
+}
+
+// DecodeFirst decodes the first of the supplied files which exists and
+// returns its name. If none exist, the error wraps ErrNotExist.
+//
+//	used, err := config.NewDecoder(&cfg).DecodeFirst(config.Locate("app")...)
+func (o *Decoder) DecodeFirst(paths ...string) (string, error) {
+	for _, f := range paths {
+		err := o.DecodeFile(f)
+		if err == nil || !isNotExist(err, f) {
+			return f, err
+		}
+	}
+	return "", fmt.Errorf("%w (%s)", ErrNotExist, strings.Join(paths, ", "))
+}
+
+// DecodeAll decodes every one of the supplied files which exists as one, and
+// returns their names. Keys in earlier files take precedence over the same
+// keys in later files, so that the result of Locate may be supplied directly.
+// If none exist, the error wraps ErrNotExist. Files are locked, decrypted and
+// verified as by DecodeFile.
+func (o *Decoder) DecodeAll(paths ...string) ([]string, error) {
+	o.reset()
+	var used []string
+	fieldMap := make(fMap)
+	for i := len(paths) - 1; i >= 0; i-- {
+		m, err := parseFileFieldMap("", paths[i], o, &includeState{})
+		if isNotExist(err, paths[i]) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range m {
+			fieldMap[k] = v
+		}
+		used = append([]string{paths[i]}, used...)
+	}
+	if len(used) == 0 {
+		return nil, fmt.Errorf("%w (%s)", ErrNotExist, strings.Join(paths, ", "))
+	}
+	o.parser = NewParser()
+	o.fieldMap = fieldMap
+	return used, o.assign()
+}
+
+// Return true if err reports that the named file itself does not exist, as
+// opposed to a file which it includes.
+func isNotExist(err error, filename string) bool {
+	if ne, ok := err.(*notExistError); ok {
+		if pe, ok := ne.err.(*os.PathError); ok {
+			return pe.Path == filename
+		}
+	}
+	return false
+}
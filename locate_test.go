@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocate(t *testing.T) {
+
+	type cfg struct {
+		Name string
+		Port int
+	}
+
+	dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+	defer os.RemoveAll(dir)
+	home := filepath.Join(dir, "home")
+	xdg := filepath.Join(dir, "xdg")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CONFIG_DIRS", xdg)
+	os.MkdirAll(filepath.Join(home, ".config", "gotest"), 0700)
+	os.MkdirAll(filepath.Join(xdg, "gotest"), 0700)
+	user := filepath.Join(home, ".config", "gotest", "gotest.conf")
+	system := filepath.Join(xdg, "gotest", "gotest.conf")
+	writeFile(user, []byte("Name = Rick"))
+	writeFile(system, []byte("Name = Morty\nPort = 80"))
+
+	Convey("Locate existing files in order of precedence", t, func() {
+		So(Locate("gotest"), ShouldResemble, []string{user, system})
+		So(searchPaths("gotest")[1], ShouldEqual, filepath.Join(home, ".gotest.conf"))
+		So(len(Locate("gotest_missing")), ShouldEqual, 0)
+	})
+
+	Convey("Decode the first file found", t, func() {
+		var x cfg
+		used, err := DecodeFirst(&x, filepath.Join(dir, "missing.conf"), system, user)
+		So(err, ShouldBeNil)
+		So(used, ShouldEqual, system)
+		So(x, ShouldResemble, cfg{"Morty", 80})
+	})
+
+	Convey("Merge all files found", t, func() {
+		var x cfg
+		used, err := NewDecoder(&x).DecodeAll(Locate("gotest")...)
+		So(err, ShouldBeNil)
+		So(used, ShouldResemble, []string{user, system})
+		So(x, ShouldResemble, cfg{"Rick", 80})
+	})
+
+	Convey("Merge encrypted files and report their warnings", t, func() {
+		c, _ := NewAESGCM([]byte("0123456789abcdef"))
+		secret := filepath.Join(dir, "secret.conf")
+		defer os.Remove(secret)
+		bs, _ := c.Encrypt([]byte("Port = 81\nPort = 82"))
+		writeFile(secret, bs)
+
+		var x cfg
+		d := NewDecoder(&x, LAST_KEY_WINS).WithCipher(c)
+		used, err := d.DecodeAll(secret, filepath.Join(dir, "missing.conf"))
+		So(err, ShouldBeNil)
+		So(used, ShouldResemble, []string{secret})
+		So(x.Port, ShouldEqual, 82)
+		So(d.Warnings(), ShouldHaveLength, 1)
+		_, err = d.DecodeAll(secret)
+		So(err, ShouldBeNil)
+		So(d.Warnings(), ShouldHaveLength, 1)
+
+		_, err = NewDecoder(&x).DecodeAll(secret)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Force error: No files found", t, func() {
+		var x cfg
+		_, err := DecodeFirst(&x, filepath.Join(dir, "missing.conf"))
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+		_, err = NewDecoder(&x).DecodeAll()
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+	})
+
+}
@@ -0,0 +1,13 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package config
+
+// File locking is not supported on this platform. The LOCK_FILE option has
+// no effect.
+func lockFile(filename string, exclusive bool) (func(), error) {
+	return func() {}, nil
+}
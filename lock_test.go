@@ -0,0 +1,94 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLockFile(t *testing.T) {
+
+	type cfg struct{ Name string }
+
+	Convey("Readers wait for a writer to release its lock", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+
+		unlock, err := lockFile(filename, true)
+		So(err, ShouldBeNil)
+		done := make(chan cfg)
+		go func() {
+			var x cfg
+			DecodeFile(filename, &x, LOCK_FILE)
+			done <- x
+		}()
+		select {
+		case <-done:
+			t.Fatal("decode did not wait for the lock")
+		case <-time.After(20 * time.Millisecond):
+		}
+		writeFile(filename, []byte("Name = Morty\n"))
+		unlock()
+		So((<-done).Name, ShouldEqual, "Morty")
+	})
+
+	Convey("Writers take an exclusive lock", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+
+		unlock, err := lockFile(filename, false)
+		So(err, ShouldBeNil)
+		done := make(chan error)
+		go func() {
+			done <- EncodeToFile(cfg{"Rick"}, filename, LOCK_FILE)
+		}()
+		select {
+		case <-done:
+			t.Fatal("encode did not wait for the lock")
+		case <-time.After(20 * time.Millisecond):
+		}
+		unlock()
+		So(<-done, ShouldBeNil)
+		So(fileExists(filename), ShouldBeTrue)
+	})
+
+	Convey("DecodeFirst skips missing files and directories", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+		missing := filepath.Join(dir, "missing", "app.conf")
+
+		var x cfg
+		used, err := NewDecoder(&x, LOCK_FILE).DecodeFirst(missing, filepath.Join(dir, "other.conf"), filename)
+		So(err, ShouldBeNil)
+		So(used, ShouldEqual, filename)
+		So(x.Name, ShouldEqual, "Rick")
+		So(fileExists(filepath.Join(dir, "other.conf.lock")), ShouldBeFalse)
+	})
+
+	Convey("DecodeFileIfExists leaves no lock file for a missing file", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+
+		x := cfg{"Rick"}
+		So(DecodeFileIfExists(filename, &x, LOCK_FILE), ShouldBeNil)
+		So(fileExists(filename+".lock"), ShouldBeFalse)
+		So(DecodeFileIfExists(filepath.Join(dir, "missing", "app.conf"), &x, LOCK_FILE), ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+	})
+
+}
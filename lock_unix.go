@@ -0,0 +1,34 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+// Take an advisory lock on filename plus ".lock", blocking until it is
+// available. A separate lock file is used because ToFile replaces the target
+// file rather than writing to it. Returns a function to release the lock.
+func lockFile(filename string, exclusive bool) (func(), error) {
+	fh, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err = syscall.Flock(int(fh.Fd()), how); err != nil {
+		fh.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(fh.Fd()), syscall.LOCK_UN)
+		fh.Close()
+	}, nil
+}
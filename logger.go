@@ -0,0 +1,41 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import "log/slog"
+
+// WithLogger sets a logger to which the decoder reports what it does. Files
+// read, includes, skipped fields and decode hooks are logged at debug level,
+// and warnings at warn level. See Decoder.Warnings.
+func (o *Decoder) WithLogger(l *slog.Logger) *Decoder {
+	o.logger = l
+	return o
+}
+
+// WithLogger sets a logger to which the parser reports includes at debug
+// level, and warnings at warn level.
+func (o *Parser) WithLogger(l *slog.Logger) *Parser {
+	o.logger = l
+	return o
+}
+
+func (o *Decoder) debug(msg string, args ...interface{}) {
+	if o.logger != nil {
+		o.logger.Debug(msg, args...)
+	}
+}
+
+func (o *Parser) debug(msg string, args ...interface{}) {
+	if o.logger != nil {
+		o.logger.Debug(msg, args...)
+	}
+}
+
+// Log a warning
+func logWarning(l *slog.Logger, w Warning) {
+	if l != nil {
+		l.Warn(w.Msg, "key", w.Key, "file", w.File, "line", w.Line)
+	}
+}
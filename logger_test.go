@@ -0,0 +1,71 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogger(t *testing.T) {
+
+	newLogger := func(buf *bytes.Buffer) *slog.Logger {
+		return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+	}
+
+	type T struct {
+		Name    string
+		Timeout int `config:",deprecated"`
+		Level   int
+		Secret  string `config:"-"`
+	}
+
+	Convey("Decoder events are logged", t, func() {
+		tempfile1 := createTempFile("GOTEST_CONFIG")
+		tempfile2 := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile1)
+		defer os.Remove(tempfile2)
+		writeFile(tempfile1, []byte("Name = a\nTimeout = 30\ninclude "+tempfile2))
+		writeFile(tempfile2, []byte("Level = high"))
+
+		var buf bytes.Buffer
+		var x T
+		d := NewDecoder(&x).WithLogger(newLogger(&buf))
+		d.WithDecodeHook(func(t reflect.Type, val string) (interface{}, bool, error) {
+			if val == "high" {
+				return 3, true, nil
+			}
+			return nil, false, nil
+		})
+		So(d.DecodeFile(tempfile1), ShouldBeNil)
+		So(x.Level, ShouldEqual, 3)
+		log := buf.String()
+		So(log, ShouldContainSubstring, `level=DEBUG msg="config: reading file" file=`+tempfile1)
+		So(log, ShouldContainSubstring, `level=DEBUG msg="config: include" file=`+tempfile2+` line=3`)
+		So(log, ShouldContainSubstring, `level=DEBUG msg="config: field skipped" field=Secret`)
+		So(log, ShouldContainSubstring, `level=DEBUG msg="config: decode hook" type=int`)
+		So(log, ShouldContainSubstring, `level=WARN msg="Deprecated key" key=Timeout file=`+tempfile1+` line=2`)
+	})
+
+	Convey("Parser warnings are logged", t, func() {
+		var buf bytes.Buffer
+		_, err := NewParser(LAST_KEY_WINS).WithLogger(newLogger(&buf)).Parse([]byte("A = 1\nA = 2"))
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "level=WARN msg=\"Duplicate key\" key=A file=\"\" line=2\n")
+	})
+
+}
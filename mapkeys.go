@@ -0,0 +1,99 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// SetMapKeyFunc sets a function to canonicalize the keys of maps when
+// decoding, eg. http.CanonicalHeaderKey, so that keys which differ only in
+// case become one map entry. It replaces the lower case of the
+// FOLD_MAP_KEYS option. Keys in one source which become the same key are
+// reported as a conflict.
+func (o *Decoder) SetMapKeyFunc(fn func(key string) string) *Decoder {
+	o.mapKeyFn = fn
+	return o
+}
+
+// Return the function which canonicalizes map keys, or nil.
+func (o *Decoder) mapKeyFunc() func(string) string {
+	if o.mapKeyFn != nil {
+		return o.mapKeyFn
+	}
+	if isOption(FOLD_MAP_KEYS, o.options) {
+		return toLower
+	}
+	return nil
+}
+
+// Canonicalize the map keys of the field map under a prefix, which is empty
+// or ends with a dot. Only the first segment of each key is a map key for a
+// map of structs. Every segment is a map key otherwise.
+func (o *Decoder) foldMapKeys(prefix string, all bool) error {
+	fn := o.mapKeyFunc()
+	if fn == nil {
+		return nil
+	}
+	var keys []string
+	for k := range o.fieldMap {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := o.fieldMap[keys[i]], o.fieldMap[keys[j]]
+		if a.no != b.no {
+			return a.no < b.no
+		}
+		return keys[i] < keys[j]
+	})
+	moved := make(map[string]string)
+	for _, k := range keys {
+		rest := k[len(prefix):]
+		key := prefix + foldKey(fn, rest, all)
+		if key == k {
+			continue
+		}
+		vs := o.fieldMap[k]
+		if ovs, ok := o.fieldMap[key]; ok {
+			other := key[len(prefix):]
+			if orig, ok := moved[key]; ok {
+				other = orig
+			}
+			// report the later of the two keys
+			if ovs.no > vs.no {
+				return o.fail(key, "Key conflict ("+rest+", "+other+")", ovs.no)
+			}
+			return o.fail(k, "Key conflict ("+other+", "+rest+")", vs.no)
+		}
+		delete(o.fieldMap, k)
+		o.fieldMap[key] = vs
+		moved[key] = rest
+	}
+	return nil
+}
+
+// Apply a canonicalization function to the segments of a dotted key, or to
+// its first segment only.
+func foldKey(fn func(string) string, k string, all bool) string {
+	var a []string
+	for {
+		i := keyIndex(k)
+		seg := k
+		if i >= 0 {
+			seg = k[:i]
+		}
+		a = append(a, keySegment(fn(unquoteKey(seg))))
+		if i < 0 {
+			return strings.Join(a, ".")
+		}
+		if !all {
+			return strings.Join(a, ".") + k[i:]
+		}
+		k = k[i+1:]
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net/http"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFoldMapKeys(t *testing.T) {
+
+	type server struct{ Host string; Port int }
+	type T struct {
+		Hosts   map[string]string
+		Servers map[string]server
+		Extra   map[string]interface{}
+	}
+
+	Convey("Fold the case of map keys", t, func() {
+		var x T
+		d := NewDecoder(&x, FOLD_MAP_KEYS)
+		err := d.DecodeString(`
+			Hosts { Web = a }
+			Servers {
+				Web { Host = b }
+				web { Port = 80 }
+			}
+			Extra { Key { Sub = 1 } }
+		`)
+		So(err, ShouldBeNil)
+		So(x.Hosts, ShouldResemble, map[string]string{"web": "a"})
+		So(x.Servers, ShouldResemble, map[string]server{"web": {"b", 80}})
+		So(x.Extra, ShouldResemble, map[string]interface{}{"key": map[string]interface{}{"sub": int64(1)}})
+
+		Convey("Later sources override entries in another case", func() {
+			So(d.DecodeString("Hosts { WEB = c }"), ShouldBeNil)
+			So(x.Hosts, ShouldResemble, map[string]string{"web": "c"})
+		})
+	})
+
+	Convey("Canonicalize map keys with a function", t, func() {
+		var m map[string]string
+		x := struct{ Headers map[string]string }{m}
+		d := NewDecoder(&x).SetMapKeyFunc(http.CanonicalHeaderKey)
+		So(d.DecodeString("Headers {\n  content-type = a\n  X-REQUEST-ID = b\n}"), ShouldBeNil)
+		So(x.Headers, ShouldResemble, map[string]string{"Content-Type": "a", "X-Request-Id": "b"})
+	})
+
+	Convey("Force error: Keys which differ only in case", t, func() {
+		var x T
+		err := Decode(&x, "Hosts {\n  Web = a\n  web = b\n}", FOLD_MAP_KEYS)
+		So(err.Error(), ShouldEqual, "Key conflict (Web, web) at line 3")
+	})
+
+}
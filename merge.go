@@ -0,0 +1,119 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"runtime"
+	"sync"
+)
+
+// Merge copies every key of each overlay into dst, in order, so that later
+// overlays take precedence over earlier ones. Keys inside brace blocks are
+// merged individually. If dst is nil, a new map is allocated.
+func Merge(dst *StringMap, overlays ...StringMap) {
+	if *dst == nil {
+		*dst = make(StringMap)
+	}
+	for _, m := range overlays {
+		for k, v := range m {
+			(*dst)[k] = v
+		}
+	}
+}
+
+// DecodeMerged parses each source (a string, byte slice or io.Reader) and
+// decodes the combined result into the supplied struct or map. Keys in later
+// sources override the same keys in earlier sources, including keys inside
+// brace blocks. Decoder options are optional.
+func DecodeMerged(x interface{}, srcs []interface{}, options ...int) error {
+	return NewDecoder(x, options...).DecodeMerged(srcs...)
+}
+
+// DecodeMerged will accept several sources, each a string, byte slice or
+// io.Reader, and decode them as one. Keys in later sources override the same
+// keys in earlier sources.
+func (o *Decoder) DecodeMerged(srcs ...interface{}) error {
+	o.reset()
+	fieldMap := make(fMap)
+	for _, src := range srcs {
+		o.parser = o.newParser()
+		o.parser.reader = bufio.NewReader(toReader(src))
+		m, err := o.parser.parse()
+		o.warnings = append(o.warnings, o.parser.warnings...)
+		o.trace = append(o.trace, o.parser.trace...)
+		if err != nil {
+			return err
+		}
+		for k, v := range m {
+			fieldMap[k] = v
+		}
+	}
+	o.fieldMap = fieldMap
+	return o.assign()
+}
+
+// DecodeFiles parses the supplied files in parallel and decodes the combined
+// result into the supplied struct or map. See Decoder.DecodeFiles. Decoder
+// options are optional.
+func DecodeFiles(files []string, x interface{}, options ...int) error {
+	return NewDecoder(x, options...).DecodeFiles(files...)
+}
+
+// DecodeFiles parses the supplied files, and the files they include, in
+// parallel, and decodes them as one. Keys in later files override the same
+// keys in earlier files, regardless of the order in which the files are
+// parsed. Each file is parsed independently, so a file may not reference
+// the keys of another. Errors, warnings and trace entries are reported in the
+// order of the files. Files are locked, decrypted and verified as by
+// DecodeFile.
+//
+//	files, _ := filepath.Glob("/etc/app/conf.d/*.conf")
+//	err := config.NewDecoder(&cfg).DecodeFiles(files...)
+func (o *Decoder) DecodeFiles(files ...string) error {
+	o.reset()
+	maps := make([]fMap, len(files))
+	errs := make([]error, len(files))
+	warnings := make([][]Warning, len(files))
+	traces := make([][]TraceEntry, len(files))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, f := range files {
+		wg.Add(1)
+		go func(i int, f string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// a copy of the decoder, so that each file has its own references
+			d := *o
+			d.refs = copyRefs(o.refs, nil)
+			maps[i], errs[i] = parseFileFieldMap("", f, &d, &includeState{})
+			warnings[i], traces[i] = d.warnings, d.trace
+		}(i, f)
+	}
+	wg.Wait()
+	for i := range files {
+		o.warnings = append(o.warnings, warnings[i]...)
+		o.trace = append(o.trace, traces[i]...)
+	}
+	var all []error
+	for _, err := range errs {
+		if err != nil {
+			all = append(all, err)
+		}
+	}
+	if len(all) > 0 {
+		return getErrors(all)
+	}
+	fieldMap := make(fMap)
+	for _, m := range maps {
+		for k, v := range m {
+			fieldMap[k] = v
+		}
+	}
+	o.parser = NewParser()
+	o.fieldMap = fieldMap
+	return o.assign()
+}
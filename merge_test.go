@@ -0,0 +1,166 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMerge(t *testing.T) {
+
+	Convey("Merge string maps with precedence", t, func() {
+		var m StringMap
+		Merge(&m,
+			StringMap{"Name": "Rick", "Server.Host": "localhost", "Server.Port": "80"},
+			StringMap{"Server.Host": "citadel"},
+			StringMap{"Server.Port": "8080"},
+		)
+		So(m, ShouldResemble, StringMap{
+			"Name":        "Rick",
+			"Server.Host": "citadel",
+			"Server.Port": "8080",
+		})
+	})
+
+}
+
+func TestDecodeMerged(t *testing.T) {
+
+	type cfgX struct {
+		Name   string
+		Server struct {
+			Host string
+			Port int
+		}
+	}
+
+	defaults := `
+		Name = Rick
+		Server {
+			Host = localhost
+			Port = 80
+		}
+	`
+	site := []byte(`
+		Server {
+			Host = citadel
+		}
+	`)
+	host := strings.NewReader("Server.Port = 8080")
+
+	Convey("Later sources override earlier sources key by key", t, func() {
+		var x cfgX
+		err := DecodeMerged(&x, []interface{}{defaults, site, host})
+		So(err, ShouldBeNil)
+		So(x.Name, ShouldEqual, "Rick")
+		So(x.Server.Host, ShouldEqual, "citadel")
+		So(x.Server.Port, ShouldEqual, 8080)
+	})
+
+	Convey("The state of a previous decode is reset", t, func() {
+		var x cfgX
+		d := NewDecoder(&x, LAST_KEY_WINS).SetTrace(true)
+		So(d.DecodeString("Server.Host = h\nName = a\nName = b\nServer.Port = many"), ShouldNotBeNil)
+		So(d.DecodeMerged(defaults, "Name = x\nName = y"), ShouldBeNil)
+		So(x.Name, ShouldEqual, "y")
+		So(d.Warnings(), ShouldHaveLength, 1)
+		So(d.Warnings()[0].Line, ShouldEqual, 2)
+		So(d.Trace(), ShouldNotBeEmpty)
+		So(d.Trace()[0].Key, ShouldEqual, "Name")
+	})
+
+	Convey("Force error: Parse error in one source", t, func() {
+		var x cfgX
+		err := DecodeMerged(&x, []interface{}{defaults, "Key1={"})
+		So(err, ShouldNotBeNil)
+	})
+
+}
+
+func TestDecodeFiles(t *testing.T) {
+
+	type T struct {
+		Name  string
+		Ports map[string]int
+	}
+
+	var files []string
+	for i := 0; i < 20; i++ {
+		f := createTempFile("GOTEST_CONFIG")
+		writeFile(f, []byte(fmt.Sprintf("Name = file%d\nPorts.p%d = %d", i, i, 8000+i)))
+		files = append(files, f)
+	}
+	defer func() {
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}()
+
+	Convey("Files are merged in order", t, func() {
+		var x T
+		So(DecodeFiles(files, &x), ShouldBeNil)
+		So(x.Name, ShouldEqual, "file19")
+		So(x.Ports, ShouldHaveLength, 20)
+		So(x.Ports["p7"], ShouldEqual, 8007)
+	})
+
+	Convey("Files do not share references", t, func() {
+		writeFile(files[1], []byte("Name = ${Ports.p0}"))
+		var x T
+		err := DecodeFiles(files[:2], &x)
+		So(err.Error(), ShouldEqual, files[1]+":1: Undefined reference (Ports.p0)")
+	})
+
+	Convey("The traces of the files are kept in the order of the files", t, func() {
+		var x T
+		d := NewDecoder(&x).SetTrace(true)
+		So(d.DecodeFiles(files[2], files[0]), ShouldBeNil)
+		var keys []string
+		for _, e := range d.Trace() {
+			if e.Rule != "assign" {
+				keys = append(keys, e.Key)
+			}
+		}
+		So(keys, ShouldResemble, []string{"Name", "Ports.p2", "Name", "Ports.p0"})
+	})
+
+	Convey("Force error: Errors are reported in the order of the files", t, func() {
+		writeFile(files[3], []byte("Name = a\nName = b"))
+		writeFile(files[5], []byte("Name = a\nPorts {"))
+		var x T
+		err := DecodeFiles(files, &x)
+		So(err.Error(), ShouldEqual, files[1]+":1: Undefined reference (Ports.p0)\n"+files[3]+":2: Duplicate key\n"+files[5]+":2: Missing closing brace")
+	})
+
+	Convey("Files are decrypted and report warnings", t, func() {
+		c, _ := NewAESGCM([]byte("0123456789abcdef"))
+		for i, src := range []string{"Name = a\nName = b", "Ports.p1 = 1\nPorts.p1 = 2"} {
+			bs, _ := c.Encrypt([]byte(src))
+			writeFile(files[i], bs)
+		}
+		var x T
+		d := NewDecoder(&x, LAST_KEY_WINS).WithCipher(c)
+		So(d.DecodeFiles(files[:2]...), ShouldBeNil)
+		So(x.Name, ShouldEqual, "b")
+		So(x.Ports["p1"], ShouldEqual, 2)
+		So(d.Warnings(), ShouldHaveLength, 2)
+		So(d.Warnings()[0].File, ShouldEqual, files[0])
+		So(d.Warnings()[1].File, ShouldEqual, files[1])
+	})
+
+	Convey("Force error: Files are verified", t, func() {
+		writeFile(files[0], AppendChecksum([]byte("Name = a\n")))
+		writeFile(files[1], []byte("Name = b\n"))
+		var x T
+		d := NewDecoder(&x).WithVerifier(VerifySHA256)
+		So(d.DecodeFiles(files[0]), ShouldBeNil)
+		So(d.DecodeFiles(files[:2]...).Error(), ShouldEqual, "No signature found ("+files[1]+")")
+	})
+
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Metadata describes how the keys of a source were matched to the fields of
+// a struct by the most recent decode. All keys are dotted and sorted.
+type Metadata struct {
+	Keys      []string // Keys in the source which were assigned to a field
+	Missing   []string // Fields which were never mentioned in the source
+	Defaulted []string // Missing fields which retained a non-zero value
+}
+
+// Metadata returns a report of the keys consumed by the most recent decode,
+// and the struct fields it left untouched. This may be used to warn about
+// stale configuration or to confirm which defaults are in effect.
+func (o *Decoder) Metadata() Metadata {
+	m := o.meta
+	m.Keys = nil
+	for k, v := range o.fieldMap {
+		if v.isDefined {
+			m.Keys = append(m.Keys, k)
+		}
+	}
+	sort.Strings(m.Keys)
+	sort.Strings(m.Missing)
+	sort.Strings(m.Defaulted)
+	return m
+}
+
+// Record a field which was not found in the source.
+func (o *Decoder) missing(v1 reflect.Value, key string) {
+	o.meta.Missing = append(o.meta.Missing, key)
+	if v1.IsValid() && !isZeroStruct(v1) {
+		o.meta.Defaulted = append(o.meta.Defaulted, key)
+	}
+}
+
+// WasSet reports whether a field, or any field within a struct or map, was
+// assigned a value by any source decoded by this Decoder, including a null
+// value. Keys are dotted field names, eg. Server.Port, regardless of the
+// case used in the source. This distinguishes a value configured as zero
+// from one which is absent, without the use of pointer fields.
+func (o *Decoder) WasSet(key string) bool {
+	if o.set[key] {
+		return true
+	}
+	for k := range o.set {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Record a field which was assigned a value.
+func (o *Decoder) markSet(key string) {
+	if o.set == nil {
+		o.set = make(map[string]bool)
+	}
+	o.set[key] = true
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetadata(t *testing.T) {
+
+	type server struct {
+		Host    string
+		Port    int
+		Started time.Time
+	}
+	type cfg struct {
+		Name   string
+		Debug  bool
+		Server server
+		Labels map[string]string
+	}
+
+	src := `
+		Name = Rick
+		Server {
+			Host = citadel
+		}
+		Labels {
+			Team = science
+		}
+	`
+
+	Convey("Report consumed, missing and defaulted keys", t, func() {
+		x := cfg{Debug: true, Server: server{Port: 8080}}
+		o := NewDecoder(&x)
+		So(o.DecodeString(src), ShouldBeNil)
+		m := o.Metadata()
+		So(m.Keys, ShouldResemble, []string{"Labels.Team", "Name", "Server.Host"})
+		So(m.Missing, ShouldResemble, []string{"Debug", "Server.Port", "Server.Started"})
+		So(m.Defaulted, ShouldResemble, []string{"Debug", "Server.Port"})
+	})
+
+	Convey("Match keys using decoder options", t, func() {
+		var x cfg
+		o := NewDecoder(&x, ALLOW_SNAKE_CASE)
+		So(o.DecodeString("name = Rick\ndebug = on"), ShouldBeNil)
+		m := o.Metadata()
+		So(m.Keys, ShouldResemble, []string{"debug", "name"})
+		So(len(m.Defaulted), ShouldEqual, 0)
+	})
+
+}
+
+func TestWasSet(t *testing.T) {
+
+	type T struct {
+		Name   string
+		Port   int
+		Server struct {
+			Host string
+			Port int
+		}
+		Tags  map[string]string
+		Extra map[string]interface{}
+	}
+
+	Convey("Report the fields which were set", t, func() {
+		var x T
+		x.Port = 80
+		d := NewDecoder(&x, ALLOW_SNAKE_CASE)
+		So(d.DecodeString("port = 0\nserver { port = 0 }\ntags { a = 1 }"), ShouldBeNil)
+		So(d.WasSet("Port"), ShouldBeTrue)
+		So(d.WasSet("Name"), ShouldBeFalse)
+		So(d.WasSet("Server"), ShouldBeTrue)
+		So(d.WasSet("Server.Port"), ShouldBeTrue)
+		So(d.WasSet("Server.Host"), ShouldBeFalse)
+		So(d.WasSet("Tags.a"), ShouldBeTrue)
+		So(d.WasSet("Extra"), ShouldBeFalse)
+
+		Convey("Successive sources add to the fields which were set", func() {
+			So(d.DecodeString("name = ~\nextra { b = 2 }"), ShouldBeNil)
+			So(d.WasSet("Name"), ShouldBeTrue)
+			So(d.WasSet("Extra.b"), ShouldBeTrue)
+			So(d.WasSet("Port"), ShouldBeTrue)
+		})
+	})
+
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import "strings"
+
+// A NameMapper derives a key from the name of a struct field, eg. a function
+// which returns max-conns for MaxConns. It is applied to each segment of a
+// dotted key.
+type NameMapper func(name string) string
+
+// SetNameMapper sets a function to derive keys from field names. The decoder
+// tries the mapped key after the field name itself, and before the keys of
+// the case options.
+func (o *Decoder) SetNameMapper(fn NameMapper) *Decoder {
+	o.mapper = fn
+	return o
+}
+
+// SetNameMapper sets a function to derive keys from field names. It replaces
+// the case options of the encoder.
+func (o *Encoder) SetNameMapper(fn NameMapper) *Encoder {
+	o.mapper = fn
+	return o
+}
+
+// Apply a name mapper to each segment of a dotted key. Quoted segments are
+// not changed.
+func mapKey(fn NameMapper, k string) string {
+	var a []string
+	for {
+		i := keyIndex(k)
+		seg := k
+		if i >= 0 {
+			seg = k[:i]
+		}
+		if !strings.HasPrefix(seg, `"`) {
+			seg = fn(seg)
+		}
+		a = append(a, seg)
+		if i < 0 {
+			return strings.Join(a, ".")
+		}
+		k = k[i+1:]
+	}
+}
+
+// The case options of the decoder, in their default order of precedence
+var keyForms = []int{ALLOW_SNAKE_CASE, ALLOW_KEBAB_CASE, ALLOW_SCREAMING_SNAKE_CASE, IGNORE_CASE}
+
+// SetKeyPrecedence sets the order in which the keys of the case options are
+// tried when a field name is not found in the source, eg.
+// SetKeyPrecedence(IGNORE_CASE, ALLOW_SNAKE_CASE). The listed options are
+// enabled. Other enabled case options are tried afterward in the default
+// order: ALLOW_SNAKE_CASE, ALLOW_KEBAB_CASE, ALLOW_SCREAMING_SNAKE_CASE and
+// IGNORE_CASE. The field name itself, then the key of a NameMapper, are
+// always tried first.
+func (o *Decoder) SetKeyPrecedence(options ...int) *Decoder {
+	o.precedence = nil
+	for _, opt := range options {
+		if !isKeyForm(opt) {
+			panic("Option not allowed")
+		}
+		o.options |= opt
+		o.precedence = append(o.precedence, opt)
+	}
+	return o
+}
+
+func isKeyForm(opt int) bool {
+	for _, f := range keyForms {
+		if opt == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the key of a field in one of the case forms
+func keyForm(opt int, k string) string {
+	switch opt {
+	case ALLOW_SNAKE_CASE:
+		return toSnakeCase(k)
+	case ALLOW_KEBAB_CASE:
+		return toKebabCase(k)
+	case ALLOW_SCREAMING_SNAKE_CASE:
+		return toScreamingSnakeCase(k)
+	}
+	return toLower(k)
+}
+
+// Return the keys under which a dotted field key may appear in the source,
+// in the order they are tried.
+func (o *Decoder) keyVariants(k string) []string {
+	a := []string{k}
+	if o.mapper != nil {
+		a = append(a, mapKey(o.mapper, k))
+	}
+	forms := append([]int{}, o.precedence...)
+	for _, f := range keyForms {
+		if isOption(f, o.options) && !containsInt(forms, f) {
+			forms = append(forms, f)
+		}
+	}
+	for _, f := range forms {
+		a = append(a, keyForm(f, k))
+	}
+	return a
+}
+
+func containsInt(a []int, n int) bool {
+	for _, x := range a {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
+
+// With the STRICT_KEYS option, report a field which is given a value under
+// more than one of its keys, eg. MaxConns and max_conns.
+func (o *Decoder) checkAmbiguous(k string) error {
+	if !isOption(STRICT_KEYS, o.options) {
+		return nil
+	}
+	var found []string
+	var no int
+	for _, key := range o.keyVariants(k) {
+		if vs, ok := o.fieldMap[key]; ok && !containsString(found, key) {
+			found = append(found, key)
+			if vs.no > no {
+				no = vs.no
+			}
+		}
+	}
+	if len(found) > 1 {
+		return o.fail(k, "Ambiguous key ("+strings.Join(found, ", ")+")", no)
+	}
+	return nil
+}
+
+func containsString(a []string, s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the prefix of the keys of a map field in the source: the first key
+// variant which has keys within it.
+func (o *Decoder) mapPrefix(parent_key string) string {
+	variants := o.keyVariants(parent_key)
+	for _, p := range variants {
+		for k := range o.fieldMap {
+			if strings.HasPrefix(k, p+".") {
+				return p
+			}
+		}
+	}
+	return variants[len(variants)-1]
+}
+
+// Return a key as it is written by the encoder.
+func (o *Encoder) keyCase(k string) string {
+	if o.mapper != nil {
+		return mapKey(o.mapper, k)
+	}
+	return setKeyCase(o.options, k)
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strings"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNameMapper(t *testing.T) {
+
+	camel := func(name string) string {
+		return strings.ToLower(name[:1]) + name[1:]
+	}
+
+	type T struct {
+		MaxConns int
+		Server   struct {
+			HostName string
+		}
+		Tags map[string]string
+	}
+
+	Convey("Decode keys derived by a name mapper", t, func() {
+		var x T
+		d := NewDecoder(&x).SetNameMapper(camel)
+		So(d.DecodeString("maxConns = 10\nserver { hostName = a }\ntags { SomeTag = b }"), ShouldBeNil)
+		So(x.MaxConns, ShouldEqual, 10)
+		So(x.Server.HostName, ShouldEqual, "a")
+		So(x.Tags, ShouldResemble, map[string]string{"SomeTag": "b"})
+
+		Convey("Field names are still accepted", func() {
+			So(d.DecodeString("MaxConns = 11"), ShouldBeNil)
+			So(x.MaxConns, ShouldEqual, 11)
+		})
+	})
+
+	Convey("Encode keys derived by a name mapper", t, func() {
+		x := T{MaxConns: 10}
+		x.Server.HostName = "a"
+		x.Tags = map[string]string{"x y": "b"}
+		var b []byte
+		So(NewEncoder(x, ENCODE_SNAKE_CASE).SetNameMapper(camel).ToBytes(&b), ShouldBeNil)
+		So(string(b), ShouldEqual, "maxConns = 10\nserver = {\n  hostName = a\n}\ntags = {\n  \"x y\" = b\n}\n")
+	})
+
+}
+
+func TestKebabCase(t *testing.T) {
+
+	type T struct {
+		MaxConns int
+		Server   struct {
+			HostName string
+		}
+	}
+
+	Convey("Decode kebab case keys", t, func() {
+		var x T
+		So(Decode(&x, "max-conns = 10\nserver {\n  host-name = a\n}", ALLOW_KEBAB_CASE), ShouldBeNil)
+		So(x.MaxConns, ShouldEqual, 10)
+		So(x.Server.HostName, ShouldEqual, "a")
+	})
+
+	Convey("Encode kebab case keys", t, func() {
+		x := T{MaxConns: 10}
+		x.Server.HostName = "a"
+		b, err := Encode(x, ENCODE_KEBAB_CASE)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "max-conns = 10\nserver = {\n  host-name = a\n}\n")
+		So(toKebabCase("DarkMatter"), ShouldEqual, "dark-matter")
+	})
+
+	Convey("Force error: Kebab case keys are extra without the option", t, func() {
+		var x T
+		So(Decode(&x, "max-conns = 10").Error(), ShouldEqual, "Extra field (max-conns) at line 1")
+	})
+
+}
+
+func TestScreamingSnakeCase(t *testing.T) {
+
+	type T struct {
+		MaxConns int
+		Server   struct {
+			HostName string
+		}
+	}
+
+	Convey("Decode upper snake case keys", t, func() {
+		var x T
+		So(Decode(&x, "MAX_CONNS = 10\nSERVER {\n  HOST_NAME = a\n}", ALLOW_SCREAMING_SNAKE_CASE), ShouldBeNil)
+		So(x.MaxConns, ShouldEqual, 10)
+		So(x.Server.HostName, ShouldEqual, "a")
+	})
+
+	Convey("Encode upper snake case keys", t, func() {
+		x := T{MaxConns: 10}
+		x.Server.HostName = "a"
+		b, err := Encode(x, ENCODE_SCREAMING_SNAKE_CASE)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "MAX_CONNS = 10\nSERVER = {\n  HOST_NAME = a\n}\n")
+		So(toScreamingSnakeCase("DarkMatter"), ShouldEqual, "DARK_MATTER")
+	})
+
+}
+
+func TestKeyPrecedence(t *testing.T) {
+
+	type T struct {
+		MaxConns int
+	}
+
+	src := "maxconns = 1\nmax_conns = 2"
+
+	Convey("Case options are tried in the default order", t, func() {
+		var x T
+		err := Decode(&x, src, ALLOW_SNAKE_CASE|IGNORE_CASE)
+		So(err.Error(), ShouldEqual, "Extra field (maxconns) at line 1")
+		So(x.MaxConns, ShouldEqual, 2)
+	})
+
+	Convey("Case options are tried in the order set", t, func() {
+		var x T
+		d := NewDecoder(&x).SetKeyPrecedence(IGNORE_CASE, ALLOW_SNAKE_CASE)
+		So(d.DecodeString(src).Error(), ShouldEqual, "Extra field (max_conns) at line 2")
+		So(x.MaxConns, ShouldEqual, 1)
+	})
+
+	Convey("Force panic: Option is not a case option", t, func() {
+		var x T
+		So(func() { NewDecoder(&x).SetKeyPrecedence(SAFE_MODE) }, ShouldPanicWith, "Option not allowed")
+	})
+
+	Convey("Strict keys", t, func() {
+
+		Convey("A single key is accepted", func() {
+			var x T
+			So(Decode(&x, "max_conns = 2", ALLOW_SNAKE_CASE|STRICT_KEYS), ShouldBeNil)
+			So(x.MaxConns, ShouldEqual, 2)
+		})
+
+		Convey("Force error: Field given a value under two keys", func() {
+			var x T
+			err := Decode(&x, "MaxConns = 1\nmax_conns = 2", ALLOW_SNAKE_CASE|STRICT_KEYS)
+			So(err.Error(), ShouldEqual, "Ambiguous key (MaxConns, max_conns) at line 2")
+		})
+
+	})
+
+}
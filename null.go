@@ -0,0 +1,61 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Unquote a value, or return the null value if it is an unquoted ~ or null.
+// A null value explicitly unsets a key: the decoder sets the field to its
+// zero value, a pointer to nil, or deletes a map entry, which is useful in a
+// file which overrides the defaults of an earlier one. Parse returns a null
+// value as an empty string.
+func parseValue(s string) (string, error) {
+	if s == "~" || s == "null" {
+		return null_value, nil
+	}
+	return unquote(s)
+}
+
+// Return true if the value of a key is null, marking the key as defined.
+func (o *Decoder) isNull(key string) bool {
+	if key == "" {
+		return false
+	}
+	vs := o.lookup(key)
+	if vs == nil || vs.val != null_value {
+		return false
+	}
+	vs.isDefined = true
+	return true
+}
+
+// Set a value to its zero value if its key is null. Returns true if it was
+// null.
+func (o *Decoder) setNull(v1 reflect.Value, key string) bool {
+	if !o.isNull(key) {
+		return false
+	}
+	if v1.CanSet() {
+		v1.Set(reflect.Zero(v1.Type()))
+	}
+	o.markSet(key)
+	return true
+}
+
+// Return true if the field map contains a key, or a key within a block.
+func (o *Decoder) hasKey(key string) bool {
+	if o.lookup(key) != nil {
+		return true
+	}
+	for k := range o.fieldMap {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
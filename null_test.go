@@ -0,0 +1,68 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNullValues(t *testing.T) {
+
+	type server struct{ Port int }
+	type T struct {
+		Name    string
+		Port    int
+		Timeout *int
+		Server  server
+		Tags    map[string]string
+		Servers map[string]server
+		Extra   map[string]interface{}
+	}
+
+	defaults := `
+		Name = app
+		Port = 80
+		Timeout = 30
+		Server { Port = 81 }
+		Tags { a = 1; b = 2 }
+		Servers {
+			web { Port = 82 }
+			api { Port = 83 }
+		}
+		Extra { x = 1; y { z = 2 } }
+	`
+
+	Convey("Null values unset fields and map entries", t, func() {
+		var x T
+		d := NewDecoder(&x)
+		So(d.DecodeString(defaults), ShouldBeNil)
+		So(*x.Timeout, ShouldEqual, 30)
+		err := d.DecodeString(`
+			Name = ~
+			Port = null
+			Timeout = ~
+			Server = ~
+			Tags { a = ~ }
+			Servers { api = null }
+			Extra { x = ~; y { z = ~; n = "null" } }
+		`)
+		So(err, ShouldBeNil)
+		So(x.Name, ShouldEqual, "")
+		So(x.Port, ShouldEqual, 0)
+		So(x.Timeout, ShouldBeNil)
+		So(x.Server, ShouldResemble, server{})
+		So(x.Tags, ShouldResemble, map[string]string{"b": "2"})
+		So(x.Servers, ShouldResemble, map[string]server{"web": {82}})
+		So(x.Extra, ShouldResemble, map[string]interface{}{"y": map[string]interface{}{"n": "null"}})
+	})
+
+	Convey("Parse null values as empty strings", t, func() {
+		m, err := Parse("A = ~\nB = \"~\"")
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"A": "", "B": "~"})
+	})
+
+}
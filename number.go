@@ -0,0 +1,69 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// A Number holds a numeric value exactly as it was written in the
+// configuration, eg. 10K or 3.14159265358979323846, so that the caller may
+// decide how to convert it. A Number is encoded as it was decoded.
+type Number string
+
+var numberType = reflect.TypeOf(Number(""))
+
+// String returns the number as it was written.
+func (n Number) String() string {
+	return string(n)
+}
+
+// Int64 returns the number as an int64. Abbreviations such as 10K are allowed.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(iFix(string(n)), 10, 64)
+}
+
+// Float64 returns the number as a float64. Abbreviations such as 2.5K are
+// allowed.
+func (n Number) Float64() (float64, error) {
+	return floatFix(string(n), 64)
+}
+
+// BigInt returns the number as a big.Int. Abbreviations such as 10K are
+// allowed.
+func (n Number) BigInt() (*big.Int, error) {
+	i, ok := new(big.Int).SetString(iFix(string(n)), 10)
+	if !ok {
+		return nil, errors.New("Invalid numeric value")
+	}
+	return i, nil
+}
+
+// Assign a Number, which must be an integer, float or fraction, with or
+// without an abbreviation.
+func set_number(v1 reflect.Value, val string) error {
+	n := Number(val)
+	if _, err := n.Float64(); err != nil || val == "" {
+		if _, ok := new(big.Rat).SetString(iFix(val)); !ok {
+			return errors.New("Invalid numeric value")
+		}
+	}
+	v1.SetString(val)
+	return nil
+}
+
+func (o *Encoder) encodeNumber(v1 reflect.Value, depth int, parent_key string) {
+	s := v1.String()
+	if s == "" {
+		if !o.isOption(ENCODE_ZERO_VALUES) {
+			return
+		}
+		s = "0"
+	}
+	o.write_kv(depth, parent_key, s)
+}
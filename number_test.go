@@ -0,0 +1,61 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNumber(t *testing.T) {
+
+	type cfg struct {
+		Limit  Number
+		Rate   Number
+		Supply Number
+		Empty  Number
+	}
+
+	src := "Limit = 10K\nRate = 3.14159265358979323846\nSupply = 123456789012345678901234567890\n"
+
+	Convey("Decode numbers exactly as written", t, func() {
+		var x cfg
+		So(Decode(&x, src), ShouldBeNil)
+		So(x.Limit.String(), ShouldEqual, "10K")
+		So(x.Rate, ShouldEqual, Number("3.14159265358979323846"))
+		i, err := x.Limit.Int64()
+		So(err, ShouldBeNil)
+		So(i, ShouldEqual, 10000)
+		f, err := x.Rate.Float64()
+		So(err, ShouldBeNil)
+		So(f, ShouldEqual, 3.141592653589793)
+		b, err := x.Supply.BigInt()
+		So(err, ShouldBeNil)
+		So(b.String(), ShouldEqual, "123456789012345678901234567890")
+		_, err = x.Supply.Int64()
+		So(err, ShouldNotBeNil)
+		_, err = x.Rate.BigInt()
+		So(err.Error(), ShouldEqual, "Invalid numeric value")
+	})
+
+	Convey("Encode numbers exactly as decoded", t, func() {
+		var x cfg
+		So(Decode(&x, src), ShouldBeNil)
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, src)
+		b1, err = Encode(cfg{}, ENCODE_ZERO_VALUES)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "Limit = 0\nRate = 0\nSupply = 0\nEmpty = 0\n")
+	})
+
+	Convey("Force error: Values which are not numbers", t, func() {
+		var x cfg
+		err := Decode(&x, "Limit = ten")
+		So(err.Error(), ShouldEqual, "Limit: Invalid numeric value at line 1")
+		So(Decode(&x, "Limit = 10K", STRICT_NUMBERS), ShouldNotBeNil)
+	})
+
+}
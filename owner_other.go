@@ -0,0 +1,12 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package config
+
+import "os"
+
+// File ownership is not copied on this platform.
+func copyOwner(fh *os.File, fi os.FileInfo) {}
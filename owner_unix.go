@@ -0,0 +1,20 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+// Give a new file the owner and group of the file it replaces. Errors are
+// ignored, since only a privileged user may give a file away.
+func copyOwner(fh *os.File, fi os.FileInfo) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		fh.Chown(int(st.Uid), int(st.Gid))
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCopyOwner(t *testing.T) {
+
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a file requires root")
+	}
+
+	Convey("An overwritten file keeps its owner and group", t, func() {
+		dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "app.conf")
+		writeFile(filename, []byte("Name = Rick\n"))
+		So(os.Chown(filename, 1234, 5678), ShouldBeNil)
+		So(EncodeToFile(struct{ Name string }{"Morty"}, filename, OVERWRITE_FILE), ShouldBeNil)
+		fi, _ := os.Stat(filename)
+		st := fi.Sys().(*syscall.Stat_t)
+		So(st.Uid, ShouldEqual, 1234)
+		So(st.Gid, ShouldEqual, 5678)
+	})
+
+}
@@ -6,19 +6,22 @@ package config
 
 import (
 	"io"
-	"os"
 	"fmt"
 	"bufio"
 	"bytes"
 	"errors"
+	"log/slog"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
 	multi_line_width = 80
+	max_encode_depth = 100 // Nesting depth of blocks written by the encoder
+	heredoc_lines    = 3   // Newlines in a string before it is written as a heredoc
 	qt               = "\x22"
 	lf               = "\n"
 	comment        = "comment"
@@ -31,7 +34,14 @@ const (
 	include        = "include"
 	quoted         = "quoted"
 	badkey         = "badkey"
+	profile        = "profile"
+	condition      = "condition"
+	anchor         = "anchor"
+	alias          = "alias"
+	quoted_key     = "quoted_key"
+	inline_block   = "inline_block"
 	nested         = "~NESTED~"
+	null_value     = "~NULL~"
 
 	time_fmt  = "15:04:05"
 	date_fmt  = "2006-01-02"
@@ -50,6 +60,7 @@ type v struct {
 	// given struct.  If this bool has not been set after
 	// decode has completed, it will be considered extra.
 	kind reflect.Kind //
+	src  string       // Name of the source (file name) of the key/value
 }
 
 // The Parser handles parsing input data from a reader.
@@ -60,7 +71,24 @@ type Parser struct {
 	errs     []error
 	fieldMap fMap
 	include  []string
+	includeAs []string // Key prefix of each included file, or empty
+	filename string
 	v        interface{}
+	limits   Limits
+	nkeys    int
+	abort    error
+	heredocBytes int64
+	profile  string
+	overlay  fMap
+	sections int
+	vars     map[string]string
+	refs     map[string]string
+	anchors  map[string]fMap
+	warnings []Warning
+	logger   *slog.Logger
+	tracing  bool
+	trace    []TraceEntry
+	line     []byte
 }
 
 // Type StringMap is the data type output by the Parse function.
@@ -79,15 +107,21 @@ func init() {
 	r := regexp.MustCompile
 	compiledRegexp = rMap{
 		comment:        r(`([^#]*)[#]`),
-		open_brace:     r(`^([\w]+)\s*[=:\s]\s*{`),
+		open_brace:     r(`^([\pL\pN_\-]+)\s*[=:\s]\s*{`),
 		close_brace:    r(`^\s*}`),
-		keyval:         r(`^\s*([\w\.]+)\s*[=:\s]\s*(.+)`), // allow all chars or just chars between quotes
-		heredoc:        r(`^\s*([\w\.]+)\s*[=:\s]\s*<<([\w]+)`),
-		multiline:      r(`^\s*([\w\.]+)\s*[=:\s]\s*(.*)\\$`),
+		keyval:         r(`^\s*([\pL\pN_\-\.]+)\s*[=:\s]\s*(.+)`), // allow all chars or just chars between quotes
+		heredoc:        r(`^\s*([\pL\pN_\-\.]+)\s*[=:\s]\s*<<([\w]+)`),
+		multiline:      r(`^\s*([\pL\pN_\-\.]+)\s*[=:\s]\s*(.*)\\$`),
 		multiline_cont: r(`^\s*([^\\]*)\\$`),
 		quoted:         r(`^"(.+)"\s*$`),
-		include:        r(`^(?i)include +(\"?[^\"=]*)\"?$`),
+		include:        r(`^(?i)include +\"?([^\"=]*?)\"?(?: +as +([\pL\pN_\-\.]+))?$`),
 		badkey:         r(`^\.|\.$|\.\.|^_$`), // match leading dot, trailing dot, adjacent dots, or a single underscore
+		profile:        r(`^@profile\s+([\w\-\.]+)\s*{$`),
+		condition:      r(`^@if\s+(.*?)\s*{$`),
+		quoted_key:     r(`^((?:[\pL\pN_\-]+\.)*"(?:[^"\\]|\\.)*"(?:\.(?:[\pL\pN_\-]+|"(?:[^"\\]|\\.)*"))*)\s*[=:]?\s*(.*)$`),
+		inline_block:   r(`^([\pL\pN_\-]+)\s*[=:\s]\s*{(.*)}$`),
+		anchor:         r(`^&([\w\-]+)\s*{$`),
+		alias:          r(`^([\pL\pN_\-]+)\s*[=:\s]\s*\*([\w\-]+)\s*({)?$`),
 	}
 }
 
@@ -100,11 +134,35 @@ func NewParser(options ...int) *Parser {
 		}
 		o.options = options[0]
 	}
+	if isOption(SAFE_MODE, o.options) {
+		o.limits = SafeLimits
+	}
 	return o
 }
 
 func (o *Parser) allowedOption(option int) bool {
-	return option == option&PARSE_LOWER_CASE
+	return option == option&(PARSE_LOWER_CASE|EXPAND_PATHS|SAFE_MODE|STRICT_NUMBERS|FIRST_KEY_WINS|LAST_KEY_WINS|FAIL_FAST)
+}
+
+// Parser options which the decoder passes to its parser
+const parserOptions = FIRST_KEY_WINS | LAST_KEY_WINS | FAIL_FAST
+
+// Apply the duplicate key policy to a key which is about to be added. Returns
+// false if the key should not be added.
+func (o *Parser) addKey(m fMap, key string, lineno int) bool {
+	if !exists(m, key) {
+		return true
+	}
+	if isOption(LAST_KEY_WINS, o.options) {
+		o.warn(key, "Duplicate key", lineno)
+		return true
+	}
+	if isOption(FIRST_KEY_WINS, o.options) {
+		o.warn(key, "Duplicate key", lineno)
+	} else {
+		o.appendErr(ErrDuplicateKey, lineno)
+	}
+	return false
 }
 
 // Parse a string, a byte slice or an io.Reader to a string map.
@@ -119,24 +177,64 @@ func Parse(src interface{}, options ...int) (StringMap, error) {
 	}
 }
 
+// Return a reader for a string, a byte slice or an io.Reader.
+func toReader(src interface{}) io.Reader {
+	switch reflect.TypeOf(src).Kind() {
+	case reflect.String:
+		return strings.NewReader(src.(string))
+	case reflect.Slice:
+		return bytes.NewReader(src.([]byte))
+	default:
+		return src.(io.Reader)
+	}
+}
+
 // Parse a file
 func ParseFile(filename string, options ...int) (StringMap, error) {
+	return parseFile("", filename, options, &includeState{}, nil)
+}
+
+// Parse a file and the files it includes. from is the name of the including
+// file, or empty.
+func parseFile(from, filename string, options []int, st *includeState, refs map[string]string) (StringMap, error) {
 	var err error
-	f, err := os.Open(filename)
+	o := NewParser(options...)
+	o.refs = refs
+	if isOption(EXPAND_PATHS, o.options) {
+		if filename, err = expandPathVars(filename, o.vars); err != nil {
+			return StringMap{}, err
+		}
+	}
+	f, err := openFile(filename, nil)
 	if err != nil {
 		return StringMap{}, err
 	}
 	defer f.Close()
-	o := NewParser(options...)
-	smap,_ := o.ParseStream(f)
+	o.filename = filename
+	var r io.Reader = f
+	if from != "" {
+		r = st.count(f, filename, o.limits)
+	}
+	smap,_ := o.ParseStream(r)
 	f.Close()
-	for _, fname := range o.include {
-		m,err := ParseFile(fname, options...)
+	if len(o.include) > 0 {
+		refs = copyRefs(refs, smap)
+	}
+	for i, fname := range o.include {
+		if err := st.add(fname, o.limits); err != nil {
+			o.errs = append(o.errs, err)
+			break
+		}
+		m,err := parseFile(filename, fname, options, st, refs)
 		if err != nil {
-			o.appendError("Errors in included file: "+fname+" (\n"+err.Error()+"\n)", 0)
+			o.errs = append(o.errs, fmt.Errorf("Errors in included file: %s (\n%w\n)", fname, err))
+		}
+		prefix := o.includeAs[i]
+		if isOption(PARSE_LOWER_CASE, o.options) {
+			prefix = toLower(prefix)
 		}
 		for k,v := range m {
-			smap[k] = v
+			smap[includeKey(prefix, k)] = v
 		}
 	}
 	return smap, getErrors(o.errs)
@@ -156,14 +254,33 @@ func (o *Parser) ParseStream(r io.Reader) (StringMap, error) {
 		if isOption(PARSE_LOWER_CASE, o.options) {
 			k = toLower(k)
 		}
+		if v.val == null_value {
+			smap[k] = ""
+			continue
+		}
 		smap[k] = v.val
 	}
 	return smap, err
 }
 
 func (o *Parser) parse() (fMap, error) {
+	r, err := gunzip(o.reader)
+	if err != nil {
+		o.errs = append(o.errs, err)
+		return fMap{}, getErrors(o.errs)
+	}
+	o.reader = r
+	if o.limits.MaxBytes > 0 {
+		o.reader = bufio.NewReader(&limitedReader{r: o.reader, max: o.limits.MaxBytes})
+	}
 	vmap, _ := o.recursive_parse(0)
-	if len(vmap) == 0 && len(o.include) == 0 {
+	for k, val := range o.overlay {
+		vmap[k] = val
+	}
+	o.resolveRefs(vmap)
+	if o.abort != nil {
+		o.errs = append(o.errs, o.abort)
+	} else if len(vmap) == 0 && len(o.include) == 0 && o.sections == 0 {
 		o.appendError("Nothing parsed", 0)
 	}
 	return vmap, getErrors(o.errs)
@@ -172,7 +289,8 @@ func (o *Parser) parse() (fMap, error) {
 func (o *Parser) recursive_parse(depth int) (fMap, error) {
 	var s string
 	var err error
-	m := matches{make([]string, 0, 0)}
+	m := getMatches()
+	defer putMatches(m)
 	fieldMap := make(fMap)
 	defer func() {
 		// remove nested placeholders
@@ -183,94 +301,113 @@ func (o *Parser) recursive_parse(depth int) (fMap, error) {
 		}
 	}()
 	for {
+		if o.abort != nil {
+			return fieldMap, nil
+		}
 		s, err = o.nextLine()
 		if err != nil {
 			if err.Error() == "EOF" {
 				err = nil
 				if depth > 0 {
-					return fieldMap, errors.New("Missing closing brace")
+					return fieldMap, ErrMissingBrace
 				}
 
+			} else {
+				o.abort = err
 			}
 			break
 		}
 		switch {
-		case findSubmatch(include, s, &m):
+		case o.match(include, s, m):
+			if m.a[2] != "" && badKey(m.a[2]) {
+				o.appendErr(ErrInvalidKey, o.lineno)
+				continue
+			}
 			o.include = append(o.include, m.a[1])
+			o.includeAs = append(o.includeAs, m.a[2])
+			o.debug("config: include", "file", m.a[1], "line", o.lineno)
 
-		case findSubmatch(open_brace, s, &m):
-			key := m.a[1]
-			lineno := o.lineno
-			// recursive
-			emap, err := o.recursive_parse(depth + 1)
-			if err != nil {
-				o.appendError(err.Error(), lineno)
-				break
-			}
-			if exists(fieldMap, key) {
-				o.appendError("Duplicate key", lineno)
-				break
-			} else {
-				fieldMap[key] = &v{nested, lineno, false, 0}
-			}
-			for k, val := range emap {
-				fieldMap[key+"."+k] = val
-			}
+		case o.match(profile, s, m):
+			o.parseProfile(m.a[1], depth)
+
+		case o.match(condition, s, m):
+			o.parseCondition(fieldMap, m.a[1], depth)
 
-		case findSubmatch(close_brace, s, &m):
+		case o.match(quoted_key, s, m):
+			o.parseQuotedKey(fieldMap, m.a[1], m.a[2], depth)
+
+		case o.match(anchor, s, m):
+			o.parseAnchor(m.a[1], depth)
+
+		case o.match(alias, s, m) && (m.a[3] != "" || o.anchors[m.a[2]] != nil):
+			o.parseAlias(fieldMap, m.a[1], m.a[2], m.a[3] != "", depth)
+
+		case o.match(inline_block, s, m) && isInlineBody(m.a[2]):
+			o.parseInline(fieldMap, m.a[1], m.a[2])
+
+		case !findSubmatch(inline_block, s, m) && o.match(open_brace, s, m):
+			o.parseBlock(fieldMap, m.a[1], depth)
+
+		case o.match(close_brace, s, m):
 			return fieldMap, nil
 
-		case findSubmatch(heredoc, s, &m):
+		case o.match(heredoc, s, m):
 			key := m.a[1]
 			code := m.a[2]
 			val, err := o.readHereDoc(code)
 			if err != nil {
-				o.appendError(err.Error(), o.lineno)
+				if o.abort == nil {
+					o.appendErr(err, o.lineno)
+				}
 				break
 			}
-			if exists(fieldMap, key) {
-				o.appendError("Duplicate key", o.lineno)
+			if !o.addKey(fieldMap, key, o.lineno) {
 				break
 			}
 			val, err = unquote(val)
 			if err != nil {
-				o.appendError(err.Error(), o.lineno)
+				o.appendErr(err, o.lineno)
 				break
 			}
-			fieldMap[key] = &v{val, o.lineno, false, 0}
+			fieldMap[key] = &v{val, o.lineno, false, 0, o.filename}
+			o.traceKey(key, val)
+			o.countKey()
 
-		case findSubmatch(multiline, s, &m):
+		case o.match(multiline, s, m):
 			key := m.a[1]
 			val := m.a[2]
 			val = o.readMultiLine(val)
-			if exists(fieldMap, key) {
-				o.appendError("Duplicate key", o.lineno)
+			if !o.addKey(fieldMap, key, o.lineno) {
 				break
 			}
 			val, err = unquote(val)
 			if err != nil {
-				o.appendError(err.Error(), o.lineno)
+				o.appendErr(err, o.lineno)
 				break
 			}
-			fieldMap[key] = &v{val, o.lineno, false, 0}
+			fieldMap[key] = &v{val, o.lineno, false, 0, o.filename}
+			o.traceKey(key, val)
+			o.countKey()
 
-		case findSubmatch(keyval, s, &m):
+		case o.match(keyval, s, m):
 			key := m.a[1]
 			val := m.a[2]
-			if exists(fieldMap, key) {
-				o.appendError("Duplicate key", o.lineno)
+			if !o.addKey(fieldMap, key, o.lineno) {
 				break
 			}
 			if badKey(key) {
-				o.appendError("Invalid key", o.lineno)
+				o.appendErr(ErrInvalidKey, o.lineno)
 				break
 			}
-			val, err = unquote(val)
+			o.checkQuotes(key, val)
+			val, err = parseValue(val)
 			if err != nil {
-				o.appendError(err.Error(), o.lineno)
+				o.appendErr(err, o.lineno)
 				break
 			}
-			fieldMap[key] = &v{val, o.lineno, false, 0}
+			fieldMap[key] = &v{val, o.lineno, false, 0, o.filename}
+			o.traceKey(key, val)
+			o.countKey()
 
 		default:
 			o.appendError("Invalid data", o.lineno)
@@ -280,50 +417,136 @@ func (o *Parser) recursive_parse(depth int) (fMap, error) {
 	return fieldMap, nil
 }
 
+// Parse a block and add its keys to the field map.
+func (o *Parser) parseBlock(fieldMap fMap, key string, depth int) {
+	lineno := o.lineno
+	if max := o.limits.MaxDepth; max > 0 && depth >= max {
+		o.abort = newError(fmt.Sprintf("Nesting exceeds limit of %d", max), lineno)
+		return
+	}
+	// recursive
+	n := len(o.trace)
+	emap, err := o.recursive_parse(depth + 1)
+	if err != nil {
+		if o.abort == nil {
+			o.appendErr(err, lineno)
+		}
+		return
+	}
+	o.addBlock(fieldMap, key, emap, lineno)
+	o.traceBlock(n, key)
+}
+
+// Add the keys of a block to a field map, prefixed with the block key.
+func (o *Parser) addBlock(fieldMap fMap, key string, emap fMap, lineno int) {
+	// a repeated block is merged if a duplicate key policy is set
+	merge := exists(fieldMap, key) && fieldMap[key].val == nested && o.options&(FIRST_KEY_WINS|LAST_KEY_WINS) != 0
+	if !merge {
+		if !o.addKey(fieldMap, key, lineno) {
+			return
+		}
+		fieldMap[key] = &v{nested, lineno, false, 0, o.filename}
+	}
+	for k, val := range emap {
+		if !exists(fieldMap, key+"."+k) || !isOption(FIRST_KEY_WINS, o.options) {
+			fieldMap[key+"."+k] = val
+		}
+	}
+}
+
+// Discard the includes found since the include list had n entries, when the
+// keys of the block which contained them are discarded.
+func (o *Parser) dropIncludes(n int) {
+	o.include = o.include[:n]
+	o.includeAs = o.includeAs[:n]
+}
+
 func badKey(k string) bool {
-	m := matches{make([]string, 0, 0)}
-	return findSubmatch(badkey, k, &m)
+	return compiledRegexp[badkey].MatchString(k)
+}
+
+// Pool of buffers used to build multi-line values and heredocs
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
 }
 
+func putBuffer(buf *bytes.Buffer) {
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// Pool of matches, so that their slices are reused from one parse to the next
+var matchesPool = sync.Pool{New: func() interface{} { return new(matches) }}
+
+func getMatches() *matches {
+	return matchesPool.Get().(*matches)
+}
+
+func putMatches(m *matches) {
+	m.a = m.a[:0]
+	matchesPool.Put(m)
+}
+
+// Match a string to a regular expression, placing the submatches in m. The
+// slice of m is reused, and is only valid after a successful match.
 func findSubmatch(key, s string, m *matches) bool {
-	m.a = compiledRegexp[key].FindStringSubmatch(s)
-	return m.a != nil
+	loc := compiledRegexp[key].FindStringSubmatchIndex(s)
+	m.a = m.a[:0]
+	if loc == nil {
+		return false
+	}
+	for i := 0; i < len(loc); i += 2 {
+		if loc[i] < 0 {
+			m.a = append(m.a, "")
+		} else {
+			m.a = append(m.a, s[loc[i]:loc[i+1]])
+		}
+	}
+	return true
 }
 
 func (o *Parser) readMultiLine(content string) string {
-	m := matches{make([]string, 0, 0)}
-	if findSubmatch(quoted, content, &m) {
+	m := getMatches()
+	defer putMatches(m)
+	if findSubmatch(quoted, content, m) {
 		content = m.a[1]
 	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString(content)
 	for {
 		s, err := o.nextLine()
 		if err != nil {
-			o.appendError("EOF encountered before multiline termination",o.lineno)
+			if err.Error() != "EOF" {
+				o.abort = err
+			} else {
+				o.appendError("EOF encountered before multiline termination",o.lineno)
+			}
 			break
 		}
-		if !findSubmatch(multiline_cont, s, &m) {
-			if findSubmatch(quoted, s, &m) {
+		if !findSubmatch(multiline_cont, s, m) {
+			if findSubmatch(quoted, s, m) {
 				s = m.a[1]
 			}
-			content += s
+			buf.WriteString(s)
 			break
 		}
 		s = m.a[1]
-		if findSubmatch(quoted, s, &m) {
+		if findSubmatch(quoted, s, m) {
 			s = m.a[1]
 		}
-		content += s
+		buf.WriteString(s)
 	}
-	return content
+	return buf.String()
 }
 
 func (o *Parser) nextLine() (s string, err error) {
-	m := matches{make([]string, 0, 0)}
 	for {
-		b, err := o.reader.ReadBytes('\n')
-		s = string(b)
+		b, err := o.readLine()
 		if err != nil {
-			if err.Error() == "EOF" && s != "" {
+			if err.Error() == "EOF" && len(b) > 0 {
 				// we still have data. keep going
 				err = nil
 			} else {
@@ -331,43 +554,50 @@ func (o *Parser) nextLine() (s string, err error) {
 			}
 		}
 		o.lineno++
-		if findSubmatch(comment, s, &m) {
-			s = m.a[1]
+		// remove a comment, and trim the line before it is copied
+		if i := commentIndex(b); i >= 0 {
+			b = b[:i]
 		}
-		s = trim(s)
-		if s != "" {
-			break
+		if b = trimBytes(b); len(b) > 0 {
+			return string(b), nil
 		}
 	}
-	return s, err
 }
 
 func (o *Parser) readHereDoc(code string) (string, error) {
-	var content string
-	var s string
 	var isCode bool
+	start := o.lineno
+	buf := getBuffer()
+	defer putBuffer(buf)
 	for {
-		b, e := o.reader.ReadBytes('\n')
+		b, e := o.readLine()
 		if e != nil {
+			if e.Error() != "EOF" {
+				o.abort = e
+				return buf.String(), e
+			}
 			if len(b) == 0 {
 				break
 			}
 		}
-		s = string(b)
 		o.lineno++
-		if code == trim(s) {
+		if code == string(trimBytes(b)) {
 			isCode = true
 			break
 		}
-		s = rtrim(s)
-		if content != "" {
-			content += "\n"
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(rtrimBytes(b))
+		if err := o.checkHeredoc(buf.Len(), start); err != nil {
+			return buf.String(), err
 		}
-		content += s
 	}
+	content := buf.String()
+	o.heredocBytes += int64(len(content))
 	var err error
 	if !isCode {
-		err = errors.New("No terminating heredoc code")
+		err = ErrUnterminatedHeredoc
 	}
 	return content, err
 }
@@ -418,6 +648,51 @@ func trim(s string) string {
 	return s[n:]
 }
 
+// Return the index of the # which begins a comment, or -1. A # within a
+// quoted string does not begin a comment, unless the quote is never closed.
+func commentIndex(b []byte) int {
+	first := bytes.IndexByte(b, '#')
+	if first < 0 || bytes.IndexByte(b[:first], '"') < 0 {
+		return first
+	}
+	var quoted bool
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case '#':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	if quoted {
+		return first
+	}
+	return -1
+}
+
+// Trim leading and trailing white space from a byte slice
+func trimBytes(b []byte) []byte {
+	b = rtrimBytes(b)
+	for len(b) > 0 && isWhiteSp(b[0]) {
+		b = b[1:]
+	}
+	return b
+}
+
+// Trim trailing white space from a byte slice
+func rtrimBytes(b []byte) []byte {
+	for len(b) > 0 && isWhiteSp(b[len(b)-1]) {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
 // Trim trailing white space
 func rtrim(s string) string {
 	var n int
@@ -444,10 +719,15 @@ func isWhiteSp(c byte) bool {
 }
 
 func (o *Parser) appendError(msg string, no int) {
-	if no > 0 {
-		msg = fmt.Sprintf("%s at line %d", msg, no)
-	}
-	o.errs = append(o.errs, errors.New(msg))
+	o.errs = append(o.errs, &ParseError{File: o.filename, Line: no, Msg: msg})
+	o.countError()
+}
+
+// Append an error, such as one of the Err values of this package, which
+// remains available to errors.Is and errors.As.
+func (o *Parser) appendErr(err error, no int) {
+	o.errs = append(o.errs, &ParseError{File: o.filename, Line: no, Msg: err.Error(), Err: err})
+	o.countError()
 }
 
 func getErrors( errs []error ) error {
@@ -459,7 +739,7 @@ func getErrors( errs []error ) error {
 		s += e.Error() + "\n"
 	}
 	s = strings.TrimRight(s, "\n")
-	return errors.New(s)
+	return &multiError{s, errs}
 }
 
 func isOption(option, options int) bool {
@@ -279,3 +279,93 @@ func TestParseFile_errors(t *testing.T) {
 
 
 }
+
+func TestParser_DuplicateKeyPolicy(t *testing.T) {
+
+	src := `
+		Key1 = 1
+		Key2 = 2
+		Key1 = 3
+		Hdoc = <<_END
+		first
+		_END
+		Hdoc = <<_END
+		last
+		_END
+		Block {
+			A = 1
+			B = 2
+		}
+		Block {
+			B = 3
+			C = 4
+		}
+	`
+
+	Convey("Keep the first value with FIRST_KEY_WINS", t, func() {
+		m, err := Parse(src, FIRST_KEY_WINS)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"Key1": "1", "Key2": "2", "Hdoc": "\t\tfirst",
+			"Block.A": "1", "Block.B": "2", "Block.C": "4"})
+	})
+
+	Convey("Keep the last value with LAST_KEY_WINS", t, func() {
+		m, err := Parse(src, LAST_KEY_WINS)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"Key1": "3", "Key2": "2", "Hdoc": "\t\tlast",
+			"Block.A": "1", "Block.B": "3", "Block.C": "4"})
+	})
+
+	Convey("Decode with a duplicate key policy", t, func() {
+		var x struct {
+			Key1  int
+			Block struct{ A, B, C int }
+		}
+		So(Decode(&x, "Key1 = 1\nKey1 = 2\nBlock {\nB = 1\n}\nBlock {\nB = 2\n}", LAST_KEY_WINS), ShouldBeNil)
+		So(x.Key1, ShouldEqual, 2)
+		So(x.Block.B, ShouldEqual, 2)
+	})
+
+	Convey("Force error: Duplicate keys without a policy", t, func() {
+		_, err := Parse(src)
+		So(err.Error(), ShouldEqual, "Duplicate key at line 4\nDuplicate key at line 10\nDuplicate key at line 15")
+	})
+
+}
+
+func TestParse_Buffers(t *testing.T) {
+
+	Convey("Lines longer than the read buffer", t, func() {
+		long := strings.Repeat("x", 10000)
+		m, err := Parse("A = " + long + " # comment\nB = <<END\n" + long + "  \nEND\nC = 1")
+		So(err, ShouldBeNil)
+		So(m["A"], ShouldEqual, long)
+		So(m["B"], ShouldEqual, long)
+		So(m["C"], ShouldEqual, "1")
+	})
+
+	Convey("Comments and blank lines do not allocate", t, func() {
+		parse := func(n int) float64 {
+			src := "A = 1\n" + strings.Repeat("# comment\n\n", n) + "B = 2\n"
+			return testing.AllocsPerRun(10, func() {
+				Parse(src)
+			})
+		}
+		// allowing for noise, such as that of the race detector, well below
+		// one allocation per line
+		So(parse(1000), ShouldBeLessThan, parse(10)+100)
+	})
+
+}
+
+func TestParse_QuotedHash(t *testing.T) {
+
+	Convey("A # within quotes does not begin a comment", t, func() {
+		m, err := Parse("A = \"a # b\" # comment\nB = \"say \\\"#1\\\"\"\nC = c # \"d\"")
+		So(err, ShouldBeNil)
+		So(m["A"], ShouldEqual, "a # b")
+		So(m["B"], ShouldEqual, `say "#1"`)
+		So(m["C"], ShouldEqual, "c")
+	})
+
+}
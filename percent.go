@@ -0,0 +1,57 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Strip the percent sign from a value assigned to an integer field.
+func intPercent(v1 reflect.Value, val string) string {
+	switch v1.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v1.Type() != durationType && strings.HasSuffix(val, "%") {
+			return val[:len(val)-1]
+		}
+	}
+	return val
+}
+
+// Write a struct field tagged with `format:"percent"` as a percentage. A
+// float is multiplied by 100, eg. 0.75 == 75%, and an integer is written as
+// is, eg. 75 == 75%. Returns false if the field is not tagged.
+//
+//	type Sampling struct {
+//		Ratio float64 `format:"percent"`
+//	}
+func (o *Encoder) encodePercentField(f reflect.StructField, v1 reflect.Value, depth int) bool {
+	if f.Tag.Get("format") != "percent" {
+		return false
+	}
+	if !o.isOption(ENCODE_ZERO_VALUES) && isZeroStruct(v1) {
+		return true
+	}
+	var s string
+	switch v1.Kind() {
+	case reflect.Float32, reflect.Float64:
+		// round away the error introduced by the multiplication
+		prec := 15
+		if v1.Kind() == reflect.Float32 {
+			prec = 6
+		}
+		s = strconv.FormatFloat(v1.Float()*100, 'g', prec, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v1.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(v1.Uint(), 10)
+	default:
+		return false
+	}
+	o.write_kv(depth, fieldKey(f), s+"%")
+	return true
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPercent(t *testing.T) {
+
+	type cfg struct {
+		Ratio    float64 `format:"percent"`
+		Sample   float32 `format:"percent"`
+		Capacity int     `format:"percent"`
+		Load     uint8   `format:"percent"`
+		Plain    float64
+	}
+
+	Convey("Decode percentages into floats", t, func() {
+		var x cfg
+		So(Decode(&x, "Ratio = 75%\nSample = 0.5%\nPlain = 7%"), ShouldBeNil)
+		So(x.Ratio, ShouldEqual, 0.75)
+		So(x.Sample, ShouldEqual, float32(0.005))
+		So(x.Plain, ShouldEqual, 0.07)
+	})
+
+	Convey("Decode percentages into integers with ALLOW_INT_PERCENT", t, func() {
+		var x cfg
+		So(Decode(&x, "Capacity = 75%\nLoad = 90%", ALLOW_INT_PERCENT), ShouldBeNil)
+		So(x.Capacity, ShouldEqual, 75)
+		So(x.Load, ShouldEqual, 90)
+	})
+
+	Convey("Encode fields tagged as percentages", t, func() {
+		x := cfg{Ratio: 0.07, Sample: 0.1, Capacity: 75, Load: 90, Plain: 0.5}
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "Ratio = 7%\nSample = 10%\nCapacity = 75%\nLoad = 90%\nPlain = 0.5\n")
+		var y cfg
+		So(Decode(&y, b1, ALLOW_INT_PERCENT), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+	Convey("Force error: Percentage for an integer without ALLOW_INT_PERCENT", t, func() {
+		var x cfg
+		So(Decode(&x, "Capacity = 75%"), ShouldNotBeNil)
+		So(Decode(&x, "Ratio = %"), ShouldNotBeNil)
+		So(Decode(&x, "Load = 300%", ALLOW_INT_PERCENT), ShouldNotBeNil)
+	})
+
+}
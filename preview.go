@@ -0,0 +1,179 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Number of unchanged lines shown around each change by PreviewFile
+const diff_context = 3
+
+// PreviewFile encodes a struct as ToFile would, without writing it, and
+// returns a unified diff between the current content of the named file and
+// the encoded data, and whether they differ. A file which does not exist is
+// compared as if it were empty. A file which would not be written, because
+// nothing is encoded, is reported as unchanged. An encrypted file is
+// compared after it is decrypted.
+//
+//	diff, changed, err := enc.PreviewFile("/etc/app.conf")
+//	if err == nil && changed {
+//		fmt.Print(diff)
+//		err = enc.ToFile("/etc/app.conf")
+//	}
+func (o *Encoder) PreviewFile(filename string) (string, bool, error) {
+	if filename == "" {
+		return "", false, errors.New("missing filename")
+	}
+	var buf bytes.Buffer
+	if err := o.ToStream(&buf); err != nil {
+		return "", false, err
+	}
+	if buf.Len() == 0 {
+		return "", false, nil
+	}
+	var old []byte
+	fh, err := openFile(filename, o.cipher)
+	switch {
+	case errors.Is(err, ErrNotExist):
+	case err != nil:
+		return "", false, err
+	default:
+		old, err = ioutil.ReadAll(fh)
+		fh.Close()
+		if err != nil {
+			return "", false, err
+		}
+	}
+	if bytes.Equal(old, buf.Bytes()) {
+		return "", false, nil
+	}
+	return unifiedDiff(filename, string(old), buf.String()), true, nil
+}
+
+// Return a unified diff of two texts, with diff_context lines of context
+func unifiedDiff(filename, a, b string) string {
+	x, y := splitLines(a), splitLines(b)
+	ops := diffLines(x, y)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", filename, filename)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// a hunk runs from the context before a change to the context after
+		// the last change within twice the context of the one before
+		start := max(i-diff_context, 0)
+		end := i
+		for j := i; j < len(ops) && j-end <= 2*diff_context; j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			}
+		}
+		end = min(end+diff_context, len(ops))
+		var na, nb int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				na++
+			}
+			if op.kind != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(ops[start].a, na), hunkRange(ops[start].b, nb))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.text)
+			sb.WriteByte('\n')
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// A line of a diff: ' ' for a line of both texts, '-' for a line of the
+// first only, or '+' for a line of the second only. a and b are the indexes
+// of the line, or of the next line, in each text.
+type diffOp struct {
+	kind byte
+	text string
+	a, b int
+}
+
+// Return the lines of a text, without line endings
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, lf), lf)
+}
+
+// Return the difference of two lists of lines as a list of operations, from
+// their longest common subsequence. Lines common to the start and end of both
+// are matched first.
+func diffLines(x, y []string) []diffOp {
+	var pre, suf int
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	mx, my := x[pre:len(x)-suf], y[pre:len(y)-suf]
+	// lcs[i][j] is the length of the common subsequence of mx[i:] and my[j:]
+	lcs := make([][]int, len(mx)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(my)+1)
+	}
+	for i := len(mx) - 1; i >= 0; i-- {
+		for j := len(my) - 1; j >= 0; j-- {
+			if mx[i] == my[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	for i := 0; i < pre; i++ {
+		ops = append(ops, diffOp{' ', x[i], i, i})
+	}
+	i, j := 0, 0
+	for i < len(mx) || j < len(my) {
+		switch {
+		case i < len(mx) && j < len(my) && mx[i] == my[j]:
+			ops = append(ops, diffOp{' ', mx[i], pre + i, pre + j})
+			i++
+			j++
+		case i < len(mx) && (j == len(my) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', mx[i], pre + i, pre + j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', my[j], pre + i, pre + j})
+			j++
+		}
+	}
+	for k := 0; k < suf; k++ {
+		ops = append(ops, diffOp{' ', x[len(x)-suf+k], len(x) - suf + k, len(y) - suf + k})
+	}
+	return ops
+}
+
+// Return the range of lines of a hunk, starting at the line with the given
+// index, as written in its header.
+func hunkRange(index, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", index)
+	case 1:
+		return fmt.Sprintf("%d", index+1)
+	}
+	return fmt.Sprintf("%d,%d", index+1, n)
+}
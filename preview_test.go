@@ -0,0 +1,75 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPreviewFile(t *testing.T) {
+
+	type T struct {
+		Name string
+		Port int
+		A, B, C, D, E, F, G, H, I, J int
+	}
+
+	tempfile := createTempFile("GOTEST_CONFIG")
+	defer os.Remove(tempfile)
+
+	x := T{Name: "Rick", Port: 80, A: 1, B: 2, C: 3, D: 4, E: 5, F: 6, G: 7, H: 8, I: 9, J: 10}
+
+	Convey("Preview a file which does not change", t, func() {
+		So(NewEncoder(x, OVERWRITE_FILE).ToFile(tempfile), ShouldBeNil)
+		diff, changed, err := NewEncoder(x).PreviewFile(tempfile)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeFalse)
+		So(diff, ShouldEqual, "")
+	})
+
+	Convey("Preview changes to a file", t, func() {
+		So(NewEncoder(x, OVERWRITE_FILE).ToFile(tempfile), ShouldBeNil)
+		y := x
+		y.Port = 8080
+		y.J = 0
+		diff, changed, err := NewEncoder(y).PreviewFile(tempfile)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+		So(diff, ShouldEqual, strings.Join([]string{
+			"--- " + tempfile,
+			"+++ " + tempfile,
+			"@@ -1,5 +1,5 @@",
+			" Name = Rick",
+			"-Port = 80",
+			"+Port = 8080",
+			" A = 1",
+			" B = 2",
+			" C = 3",
+			"@@ -9,4 +9,3 @@",
+			" G = 7",
+			" H = 8",
+			" I = 9",
+			"-J = 10",
+			"",
+		}, "\n"))
+	})
+
+	Convey("Preview a new file", t, func() {
+		os.Remove(tempfile)
+		diff, changed, err := NewEncoder(struct{ Name string }{"Rick"}).PreviewFile(tempfile)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+		So(diff, ShouldEqual, "--- "+tempfile+"\n+++ "+tempfile+"\n@@ -0,0 +1 @@\n+Name = Rick\n")
+	})
+
+	Convey("Force error: Missing file name", t, func() {
+		_, _, err := NewEncoder(x).PreviewFile("")
+		So(err.Error(), ShouldEqual, "missing filename")
+	})
+
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+// SetProfile selects the profile sections to be merged over the base keys.
+// A profile section is a top level block introduced with @profile and a
+// name. Its keys override the keys outside of any profile section, wherever
+// the section appears. Sections of other profiles, and the files they
+// include, are ignored.
+//
+//	Port = 8080
+//	@profile production {
+//		Port = 80
+//	}
+func (o *Parser) SetProfile(name string) *Parser {
+	o.profile = name
+	return o
+}
+
+// SetProfile selects the profile sections to be merged over the base keys.
+// See Parser.SetProfile.
+func (o *Decoder) SetProfile(name string) *Decoder {
+	o.profile = name
+	return o
+}
+
+// Parse a profile section. The section, and the files it includes, are kept
+// if the name matches the selected profile.
+func (o *Parser) parseProfile(name string, depth int) {
+	lineno := o.lineno
+	if depth > 0 {
+		o.appendError("Profile not allowed in a block", lineno)
+	}
+	n := len(o.include)
+	emap, err := o.recursive_parse(depth + 1)
+	if err != nil {
+		if o.abort == nil {
+			o.appendErr(err, lineno)
+		}
+		return
+	}
+	o.sections++
+	if depth > 0 || name != o.profile {
+		o.dropIncludes(n)
+		return
+	}
+	if o.overlay == nil {
+		o.overlay = make(fMap)
+	}
+	for k, val := range emap {
+		o.overlay[k] = val
+	}
+}
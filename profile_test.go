@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProfiles(t *testing.T) {
+
+	src := `
+		@profile production {
+			Debug = false
+			Server {
+				Host = example.com
+			}
+		}
+		Name  = app
+		Debug = true
+		Server {
+			Host = localhost
+			Port = 8080
+		}
+		@profile staging {
+			Server {
+				Host = staging.example.com
+			}
+		}
+	`
+
+	type cfg struct {
+		Name   string
+		Debug  bool
+		Server struct {
+			Host string
+			Port int
+		}
+	}
+
+	Convey("Merge the selected profile over the base keys", t, func() {
+		var x cfg
+		So(NewDecoder(&x).SetProfile("production").DecodeString(src), ShouldBeNil)
+		So(x.Name, ShouldEqual, "app")
+		So(x.Debug, ShouldBeFalse)
+		So(x.Server.Host, ShouldEqual, "example.com")
+		So(x.Server.Port, ShouldEqual, 8080)
+	})
+
+	Convey("Ignore profiles which are not selected", t, func() {
+		var x cfg
+		So(NewDecoder(&x).DecodeString(src), ShouldBeNil)
+		So(x.Debug, ShouldBeTrue)
+		So(x.Server.Host, ShouldEqual, "localhost")
+		m, err := NewParser().SetProfile("staging").Parse([]byte(src))
+		So(err, ShouldBeNil)
+		So(m["Server.Host"], ShouldEqual, "staging.example.com")
+	})
+
+	Convey("Files are only included by the selected profile", t, func() {
+		prodfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(prodfile)
+		writeFile(prodfile, []byte("Debug = false\nName = prod"))
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = app\nDebug = true\n@profile production {\n  include "+prodfile+"\n}"))
+		var x cfg
+		So(NewDecoder(&x).SetProfile("staging").DecodeFile(tempfile), ShouldBeNil)
+		So(x.Debug, ShouldBeTrue)
+		So(x.Name, ShouldEqual, "app")
+		m, err := ParseFile(tempfile)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{"Name": "app", "Debug": "true"})
+	})
+
+	Convey("A file may contain only profiles", t, func() {
+		m, err := NewParser().Parse([]byte("@profile dev {\nA = 1\n}"))
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, StringMap{})
+	})
+
+	Convey("Force error: Invalid profile sections", t, func() {
+		_, err := Parse("A {\n@profile dev {\nB = 1\n}\n}")
+		So(err.Error(), ShouldEqual, "Profile not allowed in a block at line 2")
+		_, err = Parse("A = 1\n@profile dev {\nB = 1\n")
+		So(err.Error(), ShouldEqual, "Missing closing brace at line 2")
+	})
+
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A reference to another key, or an escaped $${ which is written as ${
+var refRegexp = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// Resolve the ${Key} references in the values of a field map. A reference
+// names a dotted key of the same source, or of a file which included it, and
+// is replaced by the resolved value of that key. Write $${ for a literal ${.
+func (o *Parser) resolveRefs(m fMap) {
+	done := make(map[string]bool)
+	for key := range m {
+		o.resolveRef(m, key, done, nil)
+	}
+}
+
+func (o *Parser) resolveRef(m fMap, key string, done map[string]bool, stack []string) {
+	vs := m[key]
+	if done[key] || !strings.Contains(vs.val, "${") {
+		done[key] = true
+		return
+	}
+	for _, k := range stack {
+		if k == key {
+			o.appendError("Circular reference ("+strings.Join(append(stack, key), " -> ")+")", vs.no)
+			vs.val = ""
+			done[key] = true
+			return
+		}
+	}
+	stack = append(stack, key)
+	vs.val = refRegexp.ReplaceAllStringFunc(vs.val, func(s string) string {
+		if strings.HasPrefix(s, "$$") {
+			return s[1:]
+		}
+		name := s[2 : len(s)-1]
+		if ref, ok := m[name]; ok {
+			o.resolveRef(m, name, done, stack)
+			if ref.val == null_value {
+				return ""
+			}
+			return ref.val
+		}
+		if val, ok := o.refs[name]; ok {
+			return val
+		}
+		o.appendError("Undefined reference ("+name+")", vs.no)
+		return ""
+	})
+	if o.tracing {
+		o.trace = append(o.trace, TraceEntry{vs.no, "reference", "", key, vs.val})
+	}
+	done[key] = true
+}
+
+// Record the values of a field map, to be referenced by included files.
+func (o *Decoder) addRefs(m fMap) {
+	if o.refs == nil {
+		o.refs = make(map[string]string)
+	}
+	for k, vs := range m {
+		o.refs[k] = vs.val
+	}
+}
+
+// Return a copy of the references with the values of a string map added.
+func copyRefs(refs map[string]string, smap StringMap) map[string]string {
+	m := make(map[string]string, len(refs)+len(smap))
+	for k, val := range refs {
+		m[k] = val
+	}
+	for k, val := range smap {
+		m[k] = val
+	}
+	return m
+}
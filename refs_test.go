@@ -0,0 +1,60 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReferences(t *testing.T) {
+
+	Convey("Resolve references", t, func() {
+		m, err := Parse(`
+			BaseURL = http://${Server.Host}:${Server.Port}/
+			Server {
+				Host = example.com
+				Port = 8080
+			}
+			Api = ${BaseURL}api
+			Literal = $${Server.Host}
+		`)
+		So(err, ShouldBeNil)
+		So(m["BaseURL"], ShouldEqual, "http://example.com:8080/")
+		So(m["Api"], ShouldEqual, "http://example.com:8080/api")
+		So(m["Literal"], ShouldEqual, "${Server.Host}")
+	})
+
+	Convey("Resolve references to an including file", t, func() {
+		dir, err := os.MkdirTemp("", "config")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		f1 := filepath.Join(dir, "a.conf")
+		f2 := filepath.Join(dir, "b.conf")
+		writeFile(f1, []byte("Root = /srv\ninclude "+f2+"\n"))
+		writeFile(f2, []byte("Data = ${Root}/data\n"))
+
+		m, err := ParseFile(f1)
+		So(err, ShouldBeNil)
+		So(m["Data"], ShouldEqual, "/srv/data")
+
+		var x struct {
+			Root string
+			Data string
+		}
+		So(DecodeFile(f1, &x), ShouldBeNil)
+		So(x.Data, ShouldEqual, "/srv/data")
+	})
+
+	Convey("Force error: Undefined and circular references", t, func() {
+		_, err := Parse("A = ${B}")
+		So(err.Error(), ShouldEqual, "Undefined reference (B) at line 1")
+		_, err = Parse("A = ${B}\nB = ${A}")
+		So(err.Error(), ShouldStartWith, "Circular reference (")
+	})
+
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// ReloadOnHUP installs a SIGHUP handler which decodes the supplied file into
+// a new struct and stores it in cur. If the file cannot be decoded, onError
+// is called (if not nil) and the previous value remains in place. The
+// returned function removes the handler. Decoder options are optional.
+//
+//	var cfg atomic.Pointer[Config]
+//	cfg.Store(initial)
+//	stop := config.ReloadOnHUP("app.conf", &cfg, func(err error) { log.Print(err) })
+//	defer stop()
+func ReloadOnHUP[T any](filename string, cur *atomic.Pointer[T], onError func(error), options ...int) func() {
+	return reloadOnHUP(filename, cur.Store, onError, options...)
+}
+
+func reloadOnHUP[T any](filename string, store func(*T), onError func(error), options ...int) func() {
+	NewDecoder(new(T), options...) // panic early on bad arguments
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-c:
+				x := new(T)
+				if err := DecodeFile(filename, x, options...); err != nil {
+					if onError != nil {
+						onError(err)
+					}
+					continue
+				}
+				store(x)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
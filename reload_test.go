@@ -0,0 +1,60 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReloadOnHUP(t *testing.T) {
+
+	type cfgX struct {
+		Name string
+		Port int
+	}
+
+	hup := func() {
+		p, err := os.FindProcess(os.Getpid())
+		if err == nil {
+			err = p.Signal(syscall.SIGHUP)
+		}
+		if err != nil {
+			t.Skip("cannot send SIGHUP: ", err)
+		}
+	}
+
+	Convey("Reload a file on SIGHUP", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = Rick"))
+
+		var cur atomic.Pointer[cfgX]
+		first := &cfgX{Name: "Morty"}
+		cur.Store(first)
+		errs := make(chan error, 1)
+		stop := ReloadOnHUP(tempfile, &cur, func(err error) { errs <- err })
+		defer stop()
+
+		hup()
+		for i := 0; i < 200 && cur.Load() == first; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		So(cur.Load().Name, ShouldEqual, "Rick")
+
+		Convey("Keep the current value when the new file is invalid", func() {
+			second := cur.Load()
+			writeFile(tempfile, []byte("Port = many"))
+			hup()
+			So(<-errs, ShouldNotBeNil)
+			So(cur.Load(), ShouldEqual, second)
+		})
+	})
+
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// TOKEN_EOF indicates the end of the source.
+	TOKEN_EOF = iota
+
+	// TOKEN_KEY indicates a key, which may be dotted or quoted.
+	TOKEN_KEY
+
+	// TOKEN_ASSIGN indicates the = or : between a key and its value.
+	TOKEN_ASSIGN
+
+	// TOKEN_VALUE indicates a value as written, or a line of a heredoc or
+	// multi-line value.
+	TOKEN_VALUE
+
+	// TOKEN_OPEN_BRACE indicates the opening brace of a block.
+	TOKEN_OPEN_BRACE
+
+	// TOKEN_CLOSE_BRACE indicates the closing brace of a block.
+	TOKEN_CLOSE_BRACE
+
+	// TOKEN_SEPARATOR indicates the comma or semicolon between the keys of
+	// an inline block.
+	TOKEN_SEPARATOR
+
+	// TOKEN_COMMENT indicates a comment, including the #.
+	TOKEN_COMMENT
+
+	// TOKEN_INCLUDE indicates the include keyword. The file name follows as
+	// a TOKEN_VALUE, and then any as keyword, also a TOKEN_INCLUDE, and key
+	// prefix, a TOKEN_KEY.
+	TOKEN_INCLUDE
+
+	// TOKEN_DIRECTIVE indicates a @profile or @if directive, or the name of
+	// an anchor, eg. &defaults.
+	TOKEN_DIRECTIVE
+
+	// TOKEN_ALIAS indicates the use of an anchor, eg. *defaults.
+	TOKEN_ALIAS
+
+	// TOKEN_HEREDOC indicates the opening <<CODE of a heredoc, or the CODE
+	// which terminates it.
+	TOKEN_HEREDOC
+
+	// TOKEN_ERROR indicates text which matches no rule of the parser.
+	TOKEN_ERROR
+)
+
+var tokenNames = []string{"EOF", "KEY", "ASSIGN", "VALUE", "OPEN_BRACE", "CLOSE_BRACE",
+	"SEPARATOR", "COMMENT", "INCLUDE", "DIRECTIVE", "ALIAS", "HEREDOC", "ERROR"}
+
+// A Token is a lexical element of a source, with the line and column, both
+// starting at 1, of its first byte.
+type Token struct {
+	Kind   int    // One of the TOKEN constants
+	Text   string // Text of the token as written
+	Line   int    // Line number in source
+	Column int    // Byte offset within the line, plus one
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%d:%d %s %q", t.Line, t.Column, tokenNames[t.Kind], t.Text)
+}
+
+// A Scanner splits a source into tokens, using the same rules as the parser,
+// for tools such as syntax highlighters and editors. No values are
+// converted and no errors are reported, other than read errors; text which
+// the parser would reject is returned as a TOKEN_ERROR.
+//
+//	s := config.NewScanner(r)
+//	for t := s.Next(); t.Kind != config.TOKEN_EOF; t = s.Next() {
+//		fmt.Println(t)
+//	}
+//	if err := s.Err(); err != nil {
+type Scanner struct {
+	reader  *bufio.Reader
+	lineno  int
+	tokens  []Token
+	heredoc string // Code which terminates the current heredoc
+	cont    bool   // The previous line continues a multi-line value
+	err     error
+	eof     bool
+}
+
+// NewScanner returns a new Scanner which reads from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next token. At the end of the source, or after a read
+// error, a TOKEN_EOF is returned.
+func (o *Scanner) Next() Token {
+	for len(o.tokens) == 0 {
+		if o.eof {
+			return Token{Kind: TOKEN_EOF, Line: o.lineno + 1, Column: 1}
+		}
+		o.scanLine()
+	}
+	t := o.tokens[0]
+	o.tokens = o.tokens[1:]
+	return t
+}
+
+// Err returns the first read error, other than io.EOF.
+func (o *Scanner) Err() error {
+	return o.err
+}
+
+func (o *Scanner) add(kind int, text string, col int) {
+	o.tokens = append(o.tokens, Token{kind, text, o.lineno, col + 1})
+}
+
+// Split the next line of the source into tokens
+func (o *Scanner) scanLine() {
+	s, err := o.reader.ReadString('\n')
+	if err != nil {
+		o.eof = true
+		if err != io.EOF {
+			o.err = err
+			return
+		}
+		if s == "" {
+			return
+		}
+	}
+	o.lineno++
+	s = strings.TrimRight(s, "\r\n")
+	if o.heredoc != "" {
+		if trim(s) == o.heredoc {
+			o.add(TOKEN_HEREDOC, trim(s), strings.Index(s, o.heredoc))
+			o.heredoc = ""
+		} else if s = rtrim(s); s != "" {
+			o.add(TOKEN_VALUE, s, 0)
+		}
+		return
+	}
+	code, cmt := s, -1
+	if i := commentIndex([]byte(s)); i >= 0 {
+		code, cmt = s[:i], i
+	}
+	lead := len(code) - len(strings.TrimLeft(code, " \t\r\n\v\f"))
+	if code = trim(code); code != "" {
+		if o.cont {
+			o.add(TOKEN_VALUE, code, lead)
+			o.cont = strings.HasSuffix(code, `\`)
+		} else {
+			o.scanCode(code, lead)
+		}
+	}
+	if cmt >= 0 {
+		o.add(TOKEN_COMMENT, rtrim(s[cmt:]), cmt)
+	}
+}
+
+// Split the code of a line, which begins at column off, into tokens
+func (o *Scanner) scanCode(s string, off int) {
+	rule, loc := scanRule(s)
+	group := func(i int) (string, int) {
+		return s[loc[2*i]:loc[2*i+1]], off + loc[2*i]
+	}
+	switch rule {
+	case include:
+		o.add(TOKEN_INCLUDE, s[:7], off)
+		start, end := loc[2], loc[3]
+		if s[start-1] == '"' {
+			start--
+		}
+		if end < len(s) && s[end] == '"' {
+			end++
+		}
+		o.add(TOKEN_VALUE, s[start:end], off+start)
+		if loc[4] >= 0 {
+			i := end + strings.Index(strings.ToLower(s[end:loc[4]]), "as")
+			o.add(TOKEN_INCLUDE, s[i:i+2], off+i)
+			o.add(TOKEN_KEY, s[loc[4]:loc[5]], off+loc[4])
+		}
+	case profile, condition, anchor:
+		d := rtrim(s[:len(s)-1])
+		o.add(TOKEN_DIRECTIVE, d, off)
+		o.add(TOKEN_OPEN_BRACE, "{", off+len(s)-1)
+	case close_brace:
+		o.add(TOKEN_CLOSE_BRACE, "}", off+strings.IndexByte(s, '}'))
+	case alias:
+		o.key(s, off, loc)
+		name, col := group(2)
+		o.add(TOKEN_ALIAS, "*"+name, col-1)
+		if loc[6] >= 0 {
+			o.add(TOKEN_OPEN_BRACE, "{", off+loc[6])
+		}
+	case open_brace:
+		o.key(s, off, loc)
+		o.add(TOKEN_OPEN_BRACE, "{", off+len(s)-1)
+	case inline_block:
+		o.key(s, off, loc)
+		body, col := group(2)
+		o.scanInline(body, col)
+	case heredoc:
+		o.key(s, off, loc)
+		code, col := group(2)
+		o.add(TOKEN_HEREDOC, "<<"+code, col-2)
+		o.heredoc = code
+	case quoted_key, multiline, keyval:
+		o.key(s, off, loc)
+		val, col := group(2)
+		if rule == multiline {
+			// the value as written, with the backslash
+			val = s[loc[4]:]
+		}
+		switch {
+		case val == "{":
+			o.add(TOKEN_OPEN_BRACE, val, col)
+		case rule == quoted_key && len(val) > 1 && val[0] == '{' && val[len(val)-1] == '}':
+			o.scanInline(val[1:len(val)-1], col+1)
+		case val != "":
+			o.add(TOKEN_VALUE, val, col)
+		}
+		o.cont = rule == multiline
+	default:
+		o.add(TOKEN_ERROR, s, off)
+	}
+}
+
+// Add the key of a line, and the = or : which follows it
+func (o *Scanner) key(s string, off int, loc []int) {
+	o.add(TOKEN_KEY, s[loc[2]:loc[3]], off+loc[2])
+	end := len(s)
+	if len(loc) > 4 && loc[4] >= 0 {
+		end = loc[4]
+	}
+	if i := strings.IndexAny(s[loc[3]:end], "=:"); i >= 0 {
+		o.add(TOKEN_ASSIGN, s[loc[3]+i:loc[3]+i+1], off+loc[3]+i)
+	}
+}
+
+// Add the tokens of the body of an inline block which begins at column off,
+// between its braces.
+func (o *Scanner) scanInline(s string, off int) {
+	o.add(TOKEN_OPEN_BRACE, "{", off-1)
+	pos := 0
+	for i, pair := range splitInline(s) {
+		if i > 0 {
+			o.add(TOKEN_SEPARATOR, s[pos-1:pos], off+pos-1)
+		}
+		col := off + pos
+		pos += len(pair) + 1
+		lead := len(pair) - len(strings.TrimLeft(pair, " \t"))
+		if pair = trim(pair); pair == "" {
+			continue
+		}
+		col += lead
+		loc := inlinePair.FindStringSubmatchIndex(pair)
+		if loc == nil {
+			o.add(TOKEN_ERROR, pair, col)
+			continue
+		}
+		o.key(pair, col, loc)
+		val := pair[loc[4]:loc[5]]
+		if len(val) > 1 && val[0] == '{' && val[len(val)-1] == '}' {
+			o.scanInline(val[1:len(val)-1], col+loc[4]+1)
+		} else if val != "" {
+			o.add(TOKEN_VALUE, val, col+loc[4])
+		}
+	}
+	o.add(TOKEN_CLOSE_BRACE, "}", off+len(s))
+}
+
+// The rules of the parser, in the order it applies them
+var scanRules = []string{include, profile, condition, quoted_key, anchor, alias,
+	inline_block, open_brace, close_brace, heredoc, multiline, keyval}
+
+// Return the first rule which matches the code of a line, and the indexes of
+// its submatches.
+func scanRule(s string) (string, []int) {
+	for _, rule := range scanRules {
+		if loc := compiledRegexp[rule].FindStringSubmatchIndex(s); loc != nil {
+			return rule, loc
+		}
+	}
+	return "", nil
+}
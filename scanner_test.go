@@ -0,0 +1,111 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScanner(t *testing.T) {
+
+	scan := func(src string) []string {
+		var a []string
+		s := NewScanner(strings.NewReader(src))
+		for t := s.Next(); t.Kind != TOKEN_EOF; t = s.Next() {
+			a = append(a, t.String())
+		}
+		return a
+	}
+
+	Convey("Tokens of keys, values and comments", t, func() {
+		So(scan("# Sample\nName = \"Rick\"  # the name\ninclude other.conf\n"), ShouldResemble, []string{
+			`1:1 COMMENT "# Sample"`,
+			`2:1 KEY "Name"`,
+			`2:6 ASSIGN "="`,
+			`2:8 VALUE "\"Rick\""`,
+			`2:16 COMMENT "# the name"`,
+			`3:1 INCLUDE "include"`,
+			`3:9 VALUE "other.conf"`,
+		})
+	})
+
+	Convey("Tokens of blocks", t, func() {
+		So(scan("Server {\n  Limits = { Min = 1; Max: 2 }\n  Base = *defaults\n}\n@profile dev {\n}"), ShouldResemble, []string{
+			`1:1 KEY "Server"`,
+			`1:8 OPEN_BRACE "{"`,
+			`2:3 KEY "Limits"`,
+			`2:10 ASSIGN "="`,
+			`2:12 OPEN_BRACE "{"`,
+			`2:14 KEY "Min"`,
+			`2:18 ASSIGN "="`,
+			`2:20 VALUE "1"`,
+			`2:21 SEPARATOR ";"`,
+			`2:23 KEY "Max"`,
+			`2:26 ASSIGN ":"`,
+			`2:28 VALUE "2"`,
+			`2:30 CLOSE_BRACE "}"`,
+			`3:3 KEY "Base"`,
+			`3:8 ASSIGN "="`,
+			`3:10 ALIAS "*defaults"`,
+			`4:1 CLOSE_BRACE "}"`,
+			`5:1 DIRECTIVE "@profile dev"`,
+			`5:14 OPEN_BRACE "{"`,
+			`6:1 CLOSE_BRACE "}"`,
+		})
+	})
+
+	Convey("Tokens of heredoc and multi-line values", t, func() {
+		So(scan("Text = <<END\n  line # not a comment\nEND\nList = a, \\\n  b"), ShouldResemble, []string{
+			`1:1 KEY "Text"`,
+			`1:6 ASSIGN "="`,
+			`1:8 HEREDOC "<<END"`,
+			`2:1 VALUE "  line # not a comment"`,
+			`3:1 HEREDOC "END"`,
+			`4:1 KEY "List"`,
+			`4:6 ASSIGN "="`,
+			`4:8 VALUE "a, \\"`,
+			`5:3 VALUE "b"`,
+		})
+	})
+
+	Convey("Tokens of an include into a key prefix", t, func() {
+		So(scan(`include "backend.conf" as Database`), ShouldResemble, []string{
+			`1:1 INCLUDE "include"`,
+			`1:9 VALUE "\"backend.conf\""`,
+			`1:24 INCLUDE "as"`,
+			`1:27 KEY "Database"`,
+		})
+	})
+
+	Convey("Text which matches no rule is an error token", t, func() {
+		So(scan("!!!\nName\n"), ShouldResemble, []string{`1:1 ERROR "!!!"`, `2:1 ERROR "Name"`})
+	})
+
+	Convey("The end of the source is repeated", t, func() {
+		s := NewScanner(strings.NewReader("Name = a"))
+		So(s.Next().Kind, ShouldEqual, TOKEN_KEY)
+		s.Next()
+		So(s.Next().Kind, ShouldEqual, TOKEN_VALUE)
+		So(s.Next().String(), ShouldEqual, `2:1 EOF ""`)
+		So(s.Next().Kind, ShouldEqual, TOKEN_EOF)
+		So(s.Err(), ShouldBeNil)
+	})
+
+	Convey("Force error: Read error", t, func() {
+		s := NewScanner(failReader{})
+		So(s.Next().Kind, ShouldEqual, TOKEN_EOF)
+		So(errors.Is(s.Err(), errRead), ShouldBeTrue)
+	})
+
+}
+
+var errRead = errors.New("read failed")
+
+type failReader struct{}
+
+func (failReader) Read([]byte) (int, error) { return 0, errRead }
@@ -0,0 +1,99 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	enc_prefix = "ENC["
+	enc_suffix = "]"
+)
+
+// WithDecrypter sets a function to decrypt values of the form ENC[ciphertext].
+// The ciphertext between the brackets is passed to fn and the result is
+// assigned in place of the original value. Without a decrypter, such values
+// are assigned unchanged.
+func (o *Decoder) WithDecrypter(fn func(ciphertext string) (string, error)) *Decoder {
+	o.decrypt = fn
+	return o
+}
+
+// WithEncrypter sets a function to encrypt the values of struct fields tagged
+// with `encrypt:"true"`. The field value is formatted as it would otherwise be
+// encoded, passed to fn, and written as ENC[ciphertext].
+//
+//	type Config struct {
+//		Password string `encrypt:"true"`
+//	}
+func (o *Encoder) WithEncrypter(fn func(plaintext string) (string, error)) *Encoder {
+	o.encrypt = fn
+	return o
+}
+
+// Return the ciphertext of an ENC[...] value.
+func isEncrypted(s string) (string, bool) {
+	if strings.HasPrefix(s, enc_prefix) && strings.HasSuffix(s, enc_suffix) {
+		return s[len(enc_prefix) : len(s)-len(enc_suffix)], true
+	}
+	return "", false
+}
+
+// Replace every ENC[...] value in the field map with its plain text.
+func (o *Decoder) decryptValues() error {
+	if o.decrypt == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(o.fieldMap))
+	for k := range o.fieldMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, k := range keys {
+		v := o.fieldMap[k]
+		ciphertext, ok := isEncrypted(v.val)
+		if !ok {
+			continue
+		}
+		s, err := o.decrypt(ciphertext)
+		if err != nil {
+			if err = o.failValue(k, err, v.no); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		v.val = s
+	}
+	return getErrors(errs)
+}
+
+// Write an encrypted struct field. Returns false if the field is not tagged
+// for encryption.
+func (o *Encoder) encodeEncryptedField(f reflect.StructField, v1 reflect.Value, depth int) bool {
+	if f.Tag.Get("encrypt") != "true" {
+		return false
+	}
+	if !o.isOption(ENCODE_ZERO_VALUES) && isZeroStruct(v1) {
+		return true
+	}
+	var plaintext string
+	if v1.Kind() == reflect.String {
+		plaintext = v1.String()
+	} else {
+		plaintext = formatValue(v1.Interface())
+	}
+	ciphertext, err := o.encrypt(plaintext)
+	if err != nil {
+		o.errs = append(o.errs, fmt.Errorf("%w (%s)", err, fieldKey(f)))
+		return true
+	}
+	o.write_kv(depth, fieldKey(f), enc_prefix+ciphertext+enc_suffix)
+	return true
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEncryptedValues(t *testing.T) {
+
+	encrypt := func(s string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(s)), nil
+	}
+	decrypt := func(s string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		return string(b), err
+	}
+
+	type cfg struct {
+		User     string
+		Password string `encrypt:"true"`
+		Pin      int    `encrypt:"true"`
+	}
+
+	Convey("Encrypt tagged fields and decrypt them again", t, func() {
+		x := cfg{"rick", "wubba lubba", 1234}
+		var bs []byte
+		So(NewEncoder(x).WithEncrypter(encrypt).ToBytes(&bs), ShouldBeNil)
+		So(string(bs), ShouldEqual, "User = rick\nPassword = ENC[d3ViYmEgbHViYmE=]\nPin = ENC[MTIzNA==]\n")
+
+		var y cfg
+		So(NewDecoder(&y).WithDecrypter(decrypt).DecodeBytes(bs), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+	Convey("Values are unchanged without a decrypter", t, func() {
+		var y cfg
+		So(Decode(&y, "Password = ENC[abc]"), ShouldBeNil)
+		So(y.Password, ShouldEqual, "ENC[abc]")
+	})
+
+	Convey("Force error: Decryption failure", t, func() {
+		var y cfg
+		err := NewDecoder(&y).WithDecrypter(func(string) (string, error) {
+			return "", errors.New("Bad key")
+		}).DecodeString("User = rick\nPassword = ENC[abc]")
+		So(err.Error(), ShouldEqual, "Password: Bad key at line 2")
+	})
+
+}
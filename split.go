@@ -0,0 +1,97 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ToFiles will encode a struct or map to the supplied filename, as ToFile
+// does, but writes each of the named top-level blocks to a file of its own,
+// with an include directive in its place, eg.
+//
+//	err := config.NewEncoder(cfg, config.OVERWRITE_FILE).ToFiles("/etc/app/app.conf",
+//		map[string]string{"Database": "/etc/app/conf.d/database.conf"})
+//
+// writes Database = { ... } to database.conf, and
+//
+//	include /etc/app/conf.d/database.conf as Database
+//
+// to app.conf. Sections maps the key of a block, as named by the struct or
+// map before any case option is applied, to the name of its file. As included
+// files are opened relative to the working directory of the decoder, not to
+// the including file, absolute names are best. A block which encodes to
+// nothing is neither written nor included. The files of the blocks are
+// written before the main file, and every file is subject to the file
+// options of the encoder.
+func (o *Encoder) ToFiles(filename string, sections map[string]string) error {
+	if filename == "" {
+		return errors.New("missing filename")
+	}
+	for key, name := range sections {
+		if !bareKey.MatchString(key) || name == "" || strings.ContainsAny(name, `"=`+lf) {
+			return errors.New("Cannot include a block from a file (" + key + ", " + name + ")")
+		}
+	}
+	o.split, o.parts = sections, make(map[string][]byte)
+	defer func() { o.split, o.parts = nil, nil }()
+	var buf bytes.Buffer
+	if err := o.ToStream(&buf); err != nil {
+		return err
+	}
+	var keys []string
+	for key := range o.parts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := o.writeFile(o.split[key], o.parts[key]); err != nil {
+			return err
+		}
+	}
+	return o.writeFile(filename, buf.Bytes())
+}
+
+// Encode a top-level block to be written to a file of its own by ToFiles,
+// and write an include directive in its place. Returns false if the block is
+// not to be written to a file of its own.
+func (o *Encoder) encodeSection(v1 reflect.Value, key string) bool {
+	name, ok := o.split[key]
+	if !ok {
+		return false
+	}
+	for v1.Kind() == reflect.Ptr || v1.Kind() == reflect.Interface {
+		v1 = v1.Elem()
+	}
+	if v1.Kind() != reflect.Struct && v1.Kind() != reflect.Map || isTimeType(v1.Type()) {
+		o.appendErr("Cannot encode a value to a file of its own (%s)", key)
+		return true
+	}
+	var buf bytes.Buffer
+	sub := *o
+	sub.writer = &buf
+	sub.errs = nil
+	sub.split = nil
+	sub.previous_key = ""
+	sub.path = nil
+	sub.tag = ""
+	sub.v = v1
+	sub.encode()
+	o.errs = append(o.errs, sub.errs...)
+	if buf.Len() == 0 {
+		return true
+	}
+	o.parts[key] = buf.Bytes()
+	if strings.Contains(name, " ") {
+		name = `"` + name + `"`
+	}
+	o.write(1, fmt.Sprintf("include %s as %s\n", name, o.keyCase(key)))
+	return true
+}
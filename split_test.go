@@ -0,0 +1,83 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestToFiles(t *testing.T) {
+
+	type DB struct {
+		Host string
+		Port int
+	}
+	type T struct {
+		Name     string
+		Database DB
+		Cache    DB
+		Backup   DB
+	}
+
+	x := T{Name: "Rick", Database: DB{"db", 5432}, Cache: DB{"cache", 6379}}
+
+	dir, _ := ioutil.TempDir(TEMP_DIR, "GOTEST_CONFIG_")
+	defer os.RemoveAll(dir)
+	app := filepath.Join(dir, "app.conf")
+	sections := map[string]string{
+		"Database": filepath.Join(dir, "database.conf"),
+		"Cache":    filepath.Join(dir, "cache.conf"),
+		"Backup":   filepath.Join(dir, "backup.conf"),
+	}
+
+	Convey("Encode blocks to files of their own", t, func() {
+		os.Remove(app)
+		for _, f := range sections {
+			os.Remove(f)
+		}
+		So(NewEncoder(x).ToFiles(app, sections), ShouldBeNil)
+		bs, _ := ioutil.ReadFile(app)
+		So(string(bs), ShouldEqual, "Name = Rick\ninclude "+sections["Database"]+" as Database\ninclude "+sections["Cache"]+" as Cache\n")
+		bs, _ = ioutil.ReadFile(sections["Database"])
+		So(string(bs), ShouldEqual, "Host = db\nPort = 5432\n")
+		So(fileExists(sections["Backup"]), ShouldBeFalse)
+
+		Convey("The files decode to the same value", func() {
+			var y T
+			So(DecodeFile(app, &y), ShouldBeNil)
+			So(y, ShouldResemble, x)
+		})
+
+		Convey("Force error: Files exist", func() {
+			So(NewEncoder(x).ToFiles(app, sections).Error(), ShouldEqual, "file already exists")
+			So(NewEncoder(x, OVERWRITE_FILE).ToFiles(app, sections), ShouldBeNil)
+		})
+	})
+
+	Convey("Encode map entries to files of their own", t, func() {
+		m := map[string]interface{}{"Name": "Rick", "Database": map[string]int{"Port": 5432}}
+		So(NewEncoder(m, OVERWRITE_FILE).ToFiles(app, sections), ShouldBeNil)
+		bs, _ := ioutil.ReadFile(app)
+		So(string(bs), ShouldEqual, "include "+sections["Database"]+" as Database\nName = Rick\n")
+		bs, _ = ioutil.ReadFile(sections["Database"])
+		So(string(bs), ShouldEqual, "Port = 5432\n")
+	})
+
+	Convey("Force error: Value is not a block", t, func() {
+		err := NewEncoder(x, OVERWRITE_FILE).ToFiles(app, map[string]string{"Name": sections["Database"]})
+		So(err.Error(), ShouldEqual, "Cannot encode a value to a file of its own (Name)")
+	})
+
+	Convey("Force error: Invalid file name", t, func() {
+		err := NewEncoder(x, OVERWRITE_FILE).ToFiles(app, map[string]string{"Database": "a=b.conf"})
+		So(err.Error(), ShouldEqual, "Cannot include a block from a file (Database, a=b.conf)")
+		So(NewEncoder(x).ToFiles("", sections).Error(), ShouldEqual, "missing filename")
+	})
+
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Sub returns every key within the block named by the dotted prefix, with the
+// prefix removed, eg. m.Sub("Database") maps "Database.Host" to "Host". The
+// returned map is a copy.
+func (m StringMap) Sub(prefix string) StringMap {
+	prefix += "."
+	sub := make(StringMap)
+	for k, v := range m {
+		if strings.HasPrefix(k, prefix) {
+			sub[k[len(prefix):]] = v
+		}
+	}
+	return sub
+}
+
+// Tree rebuilds the dotted keys into nested maps, so that m.Tree() may be
+// marshaled directly to JSON or YAML, or used with text/template. Values remain
+// strings. A key which cannot be represented because it conflicts with a plain
+// value, such as A.B when A = 1, is omitted.
+func (m StringMap) Tree() map[string]interface{} {
+	tree, _ := nest(m, func(s string) interface{} { return s })
+	return tree
+}
+
+// Int returns the value of key as an int, or def if the key does not exist or
+// its value cannot be converted. Abbreviations such as 10K are allowed.
+func (m StringMap) Int(key string, def int) int {
+	m.get(key, &def)
+	return def
+}
+
+// Bool returns the value of key as a bool, or def if the key does not exist or
+// its value is not one of true, false, yes, no, on, off, 1 or 0.
+func (m StringMap) Bool(key string, def bool) bool {
+	m.get(key, &def)
+	return def
+}
+
+// Float returns the value of key as a float64, or def if the key does not
+// exist or its value cannot be converted.
+func (m StringMap) Float(key string, def float64) float64 {
+	m.get(key, &def)
+	return def
+}
+
+// Duration returns the value of key as a time.Duration, or def if the key does
+// not exist or its value cannot be converted. Values such as 1h30m are
+// allowed, as are plain integers which are taken to be nanoseconds.
+func (m StringMap) Duration(key string, def time.Duration) time.Duration {
+	m.get(key, &def)
+	return def
+}
+
+// Time returns the value of key as a time.Time, or def if the key does not
+// exist or its value cannot be converted.
+func (m StringMap) Time(key string, def time.Time) time.Time {
+	m.get(key, &def)
+	return def
+}
+
+// Convert the value of key into the value pointed to by x, leaving x
+// unchanged if the key does not exist or the conversion fails.
+func (m StringMap) get(key string, x interface{}) {
+	val, ok := m[key]
+	if !ok {
+		return
+	}
+	v1 := reflect.ValueOf(x).Elem()
+	tmp := reflect.New(v1.Type()).Elem()
+	tmp.Set(v1)
+	if setScalar(tmp, val) == nil {
+		v1.Set(tmp)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStringMapSub(t *testing.T) {
+
+	smap, _ := Parse(`
+		Name = Rick
+		Database {
+			Host = citadel
+			Pool {
+				Size = 10
+			}
+		}
+		DatabaseName = portal
+	`)
+
+	Convey("Extract the keys under a prefix", t, func() {
+		So(smap.Sub("Database"), ShouldResemble, StringMap{"Host": "citadel", "Pool.Size": "10"})
+		So(smap.Sub("Database.Pool"), ShouldResemble, StringMap{"Size": "10"})
+		So(smap.Sub("Database").Sub("Pool"), ShouldResemble, StringMap{"Size": "10"})
+	})
+
+	Convey("Unknown prefixes and plain keys return an empty map", t, func() {
+		So(smap.Sub("Server"), ShouldResemble, StringMap{})
+		So(smap.Sub("Name"), ShouldResemble, StringMap{})
+	})
+
+}
+
+func TestStringMapTree(t *testing.T) {
+
+	Convey("Rebuild nested maps from dotted keys", t, func() {
+		smap, _ := Parse(`
+			Name = Rick
+			Database {
+				Host = citadel
+				Pool {
+					Size = 10
+				}
+			}
+		`)
+		So(smap.Tree(), ShouldResemble, map[string]interface{}{
+			"Name": "Rick",
+			"Database": map[string]interface{}{
+				"Host": "citadel",
+				"Pool": map[string]interface{}{"Size": "10"},
+			},
+		})
+	})
+
+	Convey("Omit keys which conflict with a plain value", t, func() {
+		smap := StringMap{"A": "1", "A.B": "2", "C": "3"}
+		So(smap.Tree(), ShouldResemble, map[string]interface{}{"A": "1", "C": "3"})
+	})
+
+}
+
+func TestStringMapGetters(t *testing.T) {
+
+	smap, _ := Parse(`
+		Workers = 10K
+		Debug   = on
+		Ratio   = 1.5M
+		Timeout = 1m30s
+		Delay   = 500
+		Started = 2018-06-01 12:30:00
+		Name    = Rick
+	`)
+
+	Convey("Convert values as the decoder would", t, func() {
+		So(smap.Int("Workers", 1), ShouldEqual, 10000)
+		So(smap.Bool("Debug", false), ShouldBeTrue)
+		So(smap.Float("Ratio", 0), ShouldEqual, 1.5e6)
+		So(smap.Duration("Timeout", 0), ShouldEqual, 90*time.Second)
+		So(smap.Duration("Delay", 0), ShouldEqual, 500*time.Nanosecond)
+		So(smap.Time("Started", time.Time{}), ShouldEqual, time.Date(2018, 6, 1, 12, 30, 0, 0, time.UTC))
+	})
+
+	Convey("Return the default for missing keys and invalid values", t, func() {
+		def := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		So(smap.Int("Missing", 7), ShouldEqual, 7)
+		So(smap.Int("Name", 7), ShouldEqual, 7)
+		So(smap.Bool("Name", true), ShouldBeTrue)
+		So(smap.Float("Name", 2.5), ShouldEqual, 2.5)
+		So(smap.Duration("Name", time.Second), ShouldEqual, time.Second)
+		So(smap.Time("Name", def), ShouldEqual, def)
+	})
+
+	Convey("Decode a duration field", t, func() {
+		var x struct{ Timeout time.Duration }
+		So(Decode(&x, "Timeout = 2h"), ShouldBeNil)
+		So(x.Timeout, ShouldEqual, 2*time.Hour)
+	})
+
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// The config tag of a struct field, which sets the key of the field and any
+// alternative keys it accepts, eg.
+//
+//	Timeout int `config:"timeout,alias=timeout_seconds,alias=timeoutSecs"`
+//
+// An empty name keeps the field name. A name of "-" skips the field. The
+// deprecated option causes a warning when the field is given a value. See
+// Decoder.Warnings.
+type fieldTag struct {
+	name       string
+	aliases    []string
+	deprecated bool
+}
+
+func parseFieldTag(f reflect.StructField) fieldTag {
+	var t fieldTag
+	a := strings.Split(f.Tag.Get("config"), ",")
+	t.name = a[0]
+	for _, s := range a[1:] {
+		if strings.HasPrefix(s, "alias=") && len(s) > 6 {
+			t.aliases = append(t.aliases, s[6:])
+		}
+		if s == "deprecated" {
+			t.deprecated = true
+		}
+	}
+	if t.name == "" {
+		t.name = f.Name
+	}
+	return t
+}
+
+// Return the key of a struct field, or "" if the field is skipped or not
+// exported.
+func fieldKey(f reflect.StructField) string {
+	if !isPublic(f.Name) {
+		return ""
+	}
+	if name := parseFieldTag(f).name; name != "-" {
+		return name
+	}
+	return ""
+}
+
+// Rename the keys of a field's aliases in the field map to the key of the
+// field, including the keys within an aliased block. A field given a value
+// under more than one of its names is a conflict.
+func (o *Decoder) applyAliases(parent_key, key string, aliases []string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+	found := ""
+	if o.hasKey(key) {
+		found = key[len(parent_key):]
+		found = strings.TrimPrefix(found, ".")
+	}
+	for _, alias := range aliases {
+		var moved bool
+		for _, prefix := range o.aliasPaths(parent_key, alias) {
+			for k, vs := range o.fieldMap {
+				if k != prefix && !strings.HasPrefix(k, prefix+".") {
+					continue
+				}
+				if found != "" && found != alias {
+					return o.fail(key, "Key conflict ("+found+", "+alias+")", vs.no)
+				}
+				delete(o.fieldMap, k)
+				o.fieldMap[key+k[len(prefix):]] = vs
+				moved = true
+			}
+		}
+		if moved {
+			found = alias
+		}
+	}
+	return nil
+}
+
+// Return the keys under which an alias may appear in the source, following
+// the case options of the decoder.
+func (o *Decoder) aliasPaths(parent_key, alias string) []string {
+	if parent_key == "" {
+		return []string{alias}
+	}
+	var a []string
+	for _, p := range o.keyVariants(parent_key) {
+		a = append(a, p+"."+alias)
+	}
+	return a
+}
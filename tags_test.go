@@ -0,0 +1,53 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigTags(t *testing.T) {
+
+	type server struct {
+		Timeout int `config:"timeout,alias=timeout_seconds,alias=timeoutSecs"`
+		Secret  string `config:"-"`
+	}
+	type T struct {
+		Name   string
+		Server server `config:",alias=Backend"`
+	}
+
+	Convey("Decode fields by tag name and aliases", t, func() {
+		var x T
+		So(Decode(&x, "Name = a\nServer { timeout = 1 }"), ShouldBeNil)
+		So(x.Server.Timeout, ShouldEqual, 1)
+
+		var y T
+		So(Decode(&y, "Backend { timeoutSecs = 2 }"), ShouldBeNil)
+		So(y.Server.Timeout, ShouldEqual, 2)
+
+		var z T
+		So(Decode(&z, "server { timeout_seconds = 3 }", IGNORE_CASE), ShouldBeNil)
+		So(z.Server.Timeout, ShouldEqual, 3)
+	})
+
+	Convey("Encode fields by tag name", t, func() {
+		x := T{Name: "a", Server: server{Timeout: 5, Secret: "s"}}
+		b, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "Name = a\nServer = {\n  timeout = 5\n}\n")
+	})
+
+	Convey("Force error: Skipped fields and conflicting aliases", t, func() {
+		var x T
+		So(Decode(&x, "Server { Secret = s }").Error(), ShouldEqual, "Extra field (Server.Secret) at line 1")
+		err := Decode(&x, "Server {\ntimeout = 1\ntimeout_seconds = 2\n}")
+		So(err.Error(), ShouldEqual, "Key conflict (timeout, timeout_seconds) at line 3")
+		err = Decode(&x, "Server { timeoutSecs = 1 }\nBackend { timeout = 2 }")
+		So(err.Error(), ShouldStartWith, "Key conflict (Server, Backend) at line")
+	})
+
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+)
+
+// Template encodes a struct as an example configuration file. Every field is
+// written, including zero values. A field's comment tag is written as a comment
+// above the field, and a zero valued field is written using its default tag,
+// if one exists. Example:
+//
+//	type Config struct {
+//		Port int    `comment:"TCP port to listen on" default:"8080"`
+//		Host string `comment:"Host name or address"`
+//	}
+func Template(x interface{}, options ...int) ([]byte, error) {
+	return NewEncoder(x, options...).Template()
+}
+
+// Template encodes the value of the encoder as an example configuration file,
+// applying the settings of the encoder. See Template.
+func (o *Encoder) Template() ([]byte, error) {
+	o.options |= ENCODE_ZERO_VALUES
+	o.template = true
+	var buf bytes.Buffer
+	o.writer = &buf
+	o.encode()
+	return buf.Bytes(), getErrors(o.errs)
+}
+
+// Write the comment for a template field. If the field is zero and has a
+// default value, write the default and return true.
+func (o *Encoder) encodeTemplateField(f reflect.StructField, v1 reflect.Value, depth int) bool {
+	if c := f.Tag.Get("comment"); c != "" {
+		for _, s := range strings.Split(c, lf) {
+			o.write(depth, "# "+s+lf)
+		}
+	}
+	def, ok := f.Tag.Lookup("default")
+	if !ok || !isZeroStruct(v1) {
+		return false
+	}
+	o.write_kv(depth, fieldKey(f), def)
+	return true
+}
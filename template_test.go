@@ -0,0 +1,62 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTemplate(t *testing.T) {
+
+	type server struct {
+		Host string `comment:"Host name or address"`
+		Port int    `comment:"TCP port" default:"8080"`
+	}
+	type cfg struct {
+		Name    string `comment:"Application name\nShown in the title bar" default:"Portal Gun"`
+		Debug   bool
+		Retries int
+		Server  server `comment:"Server settings"`
+	}
+
+	Convey("Generate a template from an empty struct", t, func() {
+		var x cfg
+		expected := `# Application name
+# Shown in the title bar
+Name = Portal Gun
+Debug = False
+Retries = 0
+# Server settings
+Server = {
+  # Host name or address
+  Host = ""
+  # TCP port
+  Port = 8080
+}
+`
+		b, err := Template(x)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, expected)
+	})
+
+	Convey("Set values take precedence over defaults", t, func() {
+		x := cfg{Name: "Rick", Server: server{Port: 81}}
+		b, err := Template(&x, ENCODE_SNAKE_CASE)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldContainSubstring, "name = Rick\n")
+		So(string(b), ShouldContainSubstring, "  port = 81\n")
+	})
+
+	Convey("Generated template decodes back into the struct", t, func() {
+		var x, y cfg
+		b, err := Template(x)
+		So(err, ShouldBeNil)
+		So(Decode(&y, b), ShouldBeNil)
+		So(y.Name, ShouldEqual, "Portal Gun")
+		So(y.Server.Port, ShouldEqual, 8080)
+	})
+
+}
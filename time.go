@@ -0,0 +1,111 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Assign a time value, applying the time options and the tags of the field
+// being decoded. A field tagged with a layout, eg. `layout:"Jan 2, 2006"`, is
+// parsed with that layout rather than one of the built-in layouts.
+func (o *Decoder) setTime(v1 reflect.Value, val string) error {
+	unit := o.tag.Get("epoch")
+	if unit == "" && isOption(ALLOW_EPOCH_TIMES, o.options) {
+		unit = "s"
+	}
+	if unit != "" {
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			v1.Set(reflect.ValueOf(epochTime(n, unit)))
+			return nil
+		}
+	}
+	loc := o.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	if layout := o.tag.Get("layout"); layout != "" {
+		t, err := time.ParseInLocation(layout, val, loc)
+		if err != nil {
+			return err
+		}
+		v1.Set(reflect.ValueOf(t))
+		return nil
+	}
+	return set_time_in(v1, val, loc)
+}
+
+// SetLocation sets the location of dates and timestamps which do not specify
+// an offset, eg. 2017-12-25 08:10:00. By default they are UTC.
+func (o *Decoder) SetLocation(loc *time.Location) *Decoder {
+	o.loc = loc
+	return o
+}
+
+// SetTimeLayout sets the layout of every time value written by the encoder,
+// eg. time.RFC3339Nano, rather than choosing one of the built-in layouts from
+// the value. A layout tag on a field takes precedence.
+func (o *Encoder) SetTimeLayout(layout string) *Encoder {
+	o.timeLayout = layout
+	return o
+}
+
+var locationType = reflect.TypeOf((*time.Location)(nil))
+
+// Assign a *time.Location by name, eg. America/New_York, UTC or Local.
+func set_location(v1 reflect.Value, val string) error {
+	loc, err := time.LoadLocation(val)
+	if err != nil {
+		return errors.New("Unknown time zone (" + val + ")")
+	}
+	v1.Set(reflect.ValueOf(loc))
+	return nil
+}
+
+func (o *Encoder) encodeLocation(v1 reflect.Value, depth int, parent_key string) bool {
+	if v1.IsNil() {
+		if o.isOption(ENCODE_ZERO_VALUES) {
+			o.write_kv(depth, parent_key, "UTC")
+		}
+		return true
+	}
+	o.write_kv(depth, parent_key, v1.Interface().(*time.Location).String())
+	return true
+}
+
+// Return the time of an epoch in seconds, or milliseconds if unit is "ms".
+func epochTime(n int64, unit string) time.Time {
+	if unit == "ms" {
+		return time.UnixMilli(n).UTC()
+	}
+	return time.Unix(n, 0).UTC()
+}
+
+// Write a time.Time tagged with `epoch:"s"` or `epoch:"ms"` as an epoch, or
+// one tagged with a layout, eg. `layout:"Jan 2, 2006"`, in that layout.
+// A zero time is not written unless zero values are encoded. Returns false
+// if the field being encoded is not tagged.
+func (o *Encoder) encodeTaggedTime(v1 reflect.Value, depth int, parent_key string) bool {
+	unit, layout := o.tag.Get("epoch"), o.tag.Get("layout")
+	if unit == "" && layout == "" {
+		return false
+	}
+	t := v1.Interface().(time.Time)
+	if !o.isOption(ENCODE_ZERO_VALUES) && t.IsZero() {
+		return true
+	}
+	switch {
+	case unit == "ms":
+		o.write_kv(depth, parent_key, t.UnixMilli())
+	case unit != "":
+		o.write_kv(depth, parent_key, t.Unix())
+	default:
+		o.write_kv(depth, parent_key, t.Format(layout))
+	}
+	return true
+}
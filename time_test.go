@@ -0,0 +1,174 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEpochTimes(t *testing.T) {
+
+	type cfg struct {
+		Created time.Time
+		Updated time.Time `epoch:"ms"`
+		Expires time.Time `epoch:"s"`
+	}
+
+	when := time.Date(2017, 12, 25, 8, 10, 0, 0, time.UTC)
+
+	Convey("Decode epoch seconds with ALLOW_EPOCH_TIMES", t, func() {
+		var x cfg
+		So(Decode(&x, "Created = 1514189400", ALLOW_EPOCH_TIMES), ShouldBeNil)
+		So(x.Created, ShouldResemble, when)
+		So(Decode(&x, "Created = 2017-12-25 08:10:00", ALLOW_EPOCH_TIMES), ShouldBeNil)
+		So(x.Created, ShouldResemble, when)
+	})
+
+	Convey("Decode epochs of tagged fields", t, func() {
+		var x cfg
+		So(Decode(&x, "Updated = 1514189400250\nExpires = 1514189400"), ShouldBeNil)
+		So(x.Updated, ShouldResemble, when.Add(250*time.Millisecond))
+		So(x.Expires, ShouldResemble, when)
+	})
+
+	Convey("Encode tagged fields as epochs", t, func() {
+		x := cfg{when, when.Add(250 * time.Millisecond), when}
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "Created = 2017-12-25 08:10:00\nUpdated = 1514189400250\nExpires = 1514189400\n")
+		var y cfg
+		So(Decode(&y, b1), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+	Convey("Force error: Epoch without the option or a tag", t, func() {
+		var x cfg
+		So(Decode(&x, "Created = 1514189400"), ShouldNotBeNil)
+	})
+
+}
+
+func TestTimeLayoutTag(t *testing.T) {
+
+	type cfg struct {
+		Start  time.Time `layout:"2006-01-02 15:04"`
+		Stamp  time.Time `layout:"Jan 2, 2006 at 3:04pm (MST)"`
+		Plain  time.Time
+		Events map[string]time.Time `layout:"02/01/2006"`
+	}
+
+	Convey("Decode and encode times with a layout tag", t, func() {
+		var x cfg
+		src := `Start = 2017-12-25 08:10
+Stamp = Dec 25, 2017 at 8:10am (UTC)
+Plain = 2017-12-25
+Events = {
+  Launch = 25/12/2017
+}
+`
+		So(Decode(&x, src), ShouldBeNil)
+		So(x.Start, ShouldResemble, time.Date(2017, 12, 25, 8, 10, 0, 0, time.UTC))
+		So(x.Stamp.Equal(x.Start), ShouldBeTrue)
+		So(x.Events["Launch"], ShouldResemble, time.Date(2017, 12, 25, 0, 0, 0, 0, time.UTC))
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, src)
+	})
+
+	Convey("Force error: A value which does not match the layout", t, func() {
+		var x cfg
+		So(Decode(&x, "Start = 2017-12-25 08:10:00"), ShouldNotBeNil)
+	})
+
+	Convey("The tag of a container field does not apply to nested times", t, func() {
+		type event struct {
+			At time.Time
+		}
+		type nested struct {
+			Events map[string]event                `epoch:"s"`
+			Groups map[string]map[string]time.Time `layout:"02/01/2006"`
+		}
+		var x nested
+		So(Decode(&x, "Events.Launch.At = 2017-12-25"), ShouldBeNil)
+		So(x.Events["Launch"].At, ShouldResemble, time.Date(2017, 12, 25, 0, 0, 0, 0, time.UTC))
+		x.Groups = map[string]map[string]time.Time{"Main": {"Launch": x.Events["Launch"].At}}
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldContainSubstring, "Launch = 2017-12-25\n")
+		So(string(b1), ShouldNotContainSubstring, "25/12/2017")
+	})
+
+}
+
+func TestLocation(t *testing.T) {
+
+	type cfg struct {
+		Zone    *time.Location
+		Start   time.Time
+		Stamp   time.Time
+		Offset  time.Time
+		Clock   time.Time
+		Release time.Time `layout:"Jan 2, 2006"`
+	}
+
+	Convey("Decode and encode time zones", t, func() {
+		var x cfg
+		So(Decode(&x, "Zone = America/New_York"), ShouldBeNil)
+		So(x.Zone.String(), ShouldEqual, "America/New_York")
+		b1, err := Encode(struct{ Zone *time.Location }{x.Zone})
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "Zone = America/New_York\n")
+	})
+
+	Convey("Parse offset-less times in the location set with SetLocation", t, func() {
+		var x cfg
+		loc := time.FixedZone("EST", -5*3600)
+		src := `
+			Start   = 2017-12-25
+			Stamp   = 2017-12-25 08:10:00
+			Offset  = 2017-12-25 08:10:00 +0100
+			Clock   = 08:10:00
+			Release = Dec 25, 2017
+		`
+		So(NewDecoder(&x).SetLocation(loc).DecodeString(src), ShouldBeNil)
+		So(x.Start, ShouldResemble, time.Date(2017, 12, 25, 0, 0, 0, 0, loc))
+		So(x.Stamp, ShouldResemble, time.Date(2017, 12, 25, 8, 10, 0, 0, loc))
+		So(x.Offset.Equal(time.Date(2017, 12, 25, 7, 10, 0, 0, time.UTC)), ShouldBeTrue)
+		So(x.Clock, ShouldResemble, time.Date(0, 1, 1, 8, 10, 0, 0, time.UTC))
+		So(x.Release, ShouldResemble, time.Date(2017, 12, 25, 0, 0, 0, 0, loc))
+	})
+
+	Convey("Force error: Unknown time zone", t, func() {
+		var x cfg
+		err := Decode(&x, "Zone = Mars/Olympus_Mons")
+		So(err.Error(), ShouldEqual, "Zone: Unknown time zone (Mars/Olympus_Mons) at line 1")
+	})
+
+}
+
+func TestEncoderTimeLayout(t *testing.T) {
+
+	type cfg struct {
+		Midnight time.Time
+		Stamp    time.Time
+		Release  time.Time `layout:"Jan 2, 2006"`
+	}
+
+	Convey("Encode times with a global layout", t, func() {
+		midnight := time.Date(2017, 12, 25, 0, 0, 0, 0, time.UTC)
+		x := cfg{midnight, midnight.Add(1500 * time.Millisecond), midnight}
+		var buf bytes.Buffer
+		err := NewEncoder(x).SetTimeLayout(time.RFC3339Nano).ToStream(&buf)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "Midnight = 2017-12-25T00:00:00Z\nStamp = 2017-12-25T00:00:01.5Z\nRelease = Dec 25, 2017\n")
+		var y cfg
+		So(Decode(&y, buf.Bytes()), ShouldBeNil)
+		So(y, ShouldResemble, x)
+	})
+
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A TraceEntry records one decision made while parsing or decoding: the rule
+// which matched a line of the source and the key and value which resulted,
+// a reference which was resolved, or the field to which a value was
+// assigned.
+type TraceEntry struct {
+	Line  int    // Line number in source
+	Rule  string // Rule applied, eg. keyval, heredoc, reference or assign
+	Text  string // Source line, or for assign the key matched in the source
+	Key   string // Resulting dotted key, if any
+	Value string // Resulting value, if any
+}
+
+func (e TraceEntry) String() string {
+	s := fmt.Sprintf("line %d: %s %q", e.Line, e.Rule, e.Text)
+	if e.Key != "" {
+		s += fmt.Sprintf(" -> %s = %q", e.Key, e.Value)
+	}
+	return s
+}
+
+// SetTrace turns tracing on or off. While tracing, the parser records the
+// rule which matched each line and the key and value which resulted. See
+// Trace.
+func (o *Parser) SetTrace(on bool) *Parser {
+	o.tracing = on
+	return o
+}
+
+// Trace returns the entries recorded while tracing, in the order they were
+// made.
+func (o *Parser) Trace() []TraceEntry {
+	return o.trace
+}
+
+// SetTrace turns tracing on or off. While tracing, the decoder records the
+// decisions of its parser and the field to which each value is assigned.
+// See Trace.
+func (o *Decoder) SetTrace(on bool) *Decoder {
+	o.tracing = on
+	return o
+}
+
+// Trace returns the entries recorded by the most recent decode while tracing,
+// including those of included files, in the order they were made.
+func (o *Decoder) Trace() []TraceEntry {
+	return o.trace
+}
+
+// Match a line to a rule, recording the match while tracing. A rule which
+// matched the same line but did not apply is replaced.
+func (o *Parser) match(rule, s string, m *matches) bool {
+	if !findSubmatch(rule, s, m) {
+		return false
+	}
+	if o.tracing {
+		e := TraceEntry{Line: o.lineno, Rule: rule, Text: strings.TrimSpace(s)}
+		if n := len(o.trace); n > 0 && o.trace[n-1].Line == o.lineno && o.trace[n-1].Key == "" {
+			o.trace[n-1] = e
+		} else {
+			o.trace = append(o.trace, e)
+		}
+	}
+	return true
+}
+
+// Record the key and value which resulted from the last rule matched.
+func (o *Parser) traceKey(key, val string) {
+	n := len(o.trace)
+	if !o.tracing || n == 0 {
+		return
+	}
+	if o.trace[n-1].Key != "" {
+		o.trace = append(o.trace, o.trace[n-1])
+		n++
+	}
+	o.trace[n-1].Key = key
+	o.trace[n-1].Value = val
+}
+
+// Prefix the keys recorded since entry n with the key of a block.
+func (o *Parser) traceBlock(n int, key string) {
+	for i := max(n, 0); i < len(o.trace); i++ {
+		if o.trace[i].Key != "" {
+			o.trace[i].Key = key + "." + o.trace[i].Key
+		}
+	}
+}
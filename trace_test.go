@@ -0,0 +1,62 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTrace(t *testing.T) {
+
+	src := `Name = "Rick Sanchez"
+Server {
+  Host = localhost
+  Url = http://${Server.Host}
+}
+Note = <<END
+  multi
+END
+`
+
+	Convey("The parser records the rule of each line", t, func() {
+		p := NewParser().SetTrace(true)
+		_, err := p.Parse([]byte(src))
+		So(err, ShouldBeNil)
+		So(p.Trace(), ShouldResemble, []TraceEntry{
+			{1, "keyval", `Name = "Rick Sanchez"`, "Name", "Rick Sanchez"},
+			{2, "open_brace", "Server {", "", ""},
+			{3, "keyval", "Host = localhost", "Server.Host", "localhost"},
+			{4, "keyval", "Url = http://${Server.Host}", "Server.Url", "http://${Server.Host}"},
+			{5, "close_brace", "}", "", ""},
+			{6, "heredoc", "Note = <<END", "Note", "  multi"},
+			{4, "reference", "", "Server.Url", "http://localhost"},
+		})
+		So(p.Trace()[0].String(), ShouldEqual, `line 1: keyval "Name = \"Rick Sanchez\"" -> Name = "Rick Sanchez"`)
+	})
+
+	Convey("Nothing is recorded unless tracing", t, func() {
+		p := NewParser()
+		p.Parse([]byte(src))
+		So(p.Trace(), ShouldBeEmpty)
+	})
+
+	Convey("The decoder records the field each value is assigned to", t, func() {
+		var x struct {
+			MaxConns int
+			Tags     map[string]string
+		}
+		d := NewDecoder(&x, ALLOW_SNAKE_CASE).SetTrace(true)
+		So(d.DecodeString("max_conns = 10\ntags { a = b; c = d }"), ShouldBeNil)
+		trace := d.Trace()
+		So(trace[:3], ShouldResemble, []TraceEntry{
+			{1, "keyval", "max_conns = 10", "max_conns", "10"},
+			{2, "inline_block", "tags { a = b; c = d }", "tags.a", "b"},
+			{2, "inline_block", "tags { a = b; c = d }", "tags.c", "d"},
+		})
+		So(trace, ShouldContain, TraceEntry{1, "assign", "max_conns", "MaxConns", "10"})
+	})
+
+}
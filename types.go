@@ -0,0 +1,162 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+)
+
+// A textType converts a type which is not a built-in scalar, such as net.IP,
+// to and from a single string value.
+type textType struct {
+	parse  func(s string) (interface{}, error)
+	format func(x interface{}) string
+}
+
+// Types which are decoded and encoded as a single value. A pointer to one of
+// these types is supported as well.
+var textTypes = map[reflect.Type]textType{
+	reflect.TypeOf(net.IP{}): {
+		func(s string) (interface{}, error) {
+			if ip := net.ParseIP(s); ip != nil {
+				return ip, nil
+			}
+			return nil, errors.New("Invalid IP address")
+		},
+		func(x interface{}) string { return x.(net.IP).String() },
+	},
+	reflect.TypeOf(net.IPNet{}): {
+		func(s string) (interface{}, error) {
+			_, n, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, errors.New("Invalid CIDR address")
+			}
+			return *n, nil
+		},
+		func(x interface{}) string {
+			n := x.(net.IPNet)
+			return n.String()
+		},
+	},
+	reflect.TypeOf(net.HardwareAddr{}): {
+		func(s string) (interface{}, error) {
+			a, err := net.ParseMAC(s)
+			if err != nil {
+				return nil, errors.New("Invalid hardware address")
+			}
+			return a, nil
+		},
+		func(x interface{}) string { return x.(net.HardwareAddr).String() },
+	},
+	reflect.TypeOf(netip.Addr{}): {
+		func(s string) (interface{}, error) {
+			a, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, errors.New("Invalid IP address")
+			}
+			return a, nil
+		},
+		func(x interface{}) string { return x.(netip.Addr).String() },
+	},
+	reflect.TypeOf(netip.AddrPort{}): {
+		func(s string) (interface{}, error) {
+			a, err := netip.ParseAddrPort(s)
+			if err != nil {
+				return nil, errors.New("Invalid address and port")
+			}
+			return a, nil
+		},
+		func(x interface{}) string { return x.(netip.AddrPort).String() },
+	},
+	reflect.TypeOf(netip.Prefix{}): {
+		func(s string) (interface{}, error) {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, errors.New("Invalid CIDR address")
+			}
+			return p, nil
+		},
+		func(x interface{}) string { return x.(netip.Prefix).String() },
+	},
+	fileModeType: {parseFileMode, formatFileMode},
+	urlType: {
+		parseURL,
+		func(x interface{}) string {
+			u := x.(url.URL)
+			return u.String()
+		},
+	},
+}
+
+// Return the textType of t, or of the type t points to.
+func textTypeOf(t reflect.Type) (textType, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	tt, ok := textTypes[t]
+	return tt, ok
+}
+
+// Return true if t, or the type t points to, is decoded from a single value.
+func isTextType(t reflect.Type) bool {
+	_, ok := textTypeOf(t)
+	return ok
+}
+
+// Assign a string to a text type, or a pointer to one. A nil pointer is
+// allocated. An empty string assigns the zero value.
+func set_text(v1 reflect.Value, val string) error {
+	if val == "" {
+		v1.Set(reflect.Zero(v1.Type()))
+		return nil
+	}
+	tt, _ := textTypeOf(v1.Type())
+	x, err := tt.parse(val)
+	if err != nil {
+		return err
+	}
+	if v1.Kind() == reflect.Ptr {
+		p := reflect.New(v1.Type().Elem())
+		p.Elem().Set(reflect.ValueOf(x))
+		v1.Set(p)
+		return nil
+	}
+	v1.Set(reflect.ValueOf(x))
+	return nil
+}
+
+// Return the value of a text type, or a pointer to one, as a string. A nil
+// pointer or a zero value is an empty string.
+func formatText(v1 reflect.Value) string {
+	if v1.Kind() == reflect.Ptr {
+		if v1.IsNil() {
+			return ""
+		}
+		v1 = v1.Elem()
+	}
+	if v1.IsZero() {
+		return ""
+	}
+	tt, _ := textTypeOf(v1.Type())
+	return tt.format(v1.Interface())
+}
+
+func (o *Encoder) encodeText(v1 reflect.Value, depth int, parent_key string) bool {
+	s := formatText(v1)
+	if s == "" {
+		if !o.isOption(ENCODE_ZERO_VALUES) {
+			return true
+		}
+		s = `""`
+	} else if o.quoteAll {
+		s = o.quote(s)
+	}
+	o.write_kv(depth, parent_key, s)
+	return true
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNetTypes(t *testing.T) {
+
+	type cfg struct {
+		IP      net.IP
+		Network *net.IPNet
+		MAC     net.HardwareAddr
+		Addr    netip.Addr
+		Listen  netip.AddrPort
+		Allow   netip.Prefix
+	}
+
+	src := `IP = 192.168.1.10
+Network = 10.0.0.0/8
+MAC = 00:1a:2b:3c:4d:5e
+Addr = 2001:db8::1
+Listen = [::1]:8080
+Allow = 172.16.0.0/12
+`
+
+	Convey("Decode network address types", t, func() {
+		var x cfg
+		So(Decode(&x, src), ShouldBeNil)
+		So(x.IP.Equal(net.ParseIP("192.168.1.10")), ShouldBeTrue)
+		So(x.Network.String(), ShouldEqual, "10.0.0.0/8")
+		So(x.MAC.String(), ShouldEqual, "00:1a:2b:3c:4d:5e")
+		So(x.Addr == netip.MustParseAddr("2001:db8::1"), ShouldBeTrue)
+		So(x.Listen.Port(), ShouldEqual, 8080)
+		So(x.Allow.Bits(), ShouldEqual, 12)
+	})
+
+	Convey("Encode network address types", t, func() {
+		var x cfg
+		So(Decode(&x, src), ShouldBeNil)
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, src)
+		b1, err = Encode(cfg{})
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, "")
+	})
+
+	Convey("Zero values are encoded and decoded as empty strings", t, func() {
+		b1, err := Encode(cfg{}, ENCODE_ZERO_VALUES)
+		So(err, ShouldBeNil)
+		var x cfg
+		x.IP = net.ParseIP("127.0.0.1")
+		So(Decode(&x, b1), ShouldBeNil)
+		So(x, ShouldResemble, cfg{})
+	})
+
+	Convey("Load network address types from the environment", t, func() {
+		os.Setenv("NETTEST_LISTEN", "127.0.0.1:9000")
+		defer os.Unsetenv("NETTEST_LISTEN")
+		var x cfg
+		So(NewLoader().AddEnv("NETTEST_").Load(&x), ShouldBeNil)
+		So(x.Listen.String(), ShouldEqual, "127.0.0.1:9000")
+	})
+
+	Convey("Force error: Invalid network addresses", t, func() {
+		errs := map[string]string{
+			"IP = 300.1.1.1":          "IP: Invalid IP address at line 1",
+			"Network = 10.0.0.0/33":   "Network: Invalid CIDR address at line 1",
+			"MAC = 00:1a:2b":          "MAC: Invalid hardware address at line 1",
+			"Addr = localhost":        "Addr: Invalid IP address at line 1",
+			"Listen = 127.0.0.1":      "Listen: Invalid address and port at line 1",
+			"Allow = 172.16.0.0/12/3": "Allow: Invalid CIDR address at line 1",
+		}
+		for src, msg := range errs {
+			var x cfg
+			err := Decode(&x, src)
+			So(err.Error(), ShouldEqual, msg)
+		}
+	})
+
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A UnitFunc converts the number preceding a custom suffix to the value
+// assigned to a numeric field.
+type UnitFunc func(v float64) (float64, error)
+
+var (
+	unitsMu sync.RWMutex
+	units   = make(map[string]UnitFunc)
+)
+
+// RegisterUnit registers a custom numeric suffix with a multiplier, eg.
+// RegisterUnit("ms", 0.001) or RegisterUnit("MB/s", 1e6). The suffix may
+// follow the number directly or after a space, eg. 250ms or 10 MB/s. Custom
+// suffixes are tried before the built-in K..E suffixes, the longest first.
+// A value which is not a whole number cannot be assigned to an integer field.
+func RegisterUnit(suffix string, multiplier float64) {
+	RegisterUnitFunc(suffix, func(v float64) (float64, error) {
+		return v * multiplier, nil
+	})
+}
+
+// RegisterUnitFunc registers a custom numeric suffix with a conversion
+// function. A nil function removes the registration. See RegisterUnit.
+func RegisterUnitFunc(suffix string, fn UnitFunc) {
+	unitsMu.Lock()
+	defer unitsMu.Unlock()
+	if fn == nil {
+		delete(units, suffix)
+		return
+	}
+	units[suffix] = fn
+}
+
+// Find the longest registered suffix of s. The number preceding the suffix
+// and the conversion function are returned.
+func unitFor(s string) (string, UnitFunc, bool) {
+	unitsMu.RLock()
+	defer unitsMu.RUnlock()
+	var suffix string
+	for k := range units {
+		if len(k) > len(suffix) && strings.HasSuffix(s, k) {
+			suffix = k
+		}
+	}
+	if suffix == "" {
+		return "", nil, false
+	}
+	num := strings.TrimSpace(s[:len(s)-len(suffix)])
+	if num == "" {
+		return "", nil, false
+	}
+	return num, units[suffix], true
+}
+
+// Convert a number with a registered suffix.
+func convertUnit(num string, fn UnitFunc, b int) (float64, error) {
+	v, err := strconv.ParseFloat(strings.Replace(num, ",", "", -1), b)
+	if err != nil {
+		return 0, err
+	}
+	return fn(v)
+}
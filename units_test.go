@@ -0,0 +1,70 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUnits(t *testing.T) {
+
+	RegisterUnit("ms", 0.001)
+	RegisterUnit("rps", 1)
+	RegisterUnit("MB/s", 1e6)
+	RegisterUnit("KB/s", 1e3)
+	RegisterUnitFunc("F", func(v float64) (float64, error) {
+		if v < -459.67 {
+			return 0, errors.New("Below absolute zero")
+		}
+		return (v - 32) * 5 / 9, nil
+	})
+	defer func() {
+		for _, k := range []string{"ms", "rps", "MB/s", "KB/s", "F"} {
+			RegisterUnitFunc(k, nil)
+		}
+	}()
+
+	type cfg struct {
+		Timeout float64
+		Rate    int
+		Speed   int64
+		Upload  uint32
+		Temp    float32
+		Size    int
+	}
+
+	Convey("Decode values with custom suffixes", t, func() {
+		var x cfg
+		src := `
+			Timeout = 250ms
+			Rate    = 1,500 rps
+			Speed   = 10 MB/s
+			Upload  = 1.5KB/s
+			Temp    = 212F
+			Size    = 2K
+		`
+		So(Decode(&x, src), ShouldBeNil)
+		So(x, ShouldResemble, cfg{0.25, 1500, 10000000, 1500, 100, 2000})
+	})
+
+	Convey("A removed suffix is no longer recognized", t, func() {
+		RegisterUnitFunc("rps", nil)
+		var x cfg
+		So(Decode(&x, "Rate = 10rps"), ShouldNotBeNil)
+		RegisterUnit("rps", 1)
+	})
+
+	Convey("Force error: Invalid values with custom suffixes", t, func() {
+		var x cfg
+		So(Decode(&x, "Rate = 1.5ms"), ShouldNotBeNil)
+		So(Decode(&x, "Rate = ms"), ShouldNotBeNil)
+		So(Decode(&x, "Timeout = x ms"), ShouldNotBeNil)
+		err := Decode(&x, "Temp = -500F")
+		So(err.Error(), ShouldEqual, "Temp: Below absolute zero at line 1")
+	})
+
+}
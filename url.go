@@ -0,0 +1,59 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+var urlType = reflect.TypeOf(url.URL{})
+
+// Parse an absolute URL. A URL must have a scheme, and either a host or an
+// opaque part, eg. https://example.com/ or mailto:rick@example.com
+func parseURL(s string) (interface{}, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.New("Invalid URL")
+	}
+	if u.Scheme == "" {
+		return nil, errors.New("Missing URL scheme")
+	}
+	if u.Host == "" && u.Opaque == "" && u.Path == "" {
+		return nil, errors.New("Missing URL host")
+	}
+	return *u, nil
+}
+
+// WithURLSchemes restricts the schemes accepted for url.URL fields, eg.
+// WithURLSchemes("http", "https"), so that a misspelled scheme is reported
+// when the configuration is decoded. Schemes are not case sensitive.
+func (o *Decoder) WithURLSchemes(schemes ...string) *Decoder {
+	o.schemes = schemes
+	return o
+}
+
+// Verify the scheme of a value for a url.URL field.
+func (o *Decoder) checkScheme(v1 reflect.Value, val string) error {
+	t := v1.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if len(o.schemes) == 0 || t != urlType || val == "" {
+		return nil
+	}
+	u, err := url.Parse(val)
+	if err != nil {
+		return nil
+	}
+	for _, s := range o.schemes {
+		if strings.EqualFold(s, u.Scheme) {
+			return nil
+		}
+	}
+	return errors.New("URL scheme not allowed (" + u.Scheme + ")")
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net/url"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestURL(t *testing.T) {
+
+	type cfg struct {
+		Endpoint url.URL
+		Callback *url.URL
+		Contact  *url.URL
+	}
+
+	src := `Endpoint = https://api.example.com:8443/v1?key=value
+Callback = http://localhost/hook
+Contact = mailto:rick@example.com
+`
+
+	Convey("Decode and encode URLs", t, func() {
+		var x cfg
+		So(Decode(&x, src), ShouldBeNil)
+		So(x.Endpoint.Host, ShouldEqual, "api.example.com:8443")
+		So(x.Endpoint.Query().Get("key"), ShouldEqual, "value")
+		So(x.Callback.Path, ShouldEqual, "/hook")
+		So(x.Contact.Scheme, ShouldEqual, "mailto")
+		b1, err := Encode(x)
+		So(err, ShouldBeNil)
+		So(string(b1), ShouldEqual, src)
+	})
+
+	Convey("Restrict the accepted schemes", t, func() {
+		var x cfg
+		o := NewDecoder(&x).WithURLSchemes("HTTP", "https", "mailto")
+		So(o.DecodeString(src), ShouldBeNil)
+		err := NewDecoder(&x).WithURLSchemes("http", "https").DecodeString("Endpoint = htps://example.com")
+		So(err.Error(), ShouldEqual, "Endpoint: URL scheme not allowed (htps) at line 1")
+	})
+
+	Convey("Force error: Invalid URLs", t, func() {
+		errs := map[string]string{
+			"Endpoint = example.com/path": "Endpoint: Missing URL scheme at line 1",
+			"Endpoint = http://":          "Endpoint: Missing URL host at line 1",
+			"Callback = http://[::1":      "Callback: Invalid URL at line 1",
+		}
+		for src, msg := range errs {
+			var x cfg
+			err := Decode(&x, src)
+			So(err.Error(), ShouldEqual, msg)
+		}
+	})
+
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+)
+
+// A VerifyFunc checks the signature of the data of a configuration file.
+type VerifyFunc func(data, signature []byte) error
+
+var signatureFooter = regexp.MustCompile(`(?m)^[ \t]*#[ \t]*(sha256|signature):[ \t]*(\S+)[ \t]*\r?\n?\s*\z`)
+
+// WithVerifier sets a function to verify every file read by DecodeFile,
+// including included files, before it is parsed. The signature is taken from
+// a footer comment of the form "# sha256: ..." or "# signature: ...", in
+// which case the data is everything before the footer, or else from a
+// sidecar file with the same name plus ".sig", in which case the data is the
+// entire file. A file without a signature is rejected.
+func (o *Decoder) WithVerifier(fn VerifyFunc) *Decoder {
+	o.verify = fn
+	return o
+}
+
+// VerifySHA256 is a VerifyFunc which compares the hexadecimal SHA-256 digest
+// of the data to the signature.
+func VerifySHA256(data, signature []byte) error {
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != string(bytes.ToLower(signature)) {
+		return errors.New("Checksum mismatch")
+	}
+	return nil
+}
+
+// AppendChecksum returns the data with a "# sha256: ..." footer appended,
+// suitable for verification with VerifySHA256. A line feed is added before
+// the footer if the data does not end with one.
+func AppendChecksum(data []byte) []byte {
+	buf := bytes.NewBuffer(append([]byte(nil), data...))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	buf.WriteString("# sha256: " + hex.EncodeToString(sum[:]) + "\n")
+	return buf.Bytes()
+}
+
+// Read a file and verify its signature, returning the verified data.
+func verifyFile(filename string, r io.Reader, fn VerifyFunc) (io.Reader, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data, sig := bs, []byte(nil)
+	if m := signatureFooter.FindSubmatchIndex(bs); m != nil {
+		data, sig = bs[:m[0]], bs[m[4]:m[5]]
+	} else if sig, err = ioutil.ReadFile(filename + ".sig"); err == nil {
+		sig = bytes.TrimSpace(sig)
+	} else if os.IsNotExist(err) {
+		return nil, errors.New("No signature found (" + filename + ")")
+	} else {
+		return nil, err
+	}
+	if err = fn(data, sig); err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, filename)
+	}
+	return bytes.NewReader(bs), nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVerify(t *testing.T) {
+
+	type cfg struct {
+		Name string
+		Port int
+	}
+	data := []byte("Name = Rick\nPort = 80\n")
+
+	Convey("Verify a checksum footer", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, AppendChecksum(data))
+		var x cfg
+		So(NewDecoder(&x).WithVerifier(VerifySHA256).DecodeFile(tempfile), ShouldBeNil)
+		So(x, ShouldResemble, cfg{"Rick", 80})
+	})
+
+	Convey("Verify a checksum footer of data without a final line feed", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, AppendChecksum([]byte("Name = Rick\nPort = 80")))
+		var x cfg
+		So(NewDecoder(&x).WithVerifier(VerifySHA256).DecodeFile(tempfile), ShouldBeNil)
+		So(x, ShouldResemble, cfg{"Rick", 80})
+	})
+
+	Convey("Verify a sidecar signature with a custom scheme", t, func() {
+		key := []byte("secret")
+		sign := func(data []byte) []byte {
+			mac := hmac.New(sha256.New, key)
+			mac.Write(data)
+			return []byte(hex.EncodeToString(mac.Sum(nil)))
+		}
+		verify := func(data, signature []byte) error {
+			if !hmac.Equal(sign(data), signature) {
+				return errors.New("Bad signature")
+			}
+			return nil
+		}
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		defer os.Remove(tempfile + ".sig")
+		writeFile(tempfile, data)
+		writeFile(tempfile+".sig", append(sign(data), '\n'))
+		var x cfg
+		So(NewDecoder(&x).WithVerifier(verify).DecodeFile(tempfile), ShouldBeNil)
+		So(x.Port, ShouldEqual, 80)
+
+		writeFile(tempfile, []byte("Name = Rick\nPort = 81\n"))
+		err := NewDecoder(&x).WithVerifier(verify).DecodeFile(tempfile)
+		So(err.Error(), ShouldEqual, "Bad signature ("+tempfile+")")
+	})
+
+	Convey("Force error: Tampered and unsigned files", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		signed := AppendChecksum(data)
+		signed[7] = 'M'
+		writeFile(tempfile, signed)
+		var x cfg
+		err := NewDecoder(&x).WithVerifier(VerifySHA256).DecodeFile(tempfile)
+		So(err.Error(), ShouldEqual, "Checksum mismatch ("+tempfile+")")
+		So(x.Name, ShouldEqual, "")
+
+		writeFile(tempfile, data)
+		err = NewDecoder(&x).WithVerifier(VerifySHA256).DecodeFile(tempfile)
+		So(err.Error(), ShouldEqual, "No signature found ("+tempfile+")")
+	})
+
+}
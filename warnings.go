@@ -0,0 +1,79 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Warning describes a problem in a source which does not prevent it from
+// being decoded, such as a deprecated key, a duplicate key resolved by
+// FIRST_KEY_WINS or LAST_KEY_WINS, an extra field with the
+// ALLOW_EXTRA_FIELDS option, or a suspicious value: a boolean other than
+// true, yes, on, 1, false, no, off or 0, which is taken to be false, a
+// duration without a unit, which is taken to be nanoseconds, or a value with
+// an unbalanced double quote.
+type Warning struct {
+	File string // Name of the source file, or empty if unknown
+	Line int    // Line number in source, or zero if unknown
+	Key  string // Dotted key, or empty if unknown
+	Msg  string // Description of the problem
+}
+
+func (w Warning) String() string {
+	return formatPosition(w.File, w.Line, w.Key, w.Msg)
+}
+
+// Warnings returns the warnings of the most recent decode, including those
+// of included files, in the order they were found.
+func (o *Decoder) Warnings() []Warning {
+	return o.warnings
+}
+
+// Record a warning about a key of the field map.
+func (o *Decoder) warn(key, msg string) {
+	w := Warning{Key: key, Msg: msg}
+	if vs := o.lookup(key); vs != nil {
+		w.File, w.Line = vs.src, vs.no
+	}
+	o.warnings = append(o.warnings, w)
+	logWarning(o.logger, w)
+}
+
+// Record a warning about a value which was assigned to a field but was
+// probably not meant as written.
+func (o *Decoder) checkValue(v1 reflect.Value, key, val string) {
+	var msg string
+	switch {
+	case val == "":
+		return
+	case v1.Kind() == reflect.Bool && !isBool(val):
+		msg = "Unrecognized boolean value"
+	case v1.Type() == durationType && val != "0":
+		if _, err := strconv.ParseInt(val, 10, 64); err == nil {
+			msg = "Duration without a unit"
+		}
+	}
+	if msg != "" {
+		o.warn(key, msg)
+	}
+}
+
+// Record a warning found by the parser.
+func (o *Parser) warn(key, msg string, no int) {
+	w := Warning{o.filename, no, key, msg}
+	o.warnings = append(o.warnings, w)
+	logWarning(o.logger, w)
+}
+
+// Record a warning about a value as written which has an odd number of
+// unescaped double quotes, eg. Name = "Rick
+func (o *Parser) checkQuotes(key, raw string) {
+	if (strings.Count(raw, `"`)-strings.Count(raw, `\"`))%2 != 0 {
+		o.warn(key, "Unbalanced quote", o.lineno)
+	}
+}
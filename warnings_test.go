@@ -0,0 +1,112 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWarnings(t *testing.T) {
+
+	type T struct {
+		Name    string
+		Timeout int `config:",deprecated"`
+	}
+
+	Convey("A clean decode has no warnings", t, func() {
+		var x T
+		d := NewDecoder(&x)
+		So(d.DecodeString("Name = a"), ShouldBeNil)
+		So(d.Warnings(), ShouldBeEmpty)
+	})
+
+	Convey("Deprecated keys", t, func() {
+		var x T
+		d := NewDecoder(&x)
+		So(d.DecodeString("Name = a\nTimeout = 30"), ShouldBeNil)
+		So(x.Timeout, ShouldEqual, 30)
+		So(d.Warnings(), ShouldResemble, []Warning{{"", 2, "Timeout", "Deprecated key"}})
+		So(d.Warnings()[0].String(), ShouldEqual, "Timeout: Deprecated key at line 2")
+	})
+
+	Convey("Duplicate keys resolved by a key policy", t, func() {
+		var x T
+		d := NewDecoder(&x, LAST_KEY_WINS)
+		So(d.DecodeString("Name = a\nName = b"), ShouldBeNil)
+		So(x.Name, ShouldEqual, "b")
+		So(d.Warnings(), ShouldResemble, []Warning{{"", 2, "Name", "Duplicate key"}})
+
+		d = NewDecoder(&x, FIRST_KEY_WINS)
+		So(d.DecodeString("Name = a\nName = b"), ShouldBeNil)
+		So(x.Name, ShouldEqual, "a")
+		So(d.Warnings(), ShouldHaveLength, 1)
+	})
+
+	Convey("Suspicious values", t, func() {
+		var x struct {
+			Name  string
+			Debug bool
+			Wait  time.Duration
+			Flags map[string]bool
+		}
+		d := NewDecoder(&x)
+		So(d.DecodeString("Name = \"Rick\nDebug = 2\nWait = 10\nFlags.a = maybe"), ShouldBeNil)
+		So(x.Debug, ShouldBeFalse)
+		So(x.Wait, ShouldEqual, 10*time.Nanosecond)
+		So(d.Warnings(), ShouldResemble, []Warning{
+			{"", 1, "Name", "Unbalanced quote"},
+			{"", 2, "Debug", "Unrecognized boolean value"},
+			{"", 3, "Wait", "Duration without a unit"},
+			{"", 4, "Flags.a", "Unrecognized boolean value"},
+		})
+
+		Convey("Ordinary values are not suspicious", func() {
+			src := "Name = \"Rick \\\"C-137\\\"\"\nDebug = yes\nWait = 10s\nFlags.a = off"
+			So(d.DecodeString(src), ShouldBeNil)
+			So(x.Name, ShouldEqual, `Rick "C-137"`)
+			So(d.Warnings(), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Extra fields with ALLOW_EXTRA_FIELDS", t, func() {
+		var x T
+		d := NewDecoder(&x, ALLOW_EXTRA_FIELDS)
+		So(d.DecodeString("Name = a\nColor = green"), ShouldBeNil)
+		So(x.Name, ShouldEqual, "a")
+		So(d.Warnings(), ShouldResemble, []Warning{{"", 2, "Color", "Extra field"}})
+		So(Check(&x, "Color = green", ALLOW_EXTRA_FIELDS), ShouldBeEmpty)
+
+		Convey("Warnings are cleared by the next decode", func() {
+			So(d.DecodeString("Name = b"), ShouldBeNil)
+			So(d.Warnings(), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Warnings in a file name the file", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = a\nTimeout = 30"))
+		var x T
+		d := NewDecoder(&x)
+		So(d.DecodeFile(tempfile), ShouldBeNil)
+		So(d.Warnings()[0].String(), ShouldEqual, tempfile+":2: Timeout: Deprecated key")
+	})
+
+	Convey("Warnings are formatted like parse errors", t, func() {
+		for _, w := range []Warning{
+			{"", 0, "", "Problem"},
+			{"", 3, "Timeout", "Problem"},
+			{"app.conf", 0, "Timeout", "Problem"},
+			{"app.conf", 3, "", "Problem"},
+		} {
+			e := &ParseError{File: w.File, Line: w.Line, Key: w.Key, Msg: w.Msg}
+			So(w.String(), ShouldEqual, e.Error())
+		}
+	})
+
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Notifier reports modifications of a file. Watch returns a channel which
+// receives a value whenever the file changes. The channel is closed when the
+// Notifier is closed. Implementations based on fsnotify or similar may be
+// supplied to WatchWith.
+type Notifier interface {
+	Watch(filename string) (<-chan struct{}, error)
+	Close() error
+}
+
+// NewPollNotifier returns a Notifier which checks the modification time and
+// size of the file at every interval.
+func NewPollNotifier(interval time.Duration) Notifier {
+	return &pollNotifier{interval: interval, done: make(chan struct{})}
+}
+
+type pollNotifier struct {
+	interval time.Duration
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (o *pollNotifier) Watch(filename string) (<-chan struct{}, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan struct{}, 1)
+	go func() {
+		t := time.NewTicker(o.interval)
+		defer t.Stop()
+		mod, size := fi.ModTime(), fi.Size()
+		for {
+			select {
+			case <-o.done:
+				close(c)
+				return
+			case <-t.C:
+				fi, err := os.Stat(filename)
+				if err != nil {
+					// the file may be missing while it is being replaced
+					continue
+				}
+				if fi.ModTime().Equal(mod) && fi.Size() == size {
+					continue
+				}
+				mod, size = fi.ModTime(), fi.Size()
+				select {
+				case c <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return c, nil
+}
+
+func (o *pollNotifier) Close() error {
+	o.once.Do(func() { close(o.done) })
+	return nil
+}
+
+// A Watcher re-decodes a configuration file whenever it changes.
+type Watcher struct {
+	n       Notifier
+	stopped atomic.Bool
+}
+
+// Stop stops watching the file. The callback will not be called again once
+// any call in progress has returned, even for a change which was detected
+// before Stop was called.
+func (o *Watcher) Stop() {
+	o.stopped.Store(true)
+	o.n.Close()
+}
+
+// Watch polls the supplied file once per second. Whenever the file changes,
+// it is decoded into a deep copy of *x and the callback is called with the
+// new value, or with the decoding error. Neither *x, nor the maps, slices and
+// pointers it holds, are modified, so its values act as defaults for every
+// reload, and values passed to the callback are never changed by later
+// reloads. Changes to included files are not detected. Decoder options are
+// optional.
+func Watch[T any](filename string, x *T, fn func(T, error), options ...int) (*Watcher, error) {
+	return WatchWith(NewPollNotifier(time.Second), filename, x, fn, options...)
+}
+
+// WatchWith is like Watch but uses the supplied Notifier to detect changes.
+func WatchWith[T any](n Notifier, filename string, x *T, fn func(T, error), options ...int) (*Watcher, error) {
+	NewDecoder(x, options...) // panic early on bad arguments
+	c, err := n.Watch(filename)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{n: n}
+	go func() {
+		for range c {
+			if w.stopped.Load() {
+				return
+			}
+			cfg := deepCopy(reflect.ValueOf(x).Elem()).Interface().(T)
+			err := DecodeFile(filename, &cfg, options...)
+			if w.stopped.Load() {
+				return
+			}
+			fn(cfg, err)
+		}
+	}()
+	return w, nil
+}
+
+// Return a copy of a value which shares no maps, slices or pointer targets
+// with the original, so that decoding into the copy leaves the original
+// unchanged. The unexported fields of a struct are copied as is, apart from
+// those of big.Int, big.Float and big.Rat, which are copied with their Set
+// method.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type())
+		if isBigType(v.Type()) {
+			// the digits of a big number are a slice in an unexported field
+			src := reflect.New(v.Type())
+			src.Elem().Set(v)
+			c.MethodByName("Set").Call([]reflect.Value{src})
+			return c.Elem()
+		}
+		c.Elem().Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := c.Elem().Field(i); f.CanSet() {
+				f.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c.Elem()
+	}
+	return v
+}
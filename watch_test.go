@@ -0,0 +1,140 @@
+// Copyright (c) 2018 Mark K Mueller <github.com/mkmueller>
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"math/big"
+	"net"
+	"os"
+	"testing"
+	"time"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWatch(t *testing.T) {
+
+	type cfgX struct {
+		Name string
+		Port int
+	}
+
+	Convey("Reload a file when it changes", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = Rick"))
+
+		type result struct {
+			cfg cfgX
+			err error
+		}
+		c := make(chan result, 4)
+		x := cfgX{Port: 80}
+		w, err := WatchWith(NewPollNotifier(5*time.Millisecond), tempfile, &x, func(cfg cfgX, err error) {
+			c <- result{cfg, err}
+		})
+		So(err, ShouldBeNil)
+		defer w.Stop()
+
+		replaceFile(tempfile, []byte("Name = Morty Smith"))
+		r := <-c
+		So(r.err, ShouldBeNil)
+		So(r.cfg.Name, ShouldEqual, "Morty Smith")
+		So(r.cfg.Port, ShouldEqual, 80)
+		So(x.Name, ShouldEqual, "")
+
+		replaceFile(tempfile, []byte("Name = Rick\nPort = many"))
+		r = <-c
+		So(r.err, ShouldNotBeNil)
+	})
+
+	Convey("Reloads never modify the defaults or earlier values", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Tags = { B = 2 }"))
+
+		type cfgY struct {
+			Tags map[string]string
+			Addr net.IP
+			Sub  *cfgX
+			Max  big.Int
+		}
+		c := make(chan cfgY, 4)
+		x := cfgY{Tags: map[string]string{"A": "1"}, Addr: net.ParseIP("10.0.0.1"), Sub: &cfgX{Name: "Rick"}}
+		x.Max.SetInt64(1 << 40)
+		w, err := WatchWith(NewPollNotifier(5*time.Millisecond), tempfile, &x, func(cfg cfgY, err error) {
+			c <- cfg
+		})
+		So(err, ShouldBeNil)
+		defer w.Stop()
+
+		replaceFile(tempfile, []byte("Tags = { B = 3 }\nSub = { Name = Morty }\nMax = 7\nAddr = 10.0.0.2"))
+		r1 := <-c
+		So(r1.Tags, ShouldResemble, map[string]string{"A": "1", "B": "3"})
+		So(r1.Sub.Name, ShouldEqual, "Morty")
+		So(r1.Max.String(), ShouldEqual, "7")
+		So(r1.Addr.String(), ShouldEqual, "10.0.0.2")
+
+		replaceFile(tempfile, []byte("Tags = { B = 4 }"))
+		r2 := <-c
+		So(r2.Tags["B"], ShouldEqual, "4")
+		So(r1.Tags["B"], ShouldEqual, "3")
+		So(x.Tags, ShouldResemble, map[string]string{"A": "1"})
+		So(x.Addr.String(), ShouldEqual, "10.0.0.1")
+		So(x.Sub.Name, ShouldEqual, "Rick")
+		So(x.Max.String(), ShouldEqual, "1099511627776")
+	})
+
+	Convey("No callback after Stop", t, func() {
+		tempfile := createTempFile("GOTEST_CONFIG")
+		defer os.Remove(tempfile)
+		writeFile(tempfile, []byte("Name = Rick"))
+
+		n := &chanNotifier{c: make(chan struct{}, 1)}
+		called := make(chan bool, 1)
+		var x cfgX
+		w, err := WatchWith(n, tempfile, &x, func(cfgX, error) {
+			called <- true
+		})
+		So(err, ShouldBeNil)
+		w.Stop()
+		// a change detected before Stop, still buffered in the channel
+		n.c <- struct{}{}
+		close(n.c)
+		select {
+		case <-called:
+			t.Fail()
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	Convey("Force error: Watch a non-existent file", t, func() {
+		var x cfgX
+		_, err := Watch("non existent file.conf", &x, func(cfgX, error) {})
+		So(err, ShouldNotBeNil)
+	})
+
+}
+
+// Replace a file atomically so a watcher never sees a partial write.
+func replaceFile(file string, data []byte) {
+	writeFile(file+".tmp", data)
+	if err := os.Rename(file+".tmp", file); err != nil {
+		panic(err)
+	}
+}
+
+// chanNotifier reports a change whenever a value is sent on c. Close does not
+// close c.
+type chanNotifier struct {
+	c chan struct{}
+}
+
+func (o *chanNotifier) Watch(filename string) (<-chan struct{}, error) {
+	return o.c, nil
+}
+
+func (o *chanNotifier) Close() error {
+	return nil
+}